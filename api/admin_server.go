@@ -0,0 +1,186 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow-archive/models/archive"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/onflow/flow-archive-access/api/admin"
+	"github.com/onflow/flow-archive-access/api/cache"
+	"github.com/onflow/flow-archive-access/api/topk"
+)
+
+// AdminServer implements the generated AdminAPIServer interface, which exposes
+// endpoints for operators that should not be reachable by regular clients.
+type AdminServer struct {
+	log       zerolog.Logger
+	index     archive.Reader
+	window    uint64
+	head      uint64
+	addresses *topk.Counter
+	caches    *cache.Manager
+	disk      *cache.Disk
+}
+
+// NewAdminServer creates a new admin server, using the provided index reader as
+// a backend. The height window and pinned head are those of the Access API
+// server, and are zero if they are disabled. The addresses counter is the one
+// given to the Access API server for address accounting, and can be nil if it
+// is disabled. The cache manager and disk cache are the ones holding the Access
+// API server's response caches, and can also be nil.
+func NewAdminServer(log zerolog.Logger, index archive.Reader, window uint64, head uint64, addresses *topk.Counter, caches *cache.Manager, disk *cache.Disk) *AdminServer {
+	a := AdminServer{
+		log:       log,
+		index:     index,
+		window:    window,
+		head:      head,
+		addresses: addresses,
+		caches:    caches,
+		disk:      disk,
+	}
+
+	return &a
+}
+
+// Explain describes how the requested block ID or height resolves in the index.
+// Failing lookups are reported in the response rather than as errors, so that
+// they can be told apart from entities that are not indexed. The range that the
+// height is checked against is the one served by the Access API server, within
+// its height window and up to its pinned head.
+func (a *AdminServer) Explain(_ context.Context, in *admin.ExplainRequest) (*admin.ExplainResponse, error) {
+	served := a.index
+	if a.head > 0 {
+		served = &pinnedReader{Reader: a.index, head: a.head}
+	}
+	first, last, err := windowRange(served, a.window)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := admin.ExplainResponse{
+		FirstHeight: first,
+		LastHeight:  last,
+	}
+
+	var height uint64
+	switch target := in.Target.(type) {
+	case *admin.ExplainRequest_BlockId:
+		if len(target.BlockId) != len(flow.ZeroID) {
+			return nil, status.Errorf(codes.InvalidArgument, "block ID has invalid length %d", len(target.BlockId))
+		}
+		height, err = a.index.HeightForBlock(flow.HashToID(target.BlockId))
+		if isNotFound(err) {
+			return &resp, nil
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("could not get height for block: %s", err))
+			return &resp, nil
+		}
+	case *admin.ExplainRequest_Height:
+		height = target.Height
+	default:
+		return nil, status.Error(codes.InvalidArgument, "either a block ID or a height is required")
+	}
+
+	resp.Height = &height
+	resp.InRange = height >= first && height <= last
+
+	header, err := a.index.Header(height)
+	switch {
+	case isNotFound(err):
+	case err != nil:
+		resp.Errors = append(resp.Errors, fmt.Sprintf("could not get header: %s", err))
+	default:
+		blockID := header.ID()
+		resp.HeaderIndexed = true
+		resp.BlockId = blockID[:]
+	}
+
+	sealIDs, err := a.index.SealsByHeight(height)
+	if err != nil && !isNotFound(err) {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("could not get seals: %s", err))
+	}
+	resp.Seals = uint32(len(sealIDs))
+
+	collIDs, err := a.index.CollectionsByHeight(height)
+	if err != nil && !isNotFound(err) {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("could not get collections: %s", err))
+	}
+	resp.Collections = uint32(len(collIDs))
+
+	txIDs, err := a.index.TransactionsByHeight(height)
+	if err != nil && !isNotFound(err) {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("could not get transactions: %s", err))
+	}
+	resp.Transactions = uint32(len(txIDs))
+
+	return &resp, nil
+}
+
+// GetTopAddresses returns the most requested account addresses, along with an
+// estimate of their number of requests.
+func (a *AdminServer) GetTopAddresses(_ context.Context, _ *admin.GetTopAddressesRequest) (*admin.TopAddressesResponse, error) {
+	if a.addresses == nil {
+		return nil, status.Error(codes.Unimplemented, "address accounting is not enabled on this server")
+	}
+
+	var resp admin.TopAddressesResponse
+	for _, entry := range a.addresses.Top() {
+		count := admin.AddressCount{
+			Address: []byte(entry.Key),
+			Count:   entry.Count,
+		}
+		resp.Addresses = append(resp.Addresses, &count)
+	}
+
+	return &resp, nil
+}
+
+// FlushCaches removes all entries from the requested response cache, or from
+// all of them if no name is given. Register reads are cached by the script
+// invoker, which does not allow flushing, so they are not affected.
+func (a *AdminServer) FlushCaches(_ context.Context, in *admin.FlushCachesRequest) (*admin.FlushCachesResponse, error) {
+	var flushed []string
+	if a.caches != nil {
+		flushed = append(flushed, a.caches.Flush(in.Name)...)
+	}
+	if a.disk != nil && (in.Name == "" || in.Name == a.disk.Name()) {
+		err := a.disk.Flush()
+		if err != nil {
+			return nil, fmt.Errorf("could not flush disk cache: %w", err)
+		}
+		flushed = append(flushed, a.disk.Name())
+	}
+
+	if in.Name != "" && len(flushed) == 0 {
+		return nil, status.Errorf(codes.NotFound, "unknown cache %q", in.Name)
+	}
+
+	a.log.Info().Strs("caches", flushed).Msg("caches flushed")
+
+	resp := admin.FlushCachesResponse{
+		Flushed: flushed,
+	}
+
+	return &resp, nil
+}
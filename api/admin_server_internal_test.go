@@ -0,0 +1,340 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow-archive/testing/mocks"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/onflow/flow-archive-access/api/admin"
+	"github.com/onflow/flow-archive-access/api/cache"
+	"github.com/onflow/flow-archive-access/api/topk"
+)
+
+func TestNewAdminServer(t *testing.T) {
+	index := mocks.BaselineReader(t)
+
+	addresses := topk.New(10)
+
+	a := NewAdminServer(zerolog.Nop(), index, 0, 0, addresses, nil, nil)
+
+	require.NotNil(t, a)
+	assert.Equal(t, index, a.index)
+	assert.Equal(t, addresses, a.addresses)
+}
+
+func TestAdminServer_Explain(t *testing.T) {
+	header := mocks.GenericHeader
+	blockID := header.ID()
+	sealIDs := mocks.GenericSealIDs(2)
+	collIDs := mocks.GenericCollectionIDs(3)
+	txIDs := mocks.GenericTransactionIDs(4)
+
+	t.Run("nominal case with height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+
+			return header, nil
+		}
+		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return sealIDs, nil
+		}
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return collIDs, nil
+		}
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
+		}
+
+		a := NewAdminServer(zerolog.Nop(), index, 0, 0, nil, nil, nil)
+
+		req := &admin.ExplainRequest{Target: &admin.ExplainRequest_Height{Height: mocks.GenericHeight}}
+		resp, err := a.Explain(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotNil(t, resp.Height)
+		assert.Equal(t, mocks.GenericHeight, *resp.Height)
+		assert.True(t, resp.InRange)
+		assert.True(t, resp.HeaderIndexed)
+		assert.Equal(t, blockID[:], resp.BlockId)
+		assert.Equal(t, uint32(len(sealIDs)), resp.Seals)
+		assert.Equal(t, uint32(len(collIDs)), resp.Collections)
+		assert.Equal(t, uint32(len(txIDs)), resp.Transactions)
+		assert.Empty(t, resp.Errors)
+	})
+
+	t.Run("nominal case with block ID", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(id flow.Identifier) (uint64, error) {
+			assert.Equal(t, blockID, id)
+
+			return mocks.GenericHeight, nil
+		}
+
+		a := NewAdminServer(zerolog.Nop(), index, 0, 0, nil, nil, nil)
+
+		req := &admin.ExplainRequest{Target: &admin.ExplainRequest_BlockId{BlockId: blockID[:]}}
+		resp, err := a.Explain(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotNil(t, resp.Height)
+		assert.Equal(t, mocks.GenericHeight, *resp.Height)
+		assert.True(t, resp.InRange)
+	})
+
+	t.Run("uses served range with height window and pinned head", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return mocks.GenericHeight, nil
+		}
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight + 100, nil
+		}
+
+		a := NewAdminServer(zerolog.Nop(), index, 10, mocks.GenericHeight+50, nil, nil, nil)
+
+		req := &admin.ExplainRequest{Target: &admin.ExplainRequest_Height{Height: mocks.GenericHeight + 60}}
+		resp, err := a.Explain(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight+41, resp.FirstHeight)
+		assert.Equal(t, mocks.GenericHeight+50, resp.LastHeight)
+		assert.False(t, resp.InRange)
+	})
+
+	t.Run("handles unknown block ID", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, badger.ErrKeyNotFound
+		}
+
+		a := NewAdminServer(zerolog.Nop(), index, 0, 0, nil, nil, nil)
+
+		req := &admin.ExplainRequest{Target: &admin.ExplainRequest_BlockId{BlockId: blockID[:]}}
+		resp, err := a.Explain(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Nil(t, resp.Height)
+		assert.False(t, resp.InRange)
+		assert.Empty(t, resp.Errors)
+	})
+
+	t.Run("handles height outside of indexed range", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, badger.ErrKeyNotFound
+		}
+		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, badger.ErrKeyNotFound
+		}
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, badger.ErrKeyNotFound
+		}
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, badger.ErrKeyNotFound
+		}
+
+		a := NewAdminServer(zerolog.Nop(), index, 0, 0, nil, nil, nil)
+
+		req := &admin.ExplainRequest{Target: &admin.ExplainRequest_Height{Height: mocks.GenericHeight + 1}}
+		resp, err := a.Explain(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.False(t, resp.InRange)
+		assert.False(t, resp.HeaderIndexed)
+		assert.Zero(t, resp.Seals)
+		assert.Zero(t, resp.Collections)
+		assert.Zero(t, resp.Transactions)
+		assert.Empty(t, resp.Errors)
+	})
+
+	t.Run("reports indexer failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, mocks.GenericError
+		}
+
+		a := NewAdminServer(zerolog.Nop(), index, 0, 0, nil, nil, nil)
+
+		req := &admin.ExplainRequest{Target: &admin.ExplainRequest_Height{Height: mocks.GenericHeight}}
+		resp, err := a.Explain(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.False(t, resp.HeaderIndexed)
+		assert.Len(t, resp.Errors, 2)
+	})
+
+	t.Run("handles indexer failure on First", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		a := NewAdminServer(zerolog.Nop(), index, 0, 0, nil, nil, nil)
+
+		req := &admin.ExplainRequest{Target: &admin.ExplainRequest_Height{Height: mocks.GenericHeight}}
+		_, err := a.Explain(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles malformed block ID", func(t *testing.T) {
+		t.Parallel()
+
+		a := NewAdminServer(zerolog.Nop(), mocks.BaselineReader(t), 0, 0, nil, nil, nil)
+
+		req := &admin.ExplainRequest{Target: &admin.ExplainRequest_BlockId{BlockId: blockID[:16]}}
+		_, err := a.Explain(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("handles missing target", func(t *testing.T) {
+		t.Parallel()
+
+		a := NewAdminServer(zerolog.Nop(), mocks.BaselineReader(t), 0, 0, nil, nil, nil)
+
+		_, err := a.Explain(context.Background(), &admin.ExplainRequest{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestAdminServer_GetTopAddresses(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		addresses := topk.New(10)
+		addresses.Add(string(mocks.GenericAccount.Address[:]))
+		addresses.Add(string(mocks.GenericAccount.Address[:]))
+		addresses.Add(string(flow.EmptyAddress[:]))
+
+		a := NewAdminServer(zerolog.Nop(), mocks.BaselineReader(t), 0, 0, addresses, nil, nil)
+
+		resp, err := a.GetTopAddresses(context.Background(), &admin.GetTopAddressesRequest{})
+
+		require.NoError(t, err)
+		require.Len(t, resp.Addresses, 2)
+		assert.Equal(t, mocks.GenericAccount.Address[:], resp.Addresses[0].Address)
+		assert.Equal(t, uint64(2), resp.Addresses[0].Count)
+		assert.Equal(t, flow.EmptyAddress[:], resp.Addresses[1].Address)
+		assert.Equal(t, uint64(1), resp.Addresses[1].Count)
+	})
+
+	t.Run("handles disabled accounting", func(t *testing.T) {
+		t.Parallel()
+
+		a := NewAdminServer(zerolog.Nop(), mocks.BaselineReader(t), 0, 0, nil, nil, nil)
+
+		_, err := a.GetTopAddresses(context.Background(), &admin.GetTopAddressesRequest{})
+
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+}
+
+func TestAdminServer_FlushCaches(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		caches := cache.NewManager(0)
+		blocks := caches.NewLRU("blocks", 0)
+		blocks.Set(mocks.GenericHeight, "block", 10)
+
+		disk, err := cache.NewDisk("disk", t.TempDir(), 0, nil)
+		require.NoError(t, err)
+		defer disk.Close()
+		require.NoError(t, disk.Set([]byte("block/1"), []byte("block")))
+
+		a := NewAdminServer(zerolog.Nop(), mocks.BaselineReader(t), 0, 0, nil, caches, disk)
+
+		resp, err := a.FlushCaches(context.Background(), &admin.FlushCachesRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"blocks", "disk"}, resp.Flushed)
+		assert.Equal(t, uint64(0), blocks.Size())
+		assert.Equal(t, uint64(0), disk.Size())
+	})
+
+	t.Run("flushes cache by name", func(t *testing.T) {
+		t.Parallel()
+
+		caches := cache.NewManager(0)
+		blocks := caches.NewLRU("blocks", 0)
+		blocks.Set(mocks.GenericHeight, "block", 10)
+
+		disk, err := cache.NewDisk("disk", t.TempDir(), 0, nil)
+		require.NoError(t, err)
+		defer disk.Close()
+		require.NoError(t, disk.Set([]byte("block/1"), []byte("block")))
+
+		a := NewAdminServer(zerolog.Nop(), mocks.BaselineReader(t), 0, 0, nil, caches, disk)
+
+		resp, err := a.FlushCaches(context.Background(), &admin.FlushCachesRequest{Name: "disk"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"disk"}, resp.Flushed)
+		assert.Equal(t, uint64(10), blocks.Size())
+		assert.Equal(t, uint64(0), disk.Size())
+	})
+
+	t.Run("handles unknown cache", func(t *testing.T) {
+		t.Parallel()
+
+		a := NewAdminServer(zerolog.Nop(), mocks.BaselineReader(t), 0, 0, nil, cache.NewManager(0), nil)
+
+		_, err := a.FlushCaches(context.Background(), &admin.FlushCachesRequest{Name: "unknown"})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles disabled caches", func(t *testing.T) {
+		t.Parallel()
+
+		a := NewAdminServer(zerolog.Nop(), mocks.BaselineReader(t), 0, 0, nil, nil, nil)
+
+		resp, err := a.FlushCaches(context.Background(), &admin.FlushCachesRequest{})
+
+		require.NoError(t, err)
+		assert.Empty(t, resp.Flushed)
+	})
+}
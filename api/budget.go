@@ -0,0 +1,99 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"sync/atomic"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/onflow/flow-archive/models/archive"
+)
+
+// requestBudget accumulates the cost of a single request, in cost units, and
+// reports when it exceeds the budget of the request. Each register read and
+// each event returned by the index costs one unit.
+type requestBudget struct {
+	limit uint64
+	spent atomic.Uint64
+}
+
+func newRequestBudget(limit uint64) *requestBudget {
+	b := requestBudget{
+		limit: limit,
+	}
+
+	return &b
+}
+
+// spend adds the given cost to the request, and returns an error if the total
+// cost of the request exceeds its budget.
+func (b *requestBudget) spend(cost uint64) error {
+	if b.spent.Add(cost) > b.limit {
+		return errBudgetExceeded
+	}
+
+	return nil
+}
+
+// exceeded returns whether the total cost of the request exceeds its budget. A
+// nil budget is never exceeded.
+func (b *requestBudget) exceeded() bool {
+	if b == nil {
+		return false
+	}
+
+	return b.spent.Load() > b.limit
+}
+
+// budgetReader wraps an index reader so that the registers and events read
+// through it are charged to the budget of a request. Reads that exceed the
+// budget fail, which aborts the scripts that make them.
+type budgetReader struct {
+	archive.Reader
+
+	budget *requestBudget
+}
+
+// Values implements the archive.Reader interface and charges the values read.
+func (r *budgetReader) Values(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+	values, err := r.Reader.Values(height, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.budget.spend(uint64(len(values)))
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Events implements the archive.Reader interface and charges the events read.
+func (r *budgetReader) Events(height uint64, types ...flow.EventType) ([]flow.Event, error) {
+	events, err := r.Reader.Events(height, types...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.budget.spend(uint64(len(events)))
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,272 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onflow/cadence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow-archive/models/archive"
+	"github.com/onflow/flow-archive/testing/mocks"
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestServer_RequestBudget(t *testing.T) {
+	// The script reads the given number of registers, one at a time, through
+	// the index of its invoker, like the archive invoker does.
+	scriptFactory := func(t *testing.T, registers int) func(archive.Reader) (Invoker, error) {
+		return func(index archive.Reader) (Invoker, error) {
+			invoker := mocks.BaselineInvoker(t)
+			invoker.ScriptFunc = func(height uint64, _ []byte, _ []cadence.Value) (cadence.Value, error) {
+				for _, path := range mocks.GenericLedgerPaths(registers) {
+					_, err := index.Values(height, []ledger.Path{path})
+					if err != nil {
+						return nil, err
+					}
+				}
+				return cadence.NewInt(1), nil
+			}
+			return invoker, nil
+		}
+	}
+
+	t.Run("fails heavy script", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(_ uint64, paths []ledger.Path) ([]ledger.Value, error) {
+			return make([]ledger.Value, len(paths)), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.NewInvoker = scriptFactory(t, 6)
+		s.cfg.RequestBudget = 5
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("executes script within budget", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(_ uint64, paths []ledger.Path) ([]ledger.Value, error) {
+			return make([]ledger.Value, len(paths)), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.NewInvoker = scriptFactory(t, 5)
+		s.cfg.RequestBudget = 5
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("fails concurrent heavy scripts", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(_ uint64, paths []ledger.Path) ([]ledger.Value, error) {
+			return make([]ledger.Value, len(paths)), nil
+		}
+
+		// Both executions are held until the other request had the time to
+		// join, so that identical requests would be deduplicated.
+		release := make(chan struct{})
+		heavy := scriptFactory(t, 6)
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.NewInvoker = func(index archive.Reader) (Invoker, error) {
+			invoker, err := heavy(index)
+			if err != nil {
+				return nil, err
+			}
+			script := invoker.(*mocks.Invoker).ScriptFunc
+			invoker.(*mocks.Invoker).ScriptFunc = func(height uint64, body []byte, args []cadence.Value) (cadence.Value, error) {
+				<-release
+				return script(height, body, args)
+			}
+			return invoker, nil
+		}
+		s.cfg.RequestBudget = 5
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+				assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+			}()
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+	})
+
+	t.Run("rejects script without invoker factory", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			t.Error("script should not be executed")
+			return nil, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+		s.cfg.NewInvoker = nil
+		s.cfg.RequestBudget = 5
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("fails large event range", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight + 9, nil
+		}
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return mocks.GenericEvents(4), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.RequestBudget = 20
+
+		req := &access.GetEventsForHeightRangeRequest{
+			Type:        string(mocks.GenericEventType(0)),
+			StartHeight: mocks.GenericHeight,
+			EndHeight:   mocks.GenericHeight + 9,
+		}
+		_, err := s.GetEventsForHeightRange(context.Background(), req)
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("returns event range within budget", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight + 4, nil
+		}
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return mocks.GenericEvents(4), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.RequestBudget = 20
+
+		req := &access.GetEventsForHeightRangeRequest{
+			Type:        string(mocks.GenericEventType(0)),
+			StartHeight: mocks.GenericHeight,
+			EndHeight:   mocks.GenericHeight + 4,
+		}
+		resp, err := s.GetEventsForHeightRange(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Len(t, resp.Results, 5)
+	})
+
+	t.Run("fails events of many blocks", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return mocks.GenericEvents(4), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.RequestBudget = 10
+
+		var blockIDs [][]byte
+		for _, blockID := range mocks.GenericBlockIDs(3) {
+			blockIDs = append(blockIDs, blockID[:])
+		}
+		req := &access.GetEventsForBlockIDsRequest{
+			Type:     string(mocks.GenericEventType(0)),
+			BlockIds: blockIDs,
+		}
+		_, err := s.GetEventsForBlockIDs(context.Background(), req)
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("does not limit requests without budget", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight + 9, nil
+		}
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return mocks.GenericEvents(4), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetEventsForHeightRangeRequest{
+			Type:        string(mocks.GenericEventType(0)),
+			StartHeight: mocks.GenericHeight,
+			EndHeight:   mocks.GenericHeight + 9,
+		}
+		_, err := s.GetEventsForHeightRange(context.Background(), req)
+
+		assert.NoError(t, err)
+	})
+}
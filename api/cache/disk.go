@@ -0,0 +1,319 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+)
+
+// Prefixes of the keys of the disk cache. Entries are stored by key, and their
+// keys are also stored by insertion sequence, along with their cost, so that the
+// oldest entries can be found and evicted when the cache is full. The namespace
+// of the entries is stored on its own.
+const (
+	prefixEntry     = 'e'
+	prefixOrder     = 'o'
+	prefixNamespace = 'n'
+)
+
+// gcInterval is the interval at which the value log of the disk cache is
+// garbage collected, to reclaim the space of evicted entries.
+const gcInterval = time.Minute
+
+// gcRatio is the share of discardable data above which a value log file is
+// rewritten by garbage collection.
+const gcRatio = 0.5
+
+// Disk is a persistent cache of immutable values, which survives restarts. It is
+// limited by the total size of its keys and values, and evicts its oldest
+// entries first. Since values are immutable, an existing entry is never
+// overwritten. Its size does not count towards the budget of a manager, as it
+// does not hold its entries in memory.
+//
+// The limit applies to the logical size of the entries. The space of evicted
+// entries is only reclaimed on disk by the periodic garbage collection of the
+// value log and by compactions, so the files of the cache can temporarily
+// exceed it.
+type Disk struct {
+	name string
+	max  uint64
+	db   *badger.DB
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu        sync.Mutex
+	size      uint64
+	next      uint64
+	namespace []byte
+}
+
+// NewDisk opens the disk cache with the given name in the given directory,
+// creating it if needed, and limits it to the given number of bytes. A size of
+// zero means that the cache is not limited. The entries belong to the given
+// namespace, such as the root block ID of the served index; entries stored
+// under another namespace are flushed when the cache is opened, so that a cache
+// directory reused for another network never serves its entries.
+func NewDisk(name string, dir string, size uint64, namespace []byte) (*Disk, error) {
+	opts := badger.DefaultOptions(dir).WithLogger(nil)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not open database: %w", err)
+	}
+
+	c := Disk{
+		name:      name,
+		max:       size,
+		db:        db,
+		done:      make(chan struct{}),
+		namespace: namespace,
+	}
+
+	var stored []byte
+	err = db.View(func(tx *badger.Txn) error {
+		item, err := tx.Get([]byte{prefixNamespace})
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		stored, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("could not load namespace: %w", err)
+	}
+	if !bytes.Equal(stored, namespace) {
+		err = c.reset()
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("could not flush entries of namespace %x: %w", stored, err)
+		}
+	}
+
+	// The size of the cache and the next insertion sequence are recovered from
+	// the entries that are already stored.
+	err = db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{Prefix: []byte{prefixOrder}})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			order, err := item.ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("could not read entry order: %w", err)
+			}
+			c.size += binary.BigEndian.Uint64(order)
+			c.next = binary.BigEndian.Uint64(item.Key()[1:]) + 1
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("could not load entries: %w", err)
+	}
+
+	cacheSize.WithLabelValues(c.name).Set(float64(c.size))
+
+	c.wg.Add(1)
+	go c.collect()
+
+	return &c, nil
+}
+
+// Get returns the value stored for the given key, if there is one.
+func (c *Disk) Get(key []byte) ([]byte, bool) {
+	var value []byte
+	err := c.db.View(func(tx *badger.Txn) error {
+		item, err := tx.Get(entryKey(key))
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Set stores the given value for the given key, unless there already is one.
+// The oldest entries are evicted until the cache fits within its limit again.
+// Values that are larger than the cache's limit are not stored.
+func (c *Disk) Set(key []byte, value []byte) error {
+	cost := uint64(len(key) + len(value))
+	if c.max > 0 && cost > c.max {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.Get(key)
+	if ok {
+		return nil
+	}
+
+	seq := make([]byte, 9)
+	seq[0] = prefixOrder
+	binary.BigEndian.PutUint64(seq[1:], c.next)
+	order := make([]byte, 8, 8+len(key))
+	binary.BigEndian.PutUint64(order, cost)
+	order = append(order, key...)
+	err := c.db.Update(func(tx *badger.Txn) error {
+		err := tx.Set(entryKey(key), value)
+		if err != nil {
+			return err
+		}
+		return tx.Set(seq, order)
+	})
+	if err != nil {
+		return fmt.Errorf("could not store entry: %w", err)
+	}
+	c.next++
+	c.size += cost
+
+	for c.max > 0 && c.size > c.max {
+		evicted, err := c.evict()
+		if err != nil {
+			return fmt.Errorf("could not evict entry: %w", err)
+		}
+		c.size -= evicted
+		cacheEvictions.WithLabelValues(c.name).Inc()
+	}
+
+	cacheSize.WithLabelValues(c.name).Set(float64(c.size))
+
+	return nil
+}
+
+// Name returns the name of the cache.
+func (c *Disk) Name() string {
+	return c.name
+}
+
+// Flush removes all entries from the cache.
+func (c *Disk) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.reset()
+	if err != nil {
+		return err
+	}
+	c.size = 0
+	c.next = 0
+
+	cacheSize.WithLabelValues(c.name).Set(0)
+
+	return nil
+}
+
+// Size returns the total size of the keys and values in the cache, in bytes.
+func (c *Disk) Size() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// Close stops the garbage collection of the cache and closes the underlying
+// database.
+func (c *Disk) Close() error {
+	close(c.done)
+	c.wg.Wait()
+
+	return c.db.Close()
+}
+
+// reset drops all entries from the database, and stores the namespace of the
+// cache in their place.
+func (c *Disk) reset() error {
+	err := c.db.DropAll()
+	if err != nil {
+		return fmt.Errorf("could not drop entries: %w", err)
+	}
+
+	err = c.db.Update(func(tx *badger.Txn) error {
+		return tx.Set([]byte{prefixNamespace}, c.namespace)
+	})
+	if err != nil {
+		return fmt.Errorf("could not store namespace: %w", err)
+	}
+
+	return nil
+}
+
+// collect periodically garbage collects the value log, until the cache is
+// closed. Each run rewrites value log files for as long as some of them have
+// enough data of deleted entries to be worth it.
+func (c *Disk) collect() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(gcInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		for c.db.RunValueLogGC(gcRatio) == nil {
+		}
+	}
+}
+
+// evict removes the oldest entry from the cache, and returns its cost.
+func (c *Disk) evict() (uint64, error) {
+	var cost uint64
+	err := c.db.Update(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{Prefix: []byte{prefixOrder}})
+		defer it.Close()
+
+		it.Rewind()
+		if !it.Valid() {
+			return errors.New("cache is empty")
+		}
+		item := it.Item()
+		order, err := item.ValueCopy(nil)
+		if err != nil {
+			return fmt.Errorf("could not read entry order: %w", err)
+		}
+		cost = binary.BigEndian.Uint64(order)
+
+		err = tx.Delete(entryKey(order[8:]))
+		if err != nil {
+			return err
+		}
+		return tx.Delete(item.KeyCopy(nil))
+	})
+
+	return cost, err
+}
+
+func entryKey(key []byte) []byte {
+	return append([]byte{prefixEntry}, key...)
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisk(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewDisk("test", t.TempDir(), 100, nil)
+		require.NoError(t, err)
+		defer c.Close()
+
+		require.NoError(t, c.Set([]byte("1"), []byte("one")))
+		require.NoError(t, c.Set([]byte("2"), []byte("two")))
+
+		value, ok := c.Get([]byte("1"))
+		require.True(t, ok)
+		assert.Equal(t, []byte("one"), value)
+		assert.Equal(t, uint64(8), c.Size())
+
+		_, ok = c.Get([]byte("3"))
+		assert.False(t, ok)
+	})
+
+	t.Run("keeps existing entry", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewDisk("test", t.TempDir(), 100, nil)
+		require.NoError(t, err)
+		defer c.Close()
+
+		require.NoError(t, c.Set([]byte("1"), []byte("one")))
+		require.NoError(t, c.Set([]byte("1"), []byte("uno")))
+
+		value, ok := c.Get([]byte("1"))
+		require.True(t, ok)
+		assert.Equal(t, []byte("one"), value)
+		assert.Equal(t, uint64(4), c.Size())
+	})
+
+	t.Run("evicts oldest entries", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewDisk("test", t.TempDir(), 12, nil)
+		require.NoError(t, err)
+		defer c.Close()
+
+		require.NoError(t, c.Set([]byte("1"), []byte("one")))
+		require.NoError(t, c.Set([]byte("2"), []byte("two")))
+		require.NoError(t, c.Set([]byte("3"), []byte("six")))
+		require.NoError(t, c.Set([]byte("4"), []byte("ten")))
+
+		_, ok := c.Get([]byte("1"))
+		assert.False(t, ok)
+		for _, key := range []string{"2", "3", "4"} {
+			_, ok := c.Get([]byte(key))
+			assert.True(t, ok)
+		}
+		assert.Equal(t, uint64(12), c.Size())
+	})
+
+	t.Run("skips entries larger than limit", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewDisk("test", t.TempDir(), 12, nil)
+		require.NoError(t, err)
+		defer c.Close()
+
+		require.NoError(t, c.Set([]byte("1"), []byte("one")))
+		require.NoError(t, c.Set([]byte("2"), []byte("larger than the limit")))
+
+		_, ok := c.Get([]byte("2"))
+		assert.False(t, ok)
+		_, ok = c.Get([]byte("1"))
+		assert.True(t, ok)
+	})
+
+	t.Run("keeps entries across restarts", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		c, err := NewDisk("test", dir, 12, nil)
+		require.NoError(t, err)
+		require.NoError(t, c.Set([]byte("1"), []byte("one")))
+		require.NoError(t, c.Set([]byte("2"), []byte("two")))
+		require.NoError(t, c.Close())
+
+		c, err = NewDisk("test", dir, 12, nil)
+		require.NoError(t, err)
+		defer c.Close()
+
+		value, ok := c.Get([]byte("2"))
+		require.True(t, ok)
+		assert.Equal(t, []byte("two"), value)
+		assert.Equal(t, uint64(8), c.Size())
+
+		// Eviction order is preserved across restarts as well.
+		require.NoError(t, c.Set([]byte("3"), []byte("six")))
+		require.NoError(t, c.Set([]byte("4"), []byte("ten")))
+
+		_, ok = c.Get([]byte("1"))
+		assert.False(t, ok)
+		_, ok = c.Get([]byte("2"))
+		assert.True(t, ok)
+	})
+	t.Run("flushes all entries", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewDisk("test", t.TempDir(), 100, nil)
+		require.NoError(t, err)
+		defer c.Close()
+
+		require.NoError(t, c.Set([]byte("1"), []byte("one")))
+		require.NoError(t, c.Set([]byte("2"), []byte("two")))
+
+		require.NoError(t, c.Flush())
+
+		_, ok := c.Get([]byte("1"))
+		assert.False(t, ok)
+		assert.Equal(t, uint64(0), c.Size())
+
+		require.NoError(t, c.Set([]byte("3"), []byte("six")))
+		value, ok := c.Get([]byte("3"))
+		require.True(t, ok)
+		assert.Equal(t, []byte("six"), value)
+	})
+
+	t.Run("flushes entries of another namespace", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		c, err := NewDisk("test", dir, 100, []byte("mainnet"))
+		require.NoError(t, err)
+		require.NoError(t, c.Set([]byte("1"), []byte("one")))
+		require.NoError(t, c.Close())
+
+		c, err = NewDisk("test", dir, 100, []byte("mainnet"))
+		require.NoError(t, err)
+		_, ok := c.Get([]byte("1"))
+		assert.True(t, ok)
+		require.NoError(t, c.Close())
+
+		c, err = NewDisk("test", dir, 100, []byte("testnet"))
+		require.NoError(t, err)
+		defer c.Close()
+
+		_, ok = c.Get([]byte("1"))
+		assert.False(t, ok)
+		assert.Equal(t, uint64(0), c.Size())
+
+		// Entries of the new namespace are kept.
+		require.NoError(t, c.Set([]byte("2"), []byte("two")))
+		_, ok = c.Get([]byte("2"))
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// LRU is a cache that is limited by the total size of its entries, and which
+// evicts its least recently used entries first. Its size also counts towards
+// the budget of the manager that created it.
+type LRU struct {
+	name    string
+	max     uint64
+	manager *Manager
+	size    atomic.Uint64
+
+	mu      sync.Mutex
+	entries map[interface{}]*list.Element
+	order   *list.List
+}
+
+type entry struct {
+	key   interface{}
+	value interface{}
+	cost  uint64
+}
+
+func newLRU(name string, max uint64, manager *Manager) *LRU {
+	c := LRU{
+		name:    name,
+		max:     max,
+		manager: manager,
+		entries: make(map[interface{}]*list.Element),
+		order:   list.New(),
+	}
+
+	return &c
+}
+
+// Get returns the value stored for the given key, if there is one, and marks
+// it as the most recently used.
+func (c *LRU) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	return element.Value.(*entry).value, true
+}
+
+// Set stores the given value for the given key, with the given cost in bytes.
+// Values that are larger than the cache's limit are not stored.
+func (c *LRU) Set(key interface{}, value interface{}, cost uint64) {
+	if c.max > 0 && cost > c.max {
+		return
+	}
+
+	c.mu.Lock()
+	element, ok := c.entries[key]
+	if ok {
+		c.remove(element)
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value, cost: cost})
+	c.size.Add(cost)
+	for c.max > 0 && c.size.Load() > c.max {
+		c.remove(c.order.Back())
+		cacheEvictions.WithLabelValues(c.name).Inc()
+	}
+	cacheSize.WithLabelValues(c.name).Set(float64(c.size.Load()))
+	c.mu.Unlock()
+
+	// The manager locks the caches it evicts from, so it has to be called
+	// without holding the lock on this cache.
+	c.manager.rebalance()
+}
+
+// Name returns the name of the cache.
+func (c *LRU) Name() string {
+	return c.name
+}
+
+// Flush removes all entries from the cache.
+func (c *LRU) Flush() {
+	c.mu.Lock()
+	c.entries = make(map[interface{}]*list.Element)
+	c.order.Init()
+	c.size.Store(0)
+	cacheSize.WithLabelValues(c.name).Set(0)
+	c.mu.Unlock()
+
+	// Rebalancing updates the total size of the manager's caches.
+	c.manager.rebalance()
+}
+
+// Size returns the total cost of the entries in the cache, in bytes.
+func (c *LRU) Size() uint64 {
+	return c.size.Load()
+}
+
+// evict removes the least recently used entry from the cache, and returns its
+// cost. It returns false if the cache is empty.
+func (c *LRU) evict() (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element := c.order.Back()
+	if element == nil {
+		return 0, false
+	}
+	cost := element.Value.(*entry).cost
+	c.remove(element)
+	cacheEvictions.WithLabelValues(c.name).Inc()
+	cacheSize.WithLabelValues(c.name).Set(float64(c.size.Load()))
+
+	return cost, true
+}
+
+func (c *LRU) remove(element *list.Element) {
+	e := element.Value.(*entry)
+	delete(c.entries, e.key)
+	c.order.Remove(element)
+	c.size.Add(^(e.cost - 1))
+}
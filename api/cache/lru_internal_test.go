@@ -0,0 +1,87 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewManager(0).NewLRU("test", 100)
+		c.Set(1, "one", 10)
+		c.Set(2, "two", 20)
+
+		value, ok := c.Get(1)
+		require.True(t, ok)
+		assert.Equal(t, "one", value)
+		assert.Equal(t, uint64(30), c.Size())
+
+		_, ok = c.Get(3)
+		assert.False(t, ok)
+	})
+
+	t.Run("replaces existing entry", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewManager(0).NewLRU("test", 100)
+		c.Set(1, "one", 10)
+		c.Set(1, "uno", 15)
+
+		value, ok := c.Get(1)
+		require.True(t, ok)
+		assert.Equal(t, "uno", value)
+		assert.Equal(t, uint64(15), c.Size())
+	})
+
+	t.Run("evicts least recently used entries", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewManager(0).NewLRU("test", 30)
+		c.Set(1, "one", 10)
+		c.Set(2, "two", 10)
+		c.Set(3, "three", 10)
+
+		// Use the oldest entry, so that the second one is evicted instead.
+		_, _ = c.Get(1)
+		c.Set(4, "four", 10)
+
+		_, ok := c.Get(2)
+		assert.False(t, ok)
+		for _, key := range []int{1, 3, 4} {
+			_, ok := c.Get(key)
+			assert.True(t, ok)
+		}
+		assert.Equal(t, uint64(30), c.Size())
+	})
+
+	t.Run("skips entries larger than limit", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewManager(0).NewLRU("test", 30)
+		c.Set(1, "one", 10)
+		c.Set(2, "two", 40)
+
+		_, ok := c.Get(2)
+		assert.False(t, ok)
+		_, ok = c.Get(1)
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+import (
+	"sync"
+)
+
+// Manager keeps track of the aggregate size of a set of caches, and enforces a
+// global budget across them. Whenever the budget is exceeded, entries are
+// evicted from the largest cache until the caches fit within it again.
+type Manager struct {
+	budget uint64
+
+	mu     sync.Mutex
+	caches []*LRU
+}
+
+// NewManager creates a manager that keeps the total size of its caches below
+// the given budget in bytes. A budget of zero means that only the limits of the
+// individual caches apply.
+func NewManager(budget uint64) *Manager {
+	m := Manager{
+		budget: budget,
+	}
+
+	return &m
+}
+
+// NewLRU creates a new cache with the given name, whose size is limited to the
+// given number of bytes, and which counts towards the manager's budget. A size
+// of zero means that only the manager's budget applies.
+func (m *Manager) NewLRU(name string, size uint64) *LRU {
+	c := newLRU(name, size, m)
+
+	m.mu.Lock()
+	m.caches = append(m.caches, c)
+	m.mu.Unlock()
+
+	return c
+}
+
+// Flush removes all entries from the manager's cache with the given name, or
+// from all of its caches if the name is empty, and returns the names of the
+// caches that were flushed.
+func (m *Manager) Flush(name string) []string {
+	m.mu.Lock()
+	var caches []*LRU
+	for _, c := range m.caches {
+		if name == "" || c.Name() == name {
+			caches = append(caches, c)
+		}
+	}
+	m.mu.Unlock()
+
+	// Caches lock the manager when they are flushed, so it has to be unlocked.
+	flushed := make([]string, 0, len(caches))
+	for _, c := range caches {
+		c.Flush()
+		flushed = append(flushed, c.Name())
+	}
+
+	return flushed
+}
+
+// Size returns the total size of all the manager's caches in bytes.
+func (m *Manager) Size() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.size()
+}
+
+func (m *Manager) size() uint64 {
+	var total uint64
+	for _, c := range m.caches {
+		total += c.Size()
+	}
+
+	return total
+}
+
+// rebalance evicts entries from the largest cache until the total size of the
+// caches is within budget again.
+func (m *Manager) rebalance() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := m.size()
+	for m.budget > 0 && total > m.budget {
+		var largest *LRU
+		for _, c := range m.caches {
+			if largest == nil || c.Size() > largest.Size() {
+				largest = c
+			}
+		}
+
+		evicted, ok := largest.evict()
+		if !ok {
+			break
+		}
+		total -= evicted
+	}
+
+	cacheTotalSize.Set(float64(total))
+}
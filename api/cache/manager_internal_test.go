@@ -0,0 +1,137 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		m := NewManager(100)
+		blocks := m.NewLRU("blocks", 0)
+		accounts := m.NewLRU("accounts", 0)
+
+		blocks.Set(1, "block", 30)
+		accounts.Set(1, "account", 40)
+
+		assert.Equal(t, uint64(70), m.Size())
+	})
+
+	t.Run("evicts from largest cache when over budget", func(t *testing.T) {
+		t.Parallel()
+
+		m := NewManager(100)
+		blocks := m.NewLRU("blocks", 0)
+		accounts := m.NewLRU("accounts", 0)
+
+		for i := 0; i < 6; i++ {
+			blocks.Set(i, "block", 10)
+		}
+		for i := 0; i < 3; i++ {
+			accounts.Set(i, "account", 10)
+		}
+		assert.Equal(t, uint64(90), m.Size())
+
+		// Going over budget evicts the oldest block, as the block cache is the
+		// largest one, while the account cache is left untouched.
+		accounts.Set(3, "account", 20)
+
+		assert.LessOrEqual(t, m.Size(), uint64(100))
+		assert.Equal(t, uint64(50), accounts.Size())
+		assert.Equal(t, uint64(50), blocks.Size())
+		_, ok := blocks.Get(0)
+		assert.False(t, ok)
+		_, ok = blocks.Get(5)
+		assert.True(t, ok)
+	})
+
+	t.Run("evicts across caches under tight budget", func(t *testing.T) {
+		t.Parallel()
+
+		m := NewManager(25)
+		blocks := m.NewLRU("blocks", 0)
+		accounts := m.NewLRU("accounts", 0)
+
+		for i := 0; i < 10; i++ {
+			blocks.Set(i, "block", 10)
+			accounts.Set(i, "account", 10)
+			assert.LessOrEqual(t, m.Size(), uint64(25))
+		}
+
+		_, ok := accounts.Get(9)
+		assert.True(t, ok)
+	})
+
+	t.Run("does not limit caches without budget", func(t *testing.T) {
+		t.Parallel()
+
+		m := NewManager(0)
+		blocks := m.NewLRU("blocks", 0)
+
+		for i := 0; i < 100; i++ {
+			blocks.Set(i, "block", 1000)
+		}
+
+		assert.Equal(t, uint64(100_000), m.Size())
+	})
+	t.Run("flushes caches by name", func(t *testing.T) {
+		t.Parallel()
+
+		m := NewManager(100)
+		blocks := m.NewLRU("blocks", 0)
+		accounts := m.NewLRU("accounts", 0)
+
+		blocks.Set(1, "block", 30)
+		accounts.Set(1, "account", 40)
+
+		flushed := m.Flush("blocks")
+
+		assert.Equal(t, []string{"blocks"}, flushed)
+		assert.Equal(t, uint64(0), blocks.Size())
+		assert.Equal(t, uint64(40), m.Size())
+		_, ok := blocks.Get(1)
+		assert.False(t, ok)
+		_, ok = accounts.Get(1)
+		assert.True(t, ok)
+
+		assert.Empty(t, m.Flush("unknown"))
+	})
+
+	t.Run("flushes all caches without name", func(t *testing.T) {
+		t.Parallel()
+
+		m := NewManager(100)
+		blocks := m.NewLRU("blocks", 0)
+		accounts := m.NewLRU("accounts", 0)
+
+		blocks.Set(1, "block", 30)
+		accounts.Set(1, "account", 40)
+
+		flushed := m.Flush("")
+
+		assert.ElementsMatch(t, []string{"blocks", "accounts"}, flushed)
+		assert.Equal(t, uint64(0), m.Size())
+
+		// Flushed caches remain usable.
+		blocks.Set(2, "block", 10)
+		_, ok := blocks.Get(2)
+		assert.True(t, ok)
+	})
+}
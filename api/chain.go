@@ -0,0 +1,34 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// ParseChain returns the chain with the given ID. Unlike `flow.ChainID.Chain`,
+// it returns an error rather than panicking for chain IDs that are not known,
+// such as the ones of custom networks.
+func ParseChain(id flow.ChainID) (chain flow.Chain, err error) {
+	defer func() {
+		if recover() != nil {
+			chain, err = nil, fmt.Errorf("unknown chain ID %q", id)
+		}
+	}()
+
+	return id.Chain(), nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+)
+
+func TestParseChain(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		chain, err := ParseChain(flow.Mainnet)
+
+		require.NoError(t, err)
+		assert.Equal(t, flow.Mainnet, chain.ChainID())
+	})
+
+	t.Run("handles unknown chain ID", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseChain("flow-custom")
+
+		assert.Error(t, err)
+	})
+}
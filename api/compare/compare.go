@@ -0,0 +1,283 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Difference is a field whose value differs between two messages.
+type Difference struct {
+	// Path is the path of the field from the root of the messages, such as
+	// `block.block_seals[2].block_id`, with list indices and map keys in
+	// brackets.
+	Path string
+	// First and Second are the printable values of the field in the first and
+	// second messages, or `<unset>` if the field is not set in one of them.
+	First  string
+	Second string
+}
+
+// String implements the fmt.Stringer interface.
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Path, d.First, d.Second)
+}
+
+// unset is the printable value of fields that are not set.
+const unset = "<unset>"
+
+// Responses returns a description of the difference between the responses of
+// the archive and of the access node, or an empty string if they match. Errors
+// are considered to match when they have the same status code, as their
+// messages differ between implementations. The ignored fields are cleared from
+// both responses before they are compared.
+func Responses(archiveResp proto.Message, archiveErr error, accessResp proto.Message, accessErr error, ignored map[protoreflect.FullName]struct{}) string {
+	if archiveResp != nil {
+		Clear(proto.MessageReflect(archiveResp), ignored)
+	}
+	if accessResp != nil {
+		Clear(proto.MessageReflect(accessResp), ignored)
+	}
+
+	switch {
+	case archiveErr != nil && accessErr != nil:
+		archiveCode, accessCode := status.Code(archiveErr), status.Code(accessErr)
+		if archiveCode == accessCode {
+			return ""
+		}
+		return fmt.Sprintf("error codes differ: archive returned %s (%v), access node returned %s (%v)", archiveCode, archiveErr, accessCode, accessErr)
+
+	case archiveErr != nil:
+		return fmt.Sprintf("only archive returned an error: %v", archiveErr)
+
+	case accessErr != nil:
+		return fmt.Sprintf("only access node returned an error: %v", accessErr)
+	}
+
+	differences := Fields(archiveResp, accessResp)
+	if len(differences) == 0 {
+		return ""
+	}
+
+	descriptions := make([]string, 0, len(differences))
+	for _, difference := range differences {
+		descriptions = append(descriptions, difference.String())
+	}
+
+	return fmt.Sprintf("responses differ (archive != access node): %s", strings.Join(descriptions, "; "))
+}
+
+// Fields returns the fields whose values differ between the given messages,
+// which must be of the same type, down to their nested messages, lists and maps.
+// Differences are ordered by field number, then by list index or map key.
+func Fields(first proto.Message, second proto.Message) []Difference {
+	return messages("", proto.MessageReflect(first), proto.MessageReflect(second))
+}
+
+// messages returns the differences between the fields of two messages of the
+// same type, whose paths are prefixed with the given path.
+func messages(prefix string, first protoreflect.Message, second protoreflect.Message) []Difference {
+	var differences []Difference
+
+	fields := first.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		path := string(field.Name())
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		switch {
+		case field.IsList():
+			differences = append(differences, lists(path, field, first.Get(field).List(), second.Get(field).List())...)
+
+		case field.IsMap():
+			differences = append(differences, maps(path, field, first.Get(field).Map(), second.Get(field).Map())...)
+
+		// Scalars without presence, such as most proto3 fields, are never
+		// unset, so they are compared by value below.
+		case field.HasPresence() && first.Has(field) != second.Has(field):
+			differences = append(differences, Difference{
+				Path:   path,
+				First:  present(field, first),
+				Second: present(field, second),
+			})
+
+		case field.Message() != nil:
+			if first.Has(field) {
+				differences = append(differences, messages(path, first.Get(field).Message(), second.Get(field).Message())...)
+			}
+
+		case !equal(first.Get(field), second.Get(field)):
+			differences = append(differences, Difference{
+				Path:   path,
+				First:  format(field, first.Get(field)),
+				Second: format(field, second.Get(field)),
+			})
+		}
+	}
+
+	return differences
+}
+
+// lists returns the differences between the elements of two lists, with each
+// element that only one of them has reported as unset in the other.
+func lists(path string, field protoreflect.FieldDescriptor, first protoreflect.List, second protoreflect.List) []Difference {
+	var differences []Difference
+	for i := 0; i < first.Len() || i < second.Len(); i++ {
+		element := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= second.Len():
+			differences = append(differences, Difference{Path: element, First: format(field, first.Get(i)), Second: unset})
+		case i >= first.Len():
+			differences = append(differences, Difference{Path: element, First: unset, Second: format(field, second.Get(i))})
+		case field.Message() != nil:
+			differences = append(differences, messages(element, first.Get(i).Message(), second.Get(i).Message())...)
+		case !equal(first.Get(i), second.Get(i)):
+			differences = append(differences, Difference{Path: element, First: format(field, first.Get(i)), Second: format(field, second.Get(i))})
+		}
+	}
+
+	return differences
+}
+
+// maps returns the differences between the entries of two maps, with each entry
+// that only one of them has reported as unset in the other.
+func maps(path string, field protoreflect.FieldDescriptor, first protoreflect.Map, second protoreflect.Map) []Difference {
+	keys := make(map[string]protoreflect.MapKey)
+	collect := func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys[key.String()] = key
+		return true
+	}
+	first.Range(collect)
+	second.Range(collect)
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	value := field.MapValue()
+	var differences []Difference
+	for _, name := range names {
+		key := keys[name]
+		entry := fmt.Sprintf("%s[%s]", path, name)
+		switch {
+		case !second.Has(key):
+			differences = append(differences, Difference{Path: entry, First: format(value, first.Get(key)), Second: unset})
+		case !first.Has(key):
+			differences = append(differences, Difference{Path: entry, First: unset, Second: format(value, second.Get(key))})
+		case value.Message() != nil:
+			differences = append(differences, messages(entry, first.Get(key).Message(), second.Get(key).Message())...)
+		case !equal(first.Get(key), second.Get(key)):
+			differences = append(differences, Difference{Path: entry, First: format(value, first.Get(key)), Second: format(value, second.Get(key))})
+		}
+	}
+
+	return differences
+}
+
+// present returns the printable value of the given field of a message, or
+// `<unset>` if it is not set.
+func present(field protoreflect.FieldDescriptor, msg protoreflect.Message) string {
+	if !msg.Has(field) {
+		return unset
+	}
+
+	return format(field, msg.Get(field))
+}
+
+// equal returns whether two scalar values are equal.
+func equal(first protoreflect.Value, second protoreflect.Value) bool {
+	firstBytes, ok := first.Interface().([]byte)
+	if ok {
+		return bytes.Equal(firstBytes, second.Bytes())
+	}
+
+	return first.Interface() == second.Interface()
+}
+
+// format returns the printable representation of a single value of the given
+// field: hexadecimal for bytes, names for enums and JSON for messages.
+func format(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("%x", value.Bytes())
+	case protoreflect.StringKind:
+		return fmt.Sprintf("%q", value.String())
+	case protoreflect.EnumKind:
+		enum := field.Enum().Values().ByNumber(value.Enum())
+		if enum == nil {
+			return fmt.Sprint(value.Enum())
+		}
+		return string(enum.Name())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return Encode(proto.MessageV1(value.Message().Interface()))
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+// Clear clears the fields of the given message with the given full names,
+// including in its nested messages.
+func Clear(msg protoreflect.Message, ignored map[protoreflect.FullName]struct{}) {
+	if len(ignored) == 0 {
+		return
+	}
+
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		_, ok := ignored[field.FullName()]
+		if ok {
+			msg.Clear(field)
+			return true
+		}
+
+		switch {
+		case field.IsList() && field.Message() != nil:
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				Clear(list.Get(i).Message(), ignored)
+			}
+		case field.IsMap() && field.MapValue().Message() != nil:
+			value.Map().Range(func(_ protoreflect.MapKey, value protoreflect.Value) bool {
+				Clear(value.Message(), ignored)
+				return true
+			})
+		case !field.IsList() && !field.IsMap() && field.Message() != nil:
+			Clear(value.Message(), ignored)
+		}
+
+		return true
+	})
+}
+
+// Encode returns the JSON encoding of a message for logging.
+func Encode(msg proto.Message) string {
+	out, err := (&jsonpb.Marshaler{}).MarshalToString(msg)
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+
+	return out
+}
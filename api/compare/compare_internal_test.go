@@ -0,0 +1,144 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package compare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+
+	"github.com/onflow/flow-archive-access/api/extended"
+)
+
+func TestFields(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		first := &access.BlockResponse{Block: &entities.Block{
+			Id:         []byte{0x01, 0x02},
+			Height:     42,
+			BlockSeals: []*entities.BlockSeal{{BlockId: []byte{0x01}}, {BlockId: []byte{0x02}}},
+		}}
+		second := &access.BlockResponse{Block: &entities.Block{
+			Id:         []byte{0x01, 0x03},
+			Height:     43,
+			Timestamp:  timestamppb.Now(),
+			BlockSeals: []*entities.BlockSeal{{BlockId: []byte{0x01}}},
+		}}
+
+		got := Fields(first, second)
+
+		require.Len(t, got, 4)
+		assert.Equal(t, Difference{Path: "block.id", First: "0102", Second: "0103"}, got[0])
+		assert.Equal(t, Difference{Path: "block.height", First: "42", Second: "43"}, got[1])
+		assert.Equal(t, "block.timestamp", got[2].Path)
+		assert.Equal(t, unset, got[2].First)
+		assert.Equal(t, Difference{Path: "block.block_seals[1]", First: `{"blockId":"Ag=="}`, Second: unset}, got[3])
+	})
+
+	t.Run("handles identical messages", func(t *testing.T) {
+		t.Parallel()
+
+		msg := &access.BlockResponse{Block: &entities.Block{Height: 42}}
+
+		got := Fields(msg, msg)
+
+		assert.Empty(t, got)
+	})
+
+	t.Run("handles nested list elements", func(t *testing.T) {
+		t.Parallel()
+
+		first := &access.BlockResponse{Block: &entities.Block{BlockSeals: []*entities.BlockSeal{{BlockId: []byte{0x01}}}}}
+		second := &access.BlockResponse{Block: &entities.Block{BlockSeals: []*entities.BlockSeal{{BlockId: []byte{0x02}}}}}
+
+		got := Fields(first, second)
+
+		assert.Equal(t, []Difference{{Path: "block.block_seals[0].block_id", First: "01", Second: "02"}}, got)
+	})
+
+	t.Run("handles enums and optional fields", func(t *testing.T) {
+		t.Parallel()
+
+		finalized := uint64(5)
+		first := &extended.LatestHeightsResponse{SealedHeight: 4}
+		second := &extended.LatestHeightsResponse{SealedHeight: 4, FinalizedHeight: &finalized}
+
+		got := Fields(first, second)
+		assert.Equal(t, []Difference{{Path: "finalized_height", First: unset, Second: "5"}}, got)
+
+		firstResult := &access.TransactionResultResponse{Status: entities.TransactionStatus_SEALED}
+		secondResult := &access.TransactionResultResponse{Status: entities.TransactionStatus_EXECUTED}
+
+		got = Fields(firstResult, secondResult)
+		assert.Equal(t, []Difference{{Path: "status", First: "SEALED", Second: "EXECUTED"}}, got)
+	})
+
+	t.Run("handles zero scalars without presence", func(t *testing.T) {
+		t.Parallel()
+
+		first := &access.BlockResponse{Block: &entities.Block{Height: 0}}
+		second := &access.BlockResponse{Block: &entities.Block{Height: 1}}
+
+		got := Fields(first, second)
+
+		assert.Equal(t, []Difference{{Path: "block.height", First: "0", Second: "1"}}, got)
+	})
+}
+
+func TestResponses(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		first := &access.BlockResponse{Block: &entities.Block{Height: 42}}
+		second := &access.BlockResponse{Block: &entities.Block{Height: 43}}
+
+		got := Responses(first, nil, second, nil, nil)
+
+		assert.Equal(t, "responses differ (archive != access node): block.height: 42 != 43", got)
+	})
+
+	t.Run("clears ignored fields", func(t *testing.T) {
+		t.Parallel()
+
+		first := &access.BlockResponse{Block: &entities.Block{Height: 42, BlockSeals: []*entities.BlockSeal{{ResultId: []byte{0x01}}}}}
+		second := &access.BlockResponse{Block: &entities.Block{Height: 42, BlockSeals: []*entities.BlockSeal{{}}}}
+		ignored := map[protoreflect.FullName]struct{}{"flow.entities.BlockSeal.result_id": {}}
+
+		got := Responses(first, nil, second, nil, ignored)
+
+		assert.Empty(t, got)
+	})
+
+	t.Run("matches errors with the same code", func(t *testing.T) {
+		t.Parallel()
+
+		archiveErr := status.Error(codes.NotFound, "archive")
+		accessErr := status.Error(codes.NotFound, "access")
+
+		assert.Empty(t, Responses(nil, archiveErr, nil, accessErr, nil))
+		assert.Contains(t, Responses(nil, status.Error(codes.Internal, "archive"), nil, accessErr, nil), "error codes differ")
+		assert.Contains(t, Responses(nil, archiveErr, &access.BlockResponse{}, nil, nil), "only archive returned an error")
+		assert.Contains(t, Responses(&access.BlockResponse{}, nil, nil, accessErr, nil), "only access node returned an error")
+	})
+}
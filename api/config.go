@@ -0,0 +1,405 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/onflow/flow-archive/models/archive"
+
+	"github.com/onflow/flow-archive-access/api/cache"
+	"github.com/onflow/flow-archive-access/api/topk"
+)
+
+// DefaultConfig is the default configuration for the Access API server.
+var DefaultConfig = Config{
+	MaxRegisters:        1000,
+	AllowUnsealedBlocks: true,
+	StrictBlockAssembly: true,
+	StrictArguments:     true,
+	ScriptWorkers:       8,
+	IncludeSystemTx:     true,
+	MaxBatchSize:        1000,
+	BatchWorkers:        16,
+	MaxBlockIDs:         50,
+	Mode:                ModeArchiveOnly,
+	RangeRefresh:        time.Second,
+	PollInterval:        time.Second,
+	RangeRetries:        2,
+	RangeBackoff:        100 * time.Millisecond,
+	Log:                 zerolog.Nop(),
+}
+
+// Config is the configuration for the Access API server.
+type Config struct {
+	MaxRegisters        uint
+	NewInvoker          func(index archive.Reader) (Invoker, error)
+	AllowUnsealedBlocks bool
+	ScriptWorkers       uint
+	NewIndex            func(ctx context.Context) archive.Reader
+	BlockCache          *cache.LRU
+	DiskCache           *cache.Disk
+	IncludeSystemTx     bool
+	MaxBatchSize        uint
+	BatchWorkers        uint
+	Submitter           Submitter
+	Snapshots           SnapshotSource
+	Upstream            Upstream
+	MaxBlockIDs         uint
+	HeightWindow        uint64
+	PinnedHead          uint64
+	RequestBudget       uint64
+	MeterScripts        bool
+	Addresses           *topk.Counter
+	AllowedEventTypes   []flow.EventType
+	DeniedEventTypes    []flow.EventType
+	DecodeEvents        bool
+	StrictDecoding      bool
+	StrictArguments     bool
+	Mode                Mode
+	ProxyOverrides      map[string]bool
+	RangeRefresh        time.Duration
+	PollInterval        time.Duration
+	RangeRetries        uint
+	RangeBackoff        time.Duration
+	FullSeals           bool
+	StrictBlockAssembly bool
+	ValidateTimestamps  bool
+	ResolveDuplicates   bool
+	SystemTxChain       flow.ChainID
+	Log                 zerolog.Logger
+}
+
+// Option is an option that can be given to the server to modify its configuration.
+type Option func(*Config)
+
+// WithMaxRegisters sets the maximum number of raw registers returned when
+// looking up the registers backing an account.
+func WithMaxRegisters(max uint) Option {
+	return func(cfg *Config) {
+		cfg.MaxRegisters = max
+	}
+}
+
+// WithInvokerFactory sets the function used to create a dedicated invoker on
+// top of a given index reader. It is needed to look up the raw registers that
+// back an account, as they have to be read with an empty register cache.
+func WithInvokerFactory(factory func(index archive.Reader) (Invoker, error)) Option {
+	return func(cfg *Config) {
+		cfg.NewInvoker = factory
+	}
+}
+
+// WithAllowUnsealedBlocks sets whether blocks for which no seals have been
+// indexed yet are returned with empty seals, or rejected as unavailable.
+func WithAllowUnsealedBlocks(allow bool) Option {
+	return func(cfg *Config) {
+		cfg.AllowUnsealedBlocks = allow
+	}
+}
+
+// WithScriptWorkers sets the maximum number of scripts from script streams that
+// are executed concurrently, across all streams.
+func WithScriptWorkers(workers uint) Option {
+	return func(cfg *Config) {
+		cfg.ScriptWorkers = workers
+	}
+}
+
+// WithIndexFactory sets the function used to get the index reader for a request,
+// given its context. It allows request-scoped metadata, such as the request ID,
+// to be propagated to the archive. By default, the server's index is used.
+func WithIndexFactory(factory func(ctx context.Context) archive.Reader) Option {
+	return func(cfg *Config) {
+		cfg.NewIndex = factory
+	}
+}
+
+// WithBlockCache sets the cache used to store serialized block responses by
+// height. The cost of each entry is the size of the serialized response. By
+// default, block responses are not cached.
+func WithBlockCache(blocks *cache.LRU) Option {
+	return func(cfg *Config) {
+		cfg.BlockCache = blocks
+	}
+}
+
+// WithDiskCache sets the persistent cache to which block responses are written
+// through, on top of the block cache, so that they survive restarts. Like with
+// the block cache, only heights below the last indexed height are cached. By
+// default, responses are not cached on disk.
+func WithDiskCache(disk *cache.Disk) Option {
+	return func(cfg *Config) {
+		cfg.DiskCache = disk
+	}
+}
+
+// WithSystemTxChain sets the chain for which the system chunk transaction is
+// built, overriding the chain derived from the block header. It allows custom
+// networks, whose chain IDs do not map to a known chain, to use the system
+// transaction of a known one.
+func WithSystemTxChain(chain flow.ChainID) Option {
+	return func(cfg *Config) {
+		cfg.SystemTxChain = chain
+	}
+}
+
+// WithLogger sets the logger used to report conditions that do not fail
+// requests. By default, nothing is logged.
+func WithLogger(log zerolog.Logger) Option {
+	return func(cfg *Config) {
+		cfg.Log = log
+	}
+}
+
+// WithFullSeals sets whether the seals of blocks are returned with their result
+// ID, final state and aggregated approval signatures, as access nodes return
+// them, rather than only with the IDs of the sealed block and of its result. It
+// is disabled by default, as the signatures make block responses much larger.
+func WithFullSeals(full bool) Option {
+	return func(cfg *Config) {
+		cfg.FullSeals = full
+	}
+}
+
+// WithStrictBlockAssembly sets whether blocks that reference a collection whose
+// guarantee is missing from the index fail to be assembled. When disabled, such
+// collections are left out of the block instead. It is enabled by default.
+func WithStrictBlockAssembly(strict bool) Option {
+	return func(cfg *Config) {
+		cfg.StrictBlockAssembly = strict
+	}
+}
+
+// WithIncludeSystemTx sets whether the system chunk transaction is appended to
+// the transactions returned for a block.
+func WithIncludeSystemTx(include bool) Option {
+	return func(cfg *Config) {
+		cfg.IncludeSystemTx = include
+	}
+}
+
+// WithMaxBatchSize sets the maximum number of items that can be requested at
+// once from batch endpoints.
+func WithMaxBatchSize(max uint) Option {
+	return func(cfg *Config) {
+		cfg.MaxBatchSize = max
+	}
+}
+
+// WithBatchWorkers sets the number of items of a batch request that are looked
+// up concurrently.
+func WithBatchWorkers(workers uint) Option {
+	return func(cfg *Config) {
+		cfg.BatchWorkers = workers
+	}
+}
+
+// WithSubmitter sets the submitter to which transactions sent to the server are
+// forwarded. By default, the server does not accept transactions.
+func WithSubmitter(submitter Submitter) Option {
+	return func(cfg *Config) {
+		cfg.Submitter = submitter
+	}
+}
+
+// WithSnapshotSource sets the source from which protocol state snapshots are
+// served. By default, the server has no snapshots, and requests for them are
+// proxied to the upstream access node, if any.
+func WithSnapshotSource(snapshots SnapshotSource) Option {
+	return func(cfg *Config) {
+		cfg.Snapshots = snapshots
+	}
+}
+
+// WithUpstream sets the upstream access node that the server queries for the
+// latest finalized block. By default, no upstream is queried.
+func WithUpstream(upstream Upstream) Option {
+	return func(cfg *Config) {
+		cfg.Upstream = upstream
+	}
+}
+
+// WithMaxBlockIDs sets the maximum number of block IDs that can be given to a
+// single `GetEventsForBlockIDs` request.
+func WithMaxBlockIDs(max uint) Option {
+	return func(cfg *Config) {
+		cfg.MaxBlockIDs = max
+	}
+}
+
+// WithHeightWindow limits the heights served to the given number of most recent
+// heights, for archives that prune older heights. A window of zero serves the
+// whole indexed range, which is the default.
+func WithHeightWindow(window uint64) Option {
+	return func(cfg *Config) {
+		cfg.HeightWindow = window
+	}
+}
+
+// WithPinnedHead freezes the view of the index served at the given height,
+// which is then reported as the last indexed height even as indexing goes on,
+// and above which heights are rejected and blocks and transactions are not
+// found. A height of zero does not pin the head, which is the default.
+func WithPinnedHead(height uint64) Option {
+	return func(cfg *Config) {
+		cfg.PinnedHead = height
+	}
+}
+
+// WithRequestBudget sets the maximum cost of a single script execution or event
+// lookup, in cost units, beyond which it fails with a resource exhausted error.
+// Each register read and each event returned by the index costs one unit. A
+// budget of zero does not limit requests, which is the default. Scripts can
+// only be charged with a dedicated invoker, so they fail with a failed
+// precondition error when no invoker factory is set. The computation used by
+// scripts is not charged, as invokers do not report it.
+func WithRequestBudget(budget uint64) Option {
+	return func(cfg *Config) {
+		cfg.RequestBudget = budget
+	}
+}
+
+// WithScriptMetering sets whether each script execution uses a dedicated
+// invoker, so that the registers it reads from the index can be recorded in
+// the per-script register metrics. Dedicated invokers do not share the register
+// cache of the shared invoker, so every execution reads all of its registers.
+func WithScriptMetering(meter bool) Option {
+	return func(cfg *Config) {
+		cfg.MeterScripts = meter
+	}
+}
+
+// WithAddressAccounting sets the counter in which the account addresses that
+// are requested through account lookups and script executions are counted. For
+// scripts, the first address argument is counted. By default, requested
+// addresses are not counted.
+func WithAddressAccounting(addresses *topk.Counter) Option {
+	return func(cfg *Config) {
+		cfg.Addresses = addresses
+	}
+}
+
+// WithEventTypes sets the event types that can be queried through the
+// `GetEventsForHeightRange` and `GetEventsForBlockIDs` endpoints. Requests for
+// denied types, or for types other than the allowed ones when that list is not
+// empty, fail with a permission denied error, and events of those types are
+// left out of requests for all types. By default, all types can be queried.
+func WithEventTypes(allowed []flow.EventType, denied []flow.EventType) Option {
+	return func(cfg *Config) {
+		cfg.AllowedEventTypes = allowed
+		cfg.DeniedEventTypes = denied
+	}
+}
+
+// WithDecodeEvents sets whether the server decodes the payloads of events for
+// the `GetDecodedEventsForHeightRange` endpoint. It is disabled by default, as
+// decoding payloads is much more expensive than returning them as is.
+func WithDecodeEvents(decode bool) Option {
+	return func(cfg *Config) {
+		cfg.DecodeEvents = decode
+	}
+}
+
+// WithStrictDecoding sets whether a request for decoded events fails when the
+// payload of one of its events cannot be decoded. By default, such events are
+// returned with their stored payload only, without decoded fields.
+func WithStrictDecoding(strict bool) Option {
+	return func(cfg *Config) {
+		cfg.StrictDecoding = strict
+	}
+}
+
+// WithStrictArguments sets whether script arguments are decoded strictly, as
+// specified by the current JSON-Cadence format, which is the default. Otherwise,
+// known extensions emitted by other SDK versions, such as static types encoded
+// as plain type IDs, are accepted as well.
+func WithStrictArguments(strict bool) Option {
+	return func(cfg *Config) {
+		cfg.StrictArguments = strict
+	}
+}
+
+// WithValidateTimestamps sets whether block timestamps are checked before they
+// are returned. Timestamps before 2019 or more than a day in the future can only
+// come from corrupt index data, and are clamped to those bounds with a warning.
+func WithValidateTimestamps(validate bool) Option {
+	return func(cfg *Config) {
+		cfg.ValidateTimestamps = validate
+	}
+}
+
+// WithResolveDuplicates sets whether the inclusions of a transaction are looked
+// up in all the blocks in which it could have been included, to detect it being
+// included more than once. The first inclusion is then used, instead of the one
+// that the index maps the transaction to. Every lookup of a transaction by ID
+// then reads the transactions of each block between its reference block and
+// its indexed height, which is up to flow.DefaultTransactionExpiry reads even
+// when the transaction is not duplicated.
+func WithResolveDuplicates(resolve bool) Option {
+	return func(cfg *Config) {
+		cfg.ResolveDuplicates = resolve
+	}
+}
+
+// WithMode sets whether requests that cannot be served from the index are
+// proxied to the upstream access node, for all methods listed in `ProxyMethods`
+// that are not overridden. By default, the server runs in archive-only mode.
+func WithMode(mode Mode) Option {
+	return func(cfg *Config) {
+		cfg.Mode = mode
+	}
+}
+
+// WithProxyOverrides sets whether requests for specific methods are proxied to
+// the upstream access node, regardless of the mode of the server.
+func WithProxyOverrides(overrides map[string]bool) Option {
+	return func(cfg *Config) {
+		cfg.ProxyOverrides = overrides
+	}
+}
+
+// WithRangeRefresh sets the interval at which the last indexed height returned
+// by `GetIndexedHeightRange` is refreshed from the index. A zero interval reads
+// it from the index on every request.
+func WithRangeRefresh(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.RangeRefresh = interval
+	}
+}
+
+// WithPollInterval sets the interval at which `SubscribeExecutionData` streams
+// that have caught up with the index check it for new heights.
+func WithPollInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.PollInterval = interval
+	}
+}
+
+// WithRangeRetries sets how many times the reads for a height of a
+// `GetEventsForHeightRange` request are retried when the archive is
+// transiently unavailable, and how long to wait before the first retry, which
+// doubles for each following one. Other errors are never retried.
+func WithRangeRetries(retries uint, backoff time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.RangeRetries = retries
+		cfg.RangeBackoff = backoff
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"github.com/onflow/flow-go/engine/common/rpc/convert"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+)
+
+// sealToMessage converts a seal into its RPC message representation.
+// See https://github.com/onflow/flow-go/blob/v0.17.4/engine/common/rpc/convert/convert.go#L180-L188
+//
+// Execution receipt signatures are always empty, like on access nodes, since
+// seals do not carry them.
+func sealToMessage(seal *flow.Seal) *entities.BlockSeal {
+	blockID := seal.BlockID
+	resultID := seal.ResultID
+
+	entity := entities.BlockSeal{
+		BlockId:                    blockID[:],
+		ExecutionReceiptId:         resultID[:],
+		ExecutionReceiptSignatures: [][]byte{}, // filling seals signature with zero
+	}
+
+	return &entity
+}
+
+// fullSealToMessage converts a seal into its RPC message representation, like
+// sealToMessage, along with its result ID, final state and aggregated approval
+// signatures, like access nodes do since v0.30.
+// See https://github.com/onflow/flow-go/blob/v0.30.3/engine/common/rpc/convert/convert.go#L474-L485
+func fullSealToMessage(seal *flow.Seal) *entities.BlockSeal {
+	entity := sealToMessage(seal)
+	entity.ResultId = convert.IdentifierToMessage(seal.ResultID)
+	entity.FinalState = seal.FinalState[:]
+	entity.AggregatedApprovalSigs = convert.AggregatedSignaturesToMessages(seal.AggregatedApprovalSigs)
+
+	return entity
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Implements returns whether the server serves requests for the method of the
+// Access API or of the extended API with the given name, without the service
+// prefix, with its current configuration. Methods that the archive cannot
+// serve on its own are only implemented when they are forwarded or proxied.
+func (s *Server) Implements(method string) bool {
+	switch method {
+	case "GetLatestBlockHeader", "GetBlockHeaderByID", "GetBlockHeaderByHeight", "GetExecutionResultForBlockID":
+		return s.proxies(method)
+	case "SendTransaction":
+		return s.cfg.Submitter != nil || s.proxies(method)
+	case "GetLatestProtocolStateSnapshot":
+		return s.cfg.Snapshots != nil || s.proxies(method)
+	case "GetAccountRegistersAtBlockHeight":
+		return s.cfg.NewInvoker != nil
+	case "GetDecodedEventsForHeightRange":
+		return s.cfg.DecodeEvents
+	default:
+		return true
+	}
+}
+
+// DescriptorSet returns the descriptors of the files that define the services
+// with the given full names, along with those of all the files they import, so
+// that clients can generate stubs for them without the original proto files.
+// Imported files come before the files that import them.
+func DescriptorSet(services ...protoreflect.FullName) (*descriptorpb.FileDescriptorSet, error) {
+	var set descriptorpb.FileDescriptorSet
+	seen := make(map[string]struct{})
+
+	var add func(file protoreflect.FileDescriptor)
+	add = func(file protoreflect.FileDescriptor) {
+		_, ok := seen[file.Path()]
+		if ok {
+			return
+		}
+		seen[file.Path()] = struct{}{}
+
+		imports := file.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+
+	for _, name := range services {
+		descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not find service %s: %w", name, err)
+		}
+		service, ok := descriptor.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a service", name)
+		}
+		add(service.ParentFile())
+	}
+
+	return &set, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestServer_Implements(t *testing.T) {
+	t.Run("implements archive methods", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		assert.True(t, s.Implements("GetBlockByHeight"))
+		assert.True(t, s.Implements("ExecuteScriptAtBlockHeight"))
+		assert.True(t, s.Implements("GetIndexedHeightRange"))
+	})
+
+	t.Run("does not implement unavailable methods by default", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		assert.False(t, s.Implements("GetBlockHeaderByHeight"))
+		assert.False(t, s.Implements("SendTransaction"))
+		assert.False(t, s.Implements("GetLatestProtocolStateSnapshot"))
+		assert.False(t, s.Implements("GetAccountRegistersAtBlockHeight"))
+		assert.False(t, s.Implements("GetDecodedEventsForHeightRange"))
+	})
+
+	t.Run("implements proxied methods", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = &mockUpstream{}
+		s.cfg.ProxyOverrides = map[string]bool{"GetBlockHeaderByHeight": true}
+
+		assert.True(t, s.Implements("GetBlockHeaderByHeight"))
+		assert.False(t, s.Implements("GetBlockHeaderByID"))
+	})
+
+	t.Run("implements configured methods", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Submitter = submitterFunc(func(context.Context, *access.SendTransactionRequest) (*access.SendTransactionResponse, error) {
+			return &access.SendTransactionResponse{}, nil
+		})
+		s.cfg.DecodeEvents = true
+
+		assert.True(t, s.Implements("SendTransaction"))
+		assert.True(t, s.Implements("GetDecodedEventsForHeightRange"))
+	})
+}
+
+func TestDescriptorSet(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		set, err := DescriptorSet("flow.access.AccessAPI", "flow.archive.access.ExtendedAPI")
+		require.NoError(t, err)
+
+		// The set is self-contained, so that it can be loaded without any
+		// other descriptor.
+		files, err := protodesc.NewFiles(set)
+		require.NoError(t, err)
+
+		_, err = files.FindDescriptorByName("flow.access.AccessAPI")
+		assert.NoError(t, err)
+		_, err = files.FindDescriptorByName("flow.archive.access.ExtendedAPI")
+		assert.NoError(t, err)
+
+		seen := make(map[string]int)
+		for _, file := range set.File {
+			seen[file.GetName()]++
+		}
+		for name, count := range seen {
+			assert.Equal(t, 1, count, name)
+		}
+	})
+
+	t.Run("handles unknown service", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := DescriptorSet("flow.access.UnknownAPI")
+
+		assert.Error(t, err)
+	})
+}
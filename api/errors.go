@@ -0,0 +1,96 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/badger/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	fvmErrors "github.com/onflow/flow-go/fvm/errors"
+)
+
+// errNoIndexedBlocks is returned when the archive has not indexed any blocks
+// yet, such as on a new node that has only just started indexing.
+var errNoIndexedBlocks = status.Error(codes.Unavailable, "archive has no indexed blocks yet")
+
+// errShuttingDown is returned to the streams that are ended because the server
+// is shutting down.
+var errShuttingDown = status.Error(codes.Unavailable, "server is shutting down")
+
+// errBudgetExceeded is returned when the cost of a request exceeds the budget
+// configured for each request.
+var errBudgetExceeded = status.Error(codes.ResourceExhausted, "request exceeds its cost budget")
+
+// errBudgetUnenforceable is returned for scripts when a budget is configured for
+// each request, but there is no invoker factory to charge their reads with.
+var errBudgetUnenforceable = status.Error(codes.FailedPrecondition, "scripts cannot be charged to the request budget without an invoker factory")
+
+// errorCodePattern matches the code with which the FVM prefixes the messages of
+// the errors it returns, such as `[Error Code: 1101]`.
+var errorCodePattern = regexp.MustCompile(`\[(?:Error|Failure) Code: (\d+)\]`)
+
+// isNotFound returns whether the given index error means that the requested
+// entity is not indexed. When the index is read through the archive API, the
+// original error does not survive the round trip, so its message is matched.
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, badger.ErrKeyNotFound) || status.Code(err) == codes.NotFound {
+		return true
+	}
+
+	return strings.Contains(err.Error(), badger.ErrKeyNotFound.Error())
+}
+
+// isTransient returns whether the given index error means that the archive was
+// transiently unavailable, in which case the same read can succeed when it is
+// retried. The gRPC status is looked up through the errors that wrap it.
+func isTransient(err error) bool {
+	var st interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &st) {
+		return false
+	}
+
+	return st.GRPCStatus().Code() == codes.Unavailable
+}
+
+// transactionErrorCode returns the FVM error code of a transaction with the
+// given error message, which is zero for successful transactions. The results
+// stored in the index only have the message, so the code is read from it; a
+// message without a code, which wrapped errors can lose, is reported with the
+// code of an unknown failure.
+func transactionErrorCode(message string) fvmErrors.ErrorCode {
+	if message == "" {
+		return 0
+	}
+
+	match := errorCodePattern.FindStringSubmatch(message)
+	if match == nil {
+		return fvmErrors.FailureCodeUnknownFailure
+	}
+	code, err := strconv.ParseUint(match[1], 10, 16)
+	if err != nil {
+		return fvmErrors.FailureCodeUnknownFailure
+	}
+
+	return fvmErrors.ErrorCode(code)
+}
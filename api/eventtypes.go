@@ -0,0 +1,72 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"github.com/onflow/flow-go/model/flow"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// eventTypeAllowed returns whether events of the given type can be queried,
+// according to the configured lists of allowed and denied event types. Denied
+// types are never allowed, and when the list of allowed types is empty, every
+// other type is.
+func (s *Server) eventTypeAllowed(typ flow.EventType) bool {
+	for _, denied := range s.cfg.DeniedEventTypes {
+		if typ == denied {
+			return false
+		}
+	}
+
+	if len(s.cfg.AllowedEventTypes) == 0 {
+		return true
+	}
+	for _, allowed := range s.cfg.AllowedEventTypes {
+		if typ == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkEventType checks that events of the given type can be queried. An empty
+// type, which queries events of all types, is always accepted; the events of
+// types that cannot be queried are then left out of the results instead.
+func (s *Server) checkEventType(typ string) error {
+	if typ == "" || s.eventTypeAllowed(flow.EventType(typ)) {
+		return nil
+	}
+
+	return status.Errorf(codes.PermissionDenied, "querying events of type %s is not allowed", typ)
+}
+
+// allowedEvents returns the given events without those of the types that cannot
+// be queried.
+func (s *Server) allowedEvents(events []flow.Event) []flow.Event {
+	if len(s.cfg.AllowedEventTypes) == 0 && len(s.cfg.DeniedEventTypes) == 0 {
+		return events
+	}
+
+	allowed := make([]flow.Event, 0, len(events))
+	for _, event := range events {
+		if s.eventTypeAllowed(event.Type) {
+			allowed = append(allowed, event)
+		}
+	}
+
+	return allowed
+}
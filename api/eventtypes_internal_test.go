@@ -0,0 +1,136 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow-archive/testing/mocks"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestServer_EventTypes(t *testing.T) {
+	allowed := mocks.GenericEventType(0)
+	denied := mocks.GenericEventType(1)
+	blockID := mocks.GenericHeader.ID()
+
+	rangeRequest := func(typ flow.EventType) *access.GetEventsForHeightRangeRequest {
+		return &access.GetEventsForHeightRangeRequest{
+			Type:        string(typ),
+			StartHeight: mocks.GenericHeight,
+			EndHeight:   mocks.GenericHeight,
+		}
+	}
+	blocksRequest := func(typ flow.EventType) *access.GetEventsForBlockIDsRequest {
+		return &access.GetEventsForBlockIDsRequest{
+			Type:     string(typ),
+			BlockIds: [][]byte{blockID[:]},
+		}
+	}
+
+	t.Run("allows all types by default", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		_, err := s.GetEventsForHeightRange(context.Background(), rangeRequest(denied))
+		require.NoError(t, err)
+
+		resp, err := s.GetEventsForHeightRange(context.Background(), rangeRequest(""))
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		assert.Len(t, resp.Results[0].Events, 4)
+	})
+
+	t.Run("allows types that are not denied", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.DeniedEventTypes = []flow.EventType{denied}
+
+		_, err := s.GetEventsForHeightRange(context.Background(), rangeRequest(allowed))
+		require.NoError(t, err)
+
+		_, err = s.GetEventsForBlockIDs(context.Background(), blocksRequest(allowed))
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects denied type", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.DeniedEventTypes = []flow.EventType{denied}
+
+		_, err := s.GetEventsForHeightRange(context.Background(), rangeRequest(denied))
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = s.GetEventsForBlockIDs(context.Background(), blocksRequest(denied))
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("rejects type that is not allowed", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.AllowedEventTypes = []flow.EventType{allowed}
+
+		_, err := s.GetEventsForHeightRange(context.Background(), rangeRequest(allowed))
+		require.NoError(t, err)
+
+		_, err = s.GetEventsForHeightRange(context.Background(), rangeRequest(denied))
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = s.GetEventsForBlockIDs(context.Background(), blocksRequest(denied))
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("rejects denied type that is also allowed", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.AllowedEventTypes = []flow.EventType{allowed, denied}
+		s.cfg.DeniedEventTypes = []flow.EventType{denied}
+
+		_, err := s.GetEventsForHeightRange(context.Background(), rangeRequest(denied))
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("leaves denied events out of all types", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.DeniedEventTypes = []flow.EventType{denied}
+
+		resp, err := s.GetEventsForHeightRange(context.Background(), rangeRequest(""))
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		require.Len(t, resp.Results[0].Events, 2)
+		for _, event := range resp.Results[0].Events {
+			assert.Equal(t, string(allowed), event.Type)
+		}
+
+		blocks, err := s.GetEventsForBlockIDs(context.Background(), blocksRequest(""))
+		require.NoError(t, err)
+		require.Len(t, blocks.Results, 1)
+		assert.Len(t, blocks.Results[0].Events, 2)
+	})
+}
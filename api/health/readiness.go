@@ -0,0 +1,80 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/onflow/flow-archive/models/archive"
+)
+
+// Readiness periodically checks whether the archive index can be reached, and
+// reflects the result in the status of a service of the gRPC health server.
+type Readiness struct {
+	log      zerolog.Logger
+	server   *health.Server
+	index    archive.Reader
+	service  string
+	interval time.Duration
+}
+
+// NewReadiness creates a readiness check that sets the status of the given
+// service on the health server, by checking the index at the given interval.
+func NewReadiness(log zerolog.Logger, server *health.Server, index archive.Reader, service string, interval time.Duration) *Readiness {
+	r := Readiness{
+		log:      log.With().Str("component", "readiness").Logger(),
+		server:   server,
+		index:    index,
+		service:  service,
+		interval: interval,
+	}
+
+	return &r
+}
+
+// Run checks the readiness right away, then at every interval until the context
+// is canceled.
+func (r *Readiness) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.Check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check checks whether the index can be reached, and updates the status of the
+// readiness service accordingly.
+func (r *Readiness) Check() {
+	_, err := r.index.Last()
+	if err != nil {
+		r.log.Warn().Err(err).Msg("archive index is not reachable")
+		r.server.SetServingStatus(r.service, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+		return
+	}
+
+	r.server.SetServingStatus(r.service, grpc_health_v1.HealthCheckResponse_SERVING)
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package health
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/onflow/flow-archive/testing/mocks"
+)
+
+func TestReadiness_Check(t *testing.T) {
+	req := &grpc_health_v1.HealthCheckRequest{Service: "readiness"}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		server := health.NewServer()
+		index := mocks.BaselineReader(t)
+		r := NewReadiness(zerolog.Nop(), server, index, "readiness", 0)
+
+		r.Check()
+
+		resp, err := server.Check(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+	})
+
+	t.Run("handles unreachable index", func(t *testing.T) {
+		t.Parallel()
+
+		server := health.NewServer()
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+		r := NewReadiness(zerolog.Nop(), server, index, "readiness", 0)
+
+		r.Check()
+
+		resp, err := server.Check(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, resp.Status)
+	})
+
+	t.Run("recovers once index is reachable again", func(t *testing.T) {
+		t.Parallel()
+
+		server := health.NewServer()
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+		r := NewReadiness(zerolog.Nop(), server, index, "readiness", 0)
+		r.Check()
+
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight, nil
+		}
+		r.Check()
+
+		resp, err := server.Check(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+	})
+}
@@ -15,13 +15,27 @@
 package api
 
 import (
+	"errors"
+
 	"github.com/onflow/cadence"
+	fvmErrors "github.com/onflow/flow-go/fvm/errors"
 	"github.com/onflow/flow-go/model/flow"
 )
 
+// ErrAccountNotFound is the error that invokers return, possibly wrapped, when
+// the requested account does not exist at the requested height.
+var ErrAccountNotFound = errors.New("account not found")
+
 // Invoker represents something that can retrieve accounts at any given height, and execute scripts to retrieve values
 // from the Flow Virtual Machine.
 type Invoker interface {
 	Account(height uint64, address flow.Address) (*flow.Account, error)
 	Script(height uint64, script []byte, parameters []cadence.Value) (cadence.Value, error)
 }
+
+// isAccountNotFound returns whether the given invoker error means that the account
+// does not exist. Besides ErrAccountNotFound, it recognizes the error returned by
+// the FVM, which the archive invoker wraps.
+func isAccountNotFound(err error) bool {
+	return errors.Is(err, ErrAccountNotFound) || fvmErrors.IsAccountNotFoundError(err)
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// OmitEventsHeader is the metadata header that clients can set to `true` on
+// `GetTransactionResult` requests to leave the events out of the response.
+const OmitEventsHeader = "x-omit-events"
+
+// OmitContractsHeader is the metadata header that clients can set to `true` on
+// `GetAccount`, `GetAccountAtLatestBlock` and `GetAccountAtBlockHeight` requests
+// to leave the contracts of the account out of the response.
+const OmitContractsHeader = "x-omit-contracts"
+
+// BlockStatsHeader is the metadata header that clients can set to `true` on
+// `GetBlockByHeight`, `GetBlockByID` and `GetLatestBlock` requests to receive
+// summary statistics for the block in the response headers below. They need
+// extra reads from the index, so they are not computed by default.
+const BlockStatsHeader = "x-block-stats"
+
+// Response headers with the statistics of a block, in decimal: the number of
+// transactions and events at its height, and the total computation used by its
+// transactions.
+const (
+	BlockTransactionsHeader = "x-block-transactions"
+	BlockEventsHeader       = "x-block-events"
+	BlockComputationHeader  = "x-block-computation"
+)
+
+// ErrorCodeHeader is the metadata header that clients can set to `true` on
+// `GetTransactionResult` requests to receive the FVM error code of the
+// transaction in the response header below, in decimal. It is zero for
+// successful transactions.
+const ErrorCodeHeader = "x-error-code"
+
+// TransactionErrorCodeHeader is the response header with the FVM error code of
+// a transaction.
+const TransactionErrorCodeHeader = "x-transaction-error-code"
+
+// ReferenceBlockHeader is the metadata header that clients can set to `true` on
+// `GetCollectionByID` requests to receive the reference block of the collection
+// in the response headers below. The height is only sent when the reference
+// block is indexed.
+const ReferenceBlockHeader = "x-reference-block"
+
+// Response headers with the ID and the height of the reference block of a
+// collection, in hexadecimal and in decimal respectively.
+const (
+	ReferenceBlockIDHeader     = "x-reference-block-id"
+	ReferenceBlockHeightHeader = "x-reference-block-height"
+)
+
+// ScriptHeightHeader is the response header with the height at which a script
+// sent to `ExecuteScriptAtLatestBlock` was executed, in decimal.
+const ScriptHeightHeader = "x-script-height"
+
+// MaxStalenessHeader is the metadata header that clients can set to a duration,
+// such as `30s`, on requests for the latest block, account or script result. If
+// the latest indexed block is older than the upstream's latest finalized block
+// by more than that duration, the request is forwarded to the upstream instead.
+// It only applies to methods that are proxied, in hybrid mode or through the
+// proxy overrides, and compares block timestamps rather than heights.
+const MaxStalenessHeader = "max-staleness"
+
+// headerFlag returns the boolean value of the given metadata header of the
+// incoming request, or false if the header is absent.
+func headerFlag(ctx context.Context, header string) (bool, error) {
+	values := metadata.ValueFromIncomingContext(ctx, header)
+	if len(values) == 0 {
+		return false, nil
+	}
+
+	flag, err := strconv.ParseBool(values[0])
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "invalid value %q for header %s", values[0], header)
+	}
+
+	return flag, nil
+}
+
+// headerDuration returns the duration value of the given metadata header of the
+// incoming request, and whether the header is present.
+func headerDuration(ctx context.Context, header string) (time.Duration, bool, error) {
+	values := metadata.ValueFromIncomingContext(ctx, header)
+	if len(values) == 0 {
+		return 0, false, nil
+	}
+
+	duration, err := time.ParseDuration(values[0])
+	if err != nil || duration < 0 {
+		return 0, false, status.Errorf(codes.InvalidArgument, "invalid value %q for header %s", values[0], header)
+	}
+
+	return duration, true, nil
+}
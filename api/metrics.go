@@ -0,0 +1,82 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	namespace = "archive_access"
+)
+
+var (
+	blockCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "block_cache_hits_total",
+		Help:      "number of block requests served from the block cache",
+	})
+
+	blockCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "block_cache_misses_total",
+		Help:      "number of block requests not found in the block cache",
+	})
+
+	registerReads = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "register_reads_total",
+		Help:      "number of registers read from the index by the script invoker",
+	})
+
+	registerReadBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "register_read_bytes_total",
+		Help:      "total size in bytes of the register values read from the index by the script invoker",
+	})
+
+	scriptRegisterReads = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "script_register_reads",
+		Help:      "number of registers read from the index by a single script execution with a dedicated invoker",
+		Buckets:   prometheus.ExponentialBuckets(1, 10, 7),
+	})
+
+	scriptRegisterReadBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "script_register_read_bytes",
+		Help:      "total size in bytes of the register values read from the index by a single script execution with a dedicated invoker",
+		Buckets:   prometheus.ExponentialBuckets(1, 10, 10),
+	})
+
+	duplicateTransactions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicate_transactions_total",
+		Help:      "number of transaction lookups that found the transaction included in more than one block",
+	})
+
+	stalenessCheckFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "staleness_check_failures_total",
+		Help:      "number of requests with a maximum staleness served from the index because the upstream's latest block could not be looked up",
+	})
+
+	eventDecodeFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_decode_failures_total",
+		Help:      "number of events returned without decoded fields because their payload could not be decoded",
+	})
+)
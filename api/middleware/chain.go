@@ -0,0 +1,58 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ChainIDHeader is the response header that carries the chain ID of the network
+// served by the server, so that clients behind a load balancer in front of
+// several networks can check which one answered.
+const ChainIDHeader = "x-chain-id"
+
+// ChainIDUnaryServerInterceptor returns an interceptor that sets the given chain
+// ID in the response headers of unary requests.
+func ChainIDUnaryServerInterceptor(chainID string) grpc.UnaryServerInterceptor {
+	md := metadata.Pairs(ChainIDHeader, chainID)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		err := grpc.SetHeader(ctx, md)
+		if err != nil {
+			return nil, fmt.Errorf("could not set chain ID header: %w", err)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// ChainIDStreamServerInterceptor returns an interceptor that sets the given
+// chain ID in the response headers of streams.
+func ChainIDStreamServerInterceptor(chainID string) grpc.StreamServerInterceptor {
+	md := metadata.Pairs(ChainIDHeader, chainID)
+
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := stream.SetHeader(md)
+		if err != nil {
+			return fmt.Errorf("could not set chain ID header: %w", err)
+		}
+
+		return handler(srv, stream)
+	}
+}
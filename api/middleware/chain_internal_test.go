@@ -0,0 +1,90 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestChainIDUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetLatestBlock"}
+	interceptor := ChainIDUnaryServerInterceptor("flow-mainnet")
+
+	var stream headerStream
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), &stream)
+	resp, err := interceptor(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Equal(t, []string{"flow-mainnet"}, stream.header.Get(ChainIDHeader))
+}
+
+func TestChainIDStreamServerInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/flow.archive.access.ExtendedAPI/ExecuteScripts"}
+	interceptor := ChainIDStreamServerInterceptor("flow-mainnet")
+
+	var stream headerServerStream
+	called := false
+	err := interceptor(nil, &stream, info, func(interface{}, grpc.ServerStream) error {
+		called = true
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.True(t, called)
+	assert.Equal(t, []string{"flow-mainnet"}, stream.header.Get(ChainIDHeader))
+}
+
+// headerStream is a fake server transport stream that records the headers set
+// by unary handlers.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (h *headerStream) Method() string {
+	return ""
+}
+
+func (h *headerStream) SetHeader(md metadata.MD) error {
+	h.header = metadata.Join(h.header, md)
+	return nil
+}
+
+func (h *headerStream) SendHeader(md metadata.MD) error {
+	return h.SetHeader(md)
+}
+
+func (h *headerStream) SetTrailer(metadata.MD) error {
+	return nil
+}
+
+// headerServerStream is a server stream that records the headers set on it.
+type headerServerStream struct {
+	grpc.ServerStream
+	header metadata.MD
+}
+
+func (h *headerServerStream) SetHeader(md metadata.MD) error {
+	h.header = metadata.Join(h.header, md)
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// CodesUnaryServerInterceptor returns an interceptor that counts the responses
+// to unary requests by method and gRPC status code, including successful ones.
+func CodesUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			countCode(info.FullMethod, err)
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// CodesStreamServerInterceptor returns an interceptor that counts the final
+// status of streams by method and gRPC status code, including successful ones.
+func CodesStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			countCode(info.FullMethod, err)
+		}()
+
+		return handler(srv, stream)
+	}
+}
+
+func countCode(fullMethod string, err error) {
+	method := path.Base(fullMethod)
+	code := status.Code(err)
+	responseCodes.WithLabelValues(method, code.String()).Inc()
+}
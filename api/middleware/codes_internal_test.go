@@ -0,0 +1,64 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCodesUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetTransaction"}
+	interceptor := CodesUnaryServerInterceptor()
+
+	ok := responseCodes.WithLabelValues("GetTransaction", codes.OK.String())
+	outOfRange := responseCodes.WithLabelValues("GetTransaction", codes.OutOfRange.String())
+	okBefore := testutil.ToFloat64(ok)
+	outOfRangeBefore := testutil.ToFloat64(outOfRange)
+
+	_, _ = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.OutOfRange, "out of range")
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.OutOfRange, "out of range")
+	})
+
+	assert.Equal(t, okBefore+1, testutil.ToFloat64(ok))
+	assert.Equal(t, outOfRangeBefore+2, testutil.ToFloat64(outOfRange))
+}
+
+func TestCodesStreamServerInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/flow.archive.access.ExtendedAPI/ExecuteScripts"}
+	interceptor := CodesStreamServerInterceptor()
+
+	unavailable := responseCodes.WithLabelValues("ExecuteScripts", codes.Unavailable.String())
+	before := testutil.ToFloat64(unavailable)
+
+	err := interceptor(nil, nil, info, func(interface{}, grpc.ServerStream) error {
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, before+1, testutil.ToFloat64(unavailable))
+}
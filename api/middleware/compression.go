@@ -0,0 +1,99 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// DefaultCompressedMethods are the methods whose responses are compressed by
+// default. Events and blocks are large and compress well, while script results
+// and headers are only a few bytes long, so compressing them only costs time.
+var DefaultCompressedMethods = map[string]bool{
+	"GetEventsForHeightRange":    true,
+	"GetEventsForBlockIDs":       true,
+	"GetLatestBlock":             true,
+	"GetBlockByID":               true,
+	"GetBlockByHeight":           true,
+	"ExecuteScriptAtLatestBlock": false,
+	"ExecuteScriptAtBlockID":     false,
+	"ExecuteScriptAtBlockHeight": false,
+	"GetLatestBlockHeader":       false,
+	"GetBlockHeaderByID":         false,
+	"GetBlockHeaderByHeight":     false,
+}
+
+// Compressor chooses the compression of responses for each method. Responses
+// to methods for which compression is enabled are compressed with gzip when
+// the client accepts it, even if its request was not compressed. Responses to
+// other methods are compressed like their requests, so that clients can still
+// ask for compression explicitly by compressing their requests.
+type Compressor struct {
+	methods map[string]bool
+}
+
+// NewCompressor returns a compressor that compresses the responses to the
+// methods that are enabled in the given map, indexed by method name.
+func NewCompressor(methods map[string]bool) *Compressor {
+	c := Compressor{
+		methods: methods,
+	}
+
+	return &c
+}
+
+// UnaryServerInterceptor returns an interceptor that sets the compression of
+// the responses to unary requests.
+func (c *Compressor) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		c.compress(ctx, info.FullMethod)
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that sets the compression of
+// the responses sent on streams.
+func (c *Compressor) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		c.compress(stream.Context(), info.FullMethod)
+		return handler(srv, stream)
+	}
+}
+
+// compress sets gzip as the compressor of the responses to the given method,
+// if compression is enabled for it and the client accepts gzip.
+func (c *Compressor) compress(ctx context.Context, fullMethod string) {
+	if !c.methods[path.Base(fullMethod)] {
+		return
+	}
+
+	accepted, err := grpc.ClientSupportedCompressors(ctx)
+	if err != nil {
+		return
+	}
+	for _, name := range accepted {
+		if name != gzip.Name {
+			continue
+		}
+		// The compressor can only fail to be set once the headers were sent,
+		// in which case the response is compressed like the request.
+		_ = grpc.SetSendCompressor(ctx, gzip.Name)
+		return
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestCompressor_UnaryServerInterceptor(t *testing.T) {
+	compressor := NewCompressor(map[string]bool{
+		"GetLatestBlock":       true,
+		"GetLatestBlockHeader": false,
+	})
+
+	listener := bufconn.Listen(1024 * 1024)
+	gsvr := grpc.NewServer(grpc.UnaryInterceptor(compressor.UnaryServerInterceptor()))
+	access.RegisterAccessAPIServer(gsvr, &latestAPI{})
+	go func() {
+		_ = gsvr.Serve(listener)
+	}()
+	defer gsvr.Stop()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}
+	compression := &compressionRecorder{}
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(compression),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := access.NewAccessAPIClient(conn)
+
+	t.Run("compresses responses of enabled methods", func(t *testing.T) {
+		_, err := client.GetLatestBlock(context.Background(), &access.GetLatestBlockRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, gzip.Name, compression.last())
+	})
+
+	t.Run("does not compress responses of disabled methods", func(t *testing.T) {
+		_, err := client.GetLatestBlockHeader(context.Background(), &access.GetLatestBlockHeaderRequest{})
+
+		require.NoError(t, err)
+		assert.Empty(t, compression.last())
+	})
+
+	t.Run("honors compressed requests to disabled methods", func(t *testing.T) {
+		_, err := client.GetLatestBlockHeader(context.Background(), &access.GetLatestBlockHeaderRequest{}, grpc.UseCompressor(gzip.Name))
+
+		require.NoError(t, err)
+		assert.Equal(t, gzip.Name, compression.last())
+	})
+
+	t.Run("does not compress responses of unlisted methods", func(t *testing.T) {
+		_, err := client.Ping(context.Background(), &access.PingRequest{})
+
+		require.NoError(t, err)
+		assert.Empty(t, compression.last())
+	})
+}
+
+type latestAPI struct {
+	access.UnimplementedAccessAPIServer
+}
+
+func (latestAPI) Ping(context.Context, *access.PingRequest) (*access.PingResponse, error) {
+	return &access.PingResponse{}, nil
+}
+
+func (latestAPI) GetLatestBlock(context.Context, *access.GetLatestBlockRequest) (*access.BlockResponse, error) {
+	return &access.BlockResponse{}, nil
+}
+
+func (latestAPI) GetLatestBlockHeader(context.Context, *access.GetLatestBlockHeaderRequest) (*access.BlockHeaderResponse, error) {
+	return &access.BlockHeaderResponse{}, nil
+}
+
+// compressionRecorder records the compression of the last response received
+// by the client.
+type compressionRecorder struct {
+	mu          sync.Mutex
+	compression string
+}
+
+func (c *compressionRecorder) last() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.compression
+}
+
+func (c *compressionRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (c *compressionRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	header, ok := s.(*stats.InHeader)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compression = header.Compression
+}
+
+func (c *compressionRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (c *compressionRecorder) HandleConn(context.Context, stats.ConnStats) {}
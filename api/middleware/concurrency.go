@@ -0,0 +1,143 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConcurrencyLimiter caps the number of requests that can be in flight at the
+// same time for each gRPC method. Unlike rate limiting, which limits how fast
+// requests arrive, it limits how many are being handled concurrently, which
+// provides back-pressure when the backend slows down.
+type ConcurrencyLimiter struct {
+	fallback uint
+	limits   map[string]uint
+	wait     time.Duration
+
+	mu         sync.Mutex
+	semaphores map[string]chan struct{}
+}
+
+// NewConcurrencyLimiter creates a limiter that allows up to `fallback` requests
+// in flight for each method, unless the method has its own limit in `limits`.
+// Methods are identified by their name, without the service prefix. A limit of
+// zero means that the method is not limited. When a method's limit is reached,
+// requests wait up to `wait` for a slot before being rejected.
+func NewConcurrencyLimiter(fallback uint, limits map[string]uint, wait time.Duration) *ConcurrencyLimiter {
+	c := ConcurrencyLimiter{
+		fallback:   fallback,
+		limits:     limits,
+		wait:       wait,
+		semaphores: make(map[string]chan struct{}),
+	}
+
+	return &c
+}
+
+// UnaryServerInterceptor returns an interceptor that limits concurrent unary requests.
+func (c *ConcurrencyLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, err := c.acquire(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that limits concurrent streams.
+func (c *ConcurrencyLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := c.acquire(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return handler(srv, stream)
+	}
+}
+
+func (c *ConcurrencyLimiter) acquire(ctx context.Context, fullMethod string) (func(), error) {
+	method := path.Base(fullMethod)
+	gauge := inflightRequests.WithLabelValues(method)
+
+	semaphore := c.semaphore(method)
+	if semaphore == nil {
+		gauge.Inc()
+		return gauge.Dec, nil
+	}
+
+	release := func() {
+		<-semaphore
+		gauge.Dec()
+	}
+
+	select {
+	case semaphore <- struct{}{}:
+		gauge.Inc()
+		return release, nil
+	default:
+	}
+
+	if c.wait > 0 {
+		timer := time.NewTimer(c.wait)
+		defer timer.Stop()
+
+		select {
+		case semaphore <- struct{}{}:
+			gauge.Inc()
+			return release, nil
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	rejectedRequests.WithLabelValues(method).Inc()
+
+	return nil, status.Errorf(codes.ResourceExhausted, "too many concurrent requests for %s", method)
+}
+
+func (c *ConcurrencyLimiter) semaphore(method string) chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	semaphore, ok := c.semaphores[method]
+	if ok {
+		return semaphore
+	}
+
+	limit, ok := c.limits[method]
+	if !ok {
+		limit = c.fallback
+	}
+	if limit > 0 {
+		semaphore = make(chan struct{}, limit)
+	}
+	c.semaphores[method] = semaphore
+
+	return semaphore
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConcurrencyLimiter_UnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/ExecuteScriptAtBlockHeight"}
+
+	// blockingHandler blocks until the returned channel is closed, and signals
+	// on `started` once it is being handled.
+	blockingHandler := func(started chan<- struct{}) (grpc.UnaryHandler, chan struct{}) {
+		unblock := make(chan struct{})
+		handler := func(context.Context, interface{}) (interface{}, error) {
+			started <- struct{}{}
+			<-unblock
+			return "ok", nil
+		}
+		return handler, unblock
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewConcurrencyLimiter(1, nil, 0)
+		interceptor := limiter.UnaryServerInterceptor()
+
+		handler := func(context.Context, interface{}) (interface{}, error) {
+			return "ok", nil
+		}
+
+		for i := 0; i < 3; i++ {
+			resp, err := interceptor(context.Background(), nil, info, handler)
+
+			require.NoError(t, err)
+			assert.Equal(t, "ok", resp)
+		}
+	})
+
+	t.Run("rejects requests above limit", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewConcurrencyLimiter(1, nil, 0)
+		interceptor := limiter.UnaryServerInterceptor()
+
+		started := make(chan struct{}, 1)
+		handler, unblock := blockingHandler(started)
+
+		done := make(chan error)
+		go func() {
+			_, err := interceptor(context.Background(), nil, info, handler)
+			done <- err
+		}()
+		<-started
+
+		_, err := interceptor(context.Background(), nil, info, handler)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		close(unblock)
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("queues requests up to wait duration", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewConcurrencyLimiter(1, nil, time.Second)
+		interceptor := limiter.UnaryServerInterceptor()
+
+		started := make(chan struct{}, 2)
+		handler, unblock := blockingHandler(started)
+
+		done := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				_, err := interceptor(context.Background(), nil, info, handler)
+				done <- err
+			}()
+		}
+		<-started
+
+		close(unblock)
+		assert.NoError(t, <-done)
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("uses method specific limits", func(t *testing.T) {
+		t.Parallel()
+
+		limits := map[string]uint{"ExecuteScriptAtBlockHeight": 0}
+		limiter := NewConcurrencyLimiter(1, limits, 0)
+		interceptor := limiter.UnaryServerInterceptor()
+
+		started := make(chan struct{}, 1)
+		handler, unblock := blockingHandler(started)
+
+		done := make(chan error)
+		go func() {
+			_, err := interceptor(context.Background(), nil, info, handler)
+			done <- err
+		}()
+		<-started
+
+		resp, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+
+		close(unblock)
+		assert.NoError(t, <-done)
+	})
+}
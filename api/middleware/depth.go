@@ -0,0 +1,48 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"path"
+
+	"google.golang.org/grpc"
+)
+
+// DepthUnaryServerInterceptor returns an interceptor that records, for each
+// unary request for a given height, how far that height is below the last
+// indexed height, as returned by the given function. Since it is called for
+// every such request, the function should read the last height from a cache.
+// Heights above the last height, which it can lag behind, are recorded as a
+// depth of zero.
+func DepthUnaryServerInterceptor(last func(ctx context.Context) (uint64, error)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		height, ok := requestHeight(req)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		head, err := last(ctx)
+		if err == nil {
+			depth := uint64(0)
+			if height < head {
+				depth = head - height
+			}
+			heightDepths.WithLabelValues(path.Base(info.FullMethod)).Observe(float64(depth))
+		}
+
+		return handler(ctx, req)
+	}
+}
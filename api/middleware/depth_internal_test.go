@@ -0,0 +1,111 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/onflow/flow-archive/testing/mocks"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestDepthUnaryServerInterceptor(t *testing.T) {
+	last := func(context.Context) (uint64, error) {
+		return 100, nil
+	}
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	t.Run("records depth of requested height", func(t *testing.T) {
+		t.Parallel()
+
+		info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetBlockHeaderByHeight"}
+		before := depthHistogram(t, "GetBlockHeaderByHeight")
+
+		interceptor := DepthUnaryServerInterceptor(last)
+		resp, err := interceptor(context.Background(), &access.GetBlockHeaderByHeightRequest{Height: 58}, info, handler)
+
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+		after := depthHistogram(t, "GetBlockHeaderByHeight")
+		assert.Equal(t, before.GetSampleCount()+1, after.GetSampleCount())
+		assert.Equal(t, before.GetSampleSum()+42, after.GetSampleSum())
+	})
+
+	t.Run("records heights above last height as zero depth", func(t *testing.T) {
+		t.Parallel()
+
+		info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/ExecuteScriptAtBlockHeight"}
+		before := depthHistogram(t, "ExecuteScriptAtBlockHeight")
+
+		interceptor := DepthUnaryServerInterceptor(last)
+		_, err := interceptor(context.Background(), &access.ExecuteScriptAtBlockHeightRequest{BlockHeight: 150}, info, handler)
+
+		require.NoError(t, err)
+		after := depthHistogram(t, "ExecuteScriptAtBlockHeight")
+		assert.Equal(t, before.GetSampleCount()+1, after.GetSampleCount())
+		assert.Equal(t, before.GetSampleSum(), after.GetSampleSum())
+	})
+
+	t.Run("skips requests without height", func(t *testing.T) {
+		t.Parallel()
+
+		info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetLatestBlock"}
+
+		interceptor := DepthUnaryServerInterceptor(func(context.Context) (uint64, error) {
+			t.Error("last height should not be read")
+			return 0, nil
+		})
+		resp, err := interceptor(context.Background(), &access.GetLatestBlockRequest{}, info, handler)
+
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+		assert.Zero(t, depthHistogram(t, "GetLatestBlock").GetSampleCount())
+	})
+
+	t.Run("skips requests when last height is unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetEventsForHeightRange"}
+		before := depthHistogram(t, "GetEventsForHeightRange")
+
+		interceptor := DepthUnaryServerInterceptor(func(context.Context) (uint64, error) {
+			return 0, mocks.GenericError
+		})
+		resp, err := interceptor(context.Background(), &access.GetEventsForHeightRangeRequest{StartHeight: 58}, info, handler)
+
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+		assert.Equal(t, before.GetSampleCount(), depthHistogram(t, "GetEventsForHeightRange").GetSampleCount())
+	})
+}
+
+func depthHistogram(t *testing.T, method string) *dto.Histogram {
+	t.Helper()
+
+	var metric dto.Metric
+	err := heightDepths.WithLabelValues(method).(prometheus.Metric).Write(&metric)
+	require.NoError(t, err)
+
+	return metric.GetHistogram()
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Drainer bounds the time that in-flight unary requests are given to complete
+// once the server starts shutting down, so that a graceful stop is not held up
+// by requests that take much longer, such as stuck scripts.
+type Drainer struct {
+	grace    time.Duration
+	once     sync.Once
+	draining chan struct{}
+}
+
+// NewDrainer returns a drainer that gives in-flight unary requests the given
+// grace period to complete once draining starts.
+func NewDrainer(grace time.Duration) *Drainer {
+	d := Drainer{
+		grace:    grace,
+		draining: make(chan struct{}),
+	}
+
+	return &d
+}
+
+// Drain starts draining. Unary requests that have not completed within the grace
+// period from now are aborted. It can be called more than once.
+func (d *Drainer) Drain() {
+	d.once.Do(func() {
+		close(d.draining)
+	})
+}
+
+// UnaryServerInterceptor returns an interceptor that aborts unary requests that
+// are still being handled once the grace period of the drain has elapsed. Their
+// context is canceled, so that handlers that watch it stop, and the client gets
+// a `codes.Unavailable` error right away, even from handlers that do not, which
+// are left to complete in the background.
+func (d *Drainer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+
+	type result struct {
+		resp interface{}
+		err  error
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		done := make(chan result, 1)
+		go func() {
+			resp, err := handler(ctx, req)
+			done <- result{resp: resp, err: err}
+		}()
+
+		select {
+		case res := <-done:
+			return res.resp, res.err
+		case <-d.draining:
+		}
+
+		timer := time.NewTimer(d.grace)
+		defer timer.Stop()
+
+		select {
+		case res := <-done:
+			return res.resp, res.err
+		case <-timer.C:
+			return nil, status.Errorf(codes.Unavailable, "request aborted after %s as the server is shutting down", d.grace)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestDrainer_UnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/ExecuteScriptAtBlockHeight"}
+
+	t.Run("passes requests through before draining", func(t *testing.T) {
+		t.Parallel()
+
+		drainer := NewDrainer(time.Millisecond)
+		interceptor := drainer.UnaryServerInterceptor()
+
+		resp, err := interceptor(context.Background(), "req", info, func(_ context.Context, req interface{}) (interface{}, error) {
+			return req, nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "req", resp)
+	})
+
+	t.Run("lets requests complete within grace period", func(t *testing.T) {
+		t.Parallel()
+
+		drainer := NewDrainer(time.Second)
+		interceptor := drainer.UnaryServerInterceptor()
+
+		started := make(chan struct{})
+		go func() {
+			<-started
+			drainer.Drain()
+		}()
+		resp, err := interceptor(context.Background(), "req", info, func(_ context.Context, req interface{}) (interface{}, error) {
+			close(started)
+			time.Sleep(20 * time.Millisecond)
+			return req, nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "req", resp)
+	})
+
+	t.Run("aborts requests after grace period", func(t *testing.T) {
+		t.Parallel()
+
+		drainer := NewDrainer(10 * time.Millisecond)
+		interceptor := drainer.UnaryServerInterceptor()
+		drainer.Drain()
+
+		canceled := make(chan struct{})
+		_, err := interceptor(context.Background(), "req", info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		})
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("context of aborted request was not canceled")
+		}
+	})
+
+	t.Run("stops server gracefully in time with stuck request", func(t *testing.T) {
+		t.Parallel()
+
+		drainer := NewDrainer(50 * time.Millisecond)
+		started := make(chan struct{})
+		stuck := make(chan struct{})
+		defer close(stuck)
+
+		// The handler ignores its context, like a script that does not return.
+		api := &stuckAPI{started: started, stuck: stuck}
+		listener := bufconn.Listen(1024 * 1024)
+		gsvr := grpc.NewServer(grpc.UnaryInterceptor(drainer.UnaryServerInterceptor()))
+		access.RegisterAccessAPIServer(gsvr, api)
+		go func() {
+			_ = gsvr.Serve(listener)
+		}()
+
+		dialer := func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}
+		conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := access.NewAccessAPIClient(conn).ExecuteScriptAtBlockHeight(context.Background(), &access.ExecuteScriptAtBlockHeightRequest{})
+			errs <- err
+		}()
+		<-started
+
+		stopped := make(chan struct{})
+		go func() {
+			drainer.Drain()
+			gsvr.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			gsvr.Stop()
+			t.Fatal("server did not stop gracefully in time")
+		}
+		assert.Equal(t, codes.Unavailable, status.Code(<-errs))
+	})
+}
+
+// stuckAPI is an Access API whose script executions never return.
+type stuckAPI struct {
+	access.UnimplementedAccessAPIServer
+
+	started chan struct{}
+	stuck   chan struct{}
+}
+
+func (s *stuckAPI) ExecuteScriptAtBlockHeight(context.Context, *access.ExecuteScriptAtBlockHeightRequest) (*access.ExecuteScriptResponse, error) {
+	close(s.started)
+	<-s.stuck
+	return &access.ExecuteScriptResponse{}, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	namespace = "archive_access"
+)
+
+var (
+	inflightRequests = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "inflight_requests",
+		Help:      "number of requests currently being handled, by method",
+	}, []string{"method"})
+
+	rejectedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rejected_requests_total",
+		Help:      "number of requests rejected because too many were in flight, by method",
+	}, []string{"method"})
+
+	shedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shed_requests_total",
+		Help:      "number of requests rejected because the backend was degraded, by method",
+	}, []string{"method"})
+
+	panics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "panics_total",
+		Help:      "number of panics recovered from in request handlers, by method",
+	}, []string{"method"})
+
+	responseCodes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "responses_total",
+		Help:      "number of responses returned, by method and gRPC status code",
+	}, []string{"method", "code"})
+
+	heightDepths = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_height_depth",
+		Help:      "number of heights between the requested height and the last indexed height, by method",
+		Buckets:   prometheus.ExponentialBuckets(1, 10, 9),
+	}, []string{"method"})
+)
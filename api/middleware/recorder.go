@@ -0,0 +1,101 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+// Record is a single recorded request, as written by the recorder on one line
+// of its output. The request is the JSON encoding of the request message.
+type Record struct {
+	Method  string          `json:"method"`
+	Request json.RawMessage `json:"request"`
+}
+
+// Recorder writes the unary requests that the server receives to a writer, one
+// JSON record per line, so that they can be replayed later on.
+type Recorder struct {
+	log zerolog.Logger
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewRecorder creates a recorder that writes the requests to the given writer.
+func NewRecorder(log zerolog.Logger, w io.Writer) *Recorder {
+	r := Recorder{
+		log: log.With().Str("component", "recorder").Logger(),
+		w:   w,
+	}
+
+	return &r
+}
+
+// UnaryServerInterceptor returns an interceptor that records unary requests
+// before handling them. Requests that fail to be recorded are still handled.
+func (r *Recorder) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		err := r.record(info.FullMethod, req)
+		if err != nil {
+			r.log.Warn().Err(err).Str("method", info.FullMethod).Msg("could not record request")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (r *Recorder) record(method string, req interface{}) error {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return fmt.Errorf("unexpected request type %T", req)
+	}
+
+	var buf bytes.Buffer
+	err := (&jsonpb.Marshaler{}).Marshal(&buf, msg)
+	if err != nil {
+		return fmt.Errorf("could not encode request: %w", err)
+	}
+
+	record := Record{
+		Method:  method,
+		Request: buf.Bytes(),
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("could not encode record: %w", err)
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err = r.w.Write(line)
+	if err != nil {
+		return fmt.Errorf("could not write record: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestRecorder_UnaryServerInterceptor(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		recorder := NewRecorder(zerolog.Nop(), &buf)
+		interceptor := recorder.UnaryServerInterceptor()
+
+		requests := []*access.GetBlockByHeightRequest{
+			{Height: 42},
+			{Height: 43, FullBlockResponse: true},
+		}
+		info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetBlockByHeight"}
+		for _, req := range requests {
+			resp, err := interceptor(context.Background(), req, info, func(context.Context, interface{}) (interface{}, error) {
+				return "ok", nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "ok", resp)
+		}
+
+		scanner := bufio.NewScanner(&buf)
+		for _, want := range requests {
+			require.True(t, scanner.Scan())
+
+			var record Record
+			err := json.Unmarshal(scanner.Bytes(), &record)
+			require.NoError(t, err)
+			assert.Equal(t, info.FullMethod, record.Method)
+
+			var got access.GetBlockByHeightRequest
+			err = jsonpb.Unmarshal(bytes.NewReader(record.Request), &got)
+			require.NoError(t, err)
+			assert.Equal(t, want.Height, got.Height)
+			assert.Equal(t, want.FullBlockResponse, got.FullBlockResponse)
+		}
+		assert.False(t, scanner.Scan())
+	})
+
+	t.Run("handles requests that cannot be recorded", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		recorder := NewRecorder(zerolog.Nop(), &buf)
+		interceptor := recorder.UnaryServerInterceptor()
+
+		info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/Ping"}
+		called := false
+		_, err := interceptor(context.Background(), "not a message", info, func(context.Context, interface{}) (interface{}, error) {
+			called = true
+			return nil, nil
+		})
+
+		require.NoError(t, err)
+		assert.True(t, called)
+		assert.Zero(t, buf.Len())
+	})
+}
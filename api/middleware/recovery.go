@@ -0,0 +1,82 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"path"
+	"runtime/debug"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryUnaryServerInterceptor returns an interceptor that recovers from
+// panics in the handlers of unary requests, so that a bug in a single handler
+// does not bring down the whole server. The panic is logged with its stack
+// trace, counted, and returned to the client as a `codes.Internal` error.
+func RecoveryUnaryServerInterceptor(log zerolog.Logger) grpc.UnaryServerInterceptor {
+	log = log.With().Str("component", "recovery").Logger()
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			r := recover()
+			if r != nil {
+				err = Recovered(ctx, log, info.FullMethod, r)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamServerInterceptor returns an interceptor that recovers from
+// panics in the handlers of streams, like the unary interceptor. Panics in the
+// goroutines that handlers start on their own are not recovered by it, and
+// have to be recovered by the handlers with `Recovered`.
+func RecoveryStreamServerInterceptor(log zerolog.Logger) grpc.StreamServerInterceptor {
+	log = log.With().Str("component", "recovery").Logger()
+
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			r := recover()
+			if r != nil {
+				err = Recovered(stream.Context(), log, info.FullMethod, r)
+			}
+		}()
+
+		return handler(srv, stream)
+	}
+}
+
+// Recovered logs and counts the given panic value, recovered while handling the
+// given method, and returns the error sent to the client instead of the response.
+func Recovered(ctx context.Context, log zerolog.Logger, method string, r interface{}) error {
+	event := log.Error().
+		Str("method", method).
+		Interface("panic", r).
+		Bytes("stack", debug.Stack())
+	id, ok := RequestIDFromContext(ctx)
+	if ok {
+		event = event.Str("request_id", id)
+	}
+	event.Msg("recovered from panic in handler")
+
+	panics.WithLabelValues(path.Base(method)).Inc()
+
+	return status.Error(codes.Internal, "internal error")
+}
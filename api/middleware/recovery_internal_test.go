@@ -0,0 +1,122 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestRecoveryUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetTransactionResult"}
+
+	t.Run("passes responses through", func(t *testing.T) {
+		t.Parallel()
+
+		interceptor := RecoveryUnaryServerInterceptor(zerolog.Nop())
+
+		resp, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+	})
+
+	t.Run("recovers from panic", func(t *testing.T) {
+		t.Parallel()
+
+		counter := panics.WithLabelValues("GetTransactionResult")
+		before := testutil.ToFloat64(counter)
+
+		interceptor := RecoveryUnaryServerInterceptor(zerolog.Nop())
+
+		_, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+			var result *access.TransactionResultResponse
+			return result.Events, nil
+		})
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, before+1, testutil.ToFloat64(counter))
+	})
+
+	t.Run("keeps server alive after panic", func(t *testing.T) {
+		t.Parallel()
+
+		listener := bufconn.Listen(1024 * 1024)
+		gsvr := grpc.NewServer(grpc.UnaryInterceptor(RecoveryUnaryServerInterceptor(zerolog.Nop())))
+		access.RegisterAccessAPIServer(gsvr, &panickingAPI{})
+		go func() {
+			_ = gsvr.Serve(listener)
+		}()
+		defer gsvr.Stop()
+
+		dialer := func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}
+		conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer conn.Close()
+		client := access.NewAccessAPIClient(conn)
+
+		_, err = client.GetTransactionResultByIndex(context.Background(), &access.GetTransactionByIndexRequest{})
+		assert.Equal(t, codes.Internal, status.Code(err))
+
+		_, err = client.Ping(context.Background(), &access.PingRequest{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestRecoveryStreamServerInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/flow.archive.access.ExtendedAPI/SubscribeExecutionData"}
+	interceptor := RecoveryStreamServerInterceptor(zerolog.Nop())
+
+	counter := panics.WithLabelValues("SubscribeExecutionData")
+	before := testutil.ToFloat64(counter)
+
+	stream := contextStream{ctx: context.Background()}
+	err := interceptor(nil, &stream, info, func(interface{}, grpc.ServerStream) error {
+		panic("handler failed")
+	})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+// panickingAPI is an Access API whose transaction result lookups panic.
+type panickingAPI struct {
+	access.UnimplementedAccessAPIServer
+}
+
+func (p *panickingAPI) Ping(context.Context, *access.PingRequest) (*access.PingResponse, error) {
+	return &access.PingResponse{}, nil
+}
+
+func (p *panickingAPI) GetTransactionResultByIndex(context.Context, *access.GetTransactionByIndexRequest) (*access.TransactionResultResponse, error) {
+	panic("handler failed")
+}
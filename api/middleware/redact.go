@@ -0,0 +1,294 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Redaction actions that can be applied to fields and events.
+const (
+	// RedactStrip removes the field or event from the response.
+	RedactStrip = "strip"
+	// RedactHash replaces the value of the field, or the payload of the event,
+	// with its SHA-256 hash.
+	RedactHash = "hash"
+)
+
+// Names of the event message and of the fields that event redaction relies on.
+const (
+	eventMessage = "flow.entities.Event"
+	eventType    = "type"
+	eventPayload = "payload"
+)
+
+// RedactionPolicy describes which fields and events are redacted from responses.
+type RedactionPolicy struct {
+	Fields []FieldRedaction `json:"fields"`
+	Events []EventRedaction `json:"events"`
+}
+
+// FieldRedaction redacts a field wherever its message appears in a response.
+// The field is identified by its full name, such as
+// `flow.entities.Transaction.script`.
+type FieldRedaction struct {
+	Field  string `json:"field"`
+	Action string `json:"action"`
+}
+
+// EventRedaction redacts the events of a type, such as
+// `A.1654653399040a61.FlowToken.TokensDeposited`, from a response.
+type EventRedaction struct {
+	Type   string `json:"type"`
+	Action string `json:"action"`
+}
+
+// LoadRedactionPolicy reads a redaction policy from the JSON file at the given path.
+func LoadRedactionPolicy(path string) (RedactionPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RedactionPolicy{}, fmt.Errorf("could not read policy file: %w", err)
+	}
+
+	var policy RedactionPolicy
+	err = json.Unmarshal(data, &policy)
+	if err != nil {
+		return RedactionPolicy{}, fmt.Errorf("could not decode policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// Redactor strips or hashes the fields and events of responses that are
+// configured in a redaction policy, before they are sent to the client.
+type Redactor struct {
+	fields map[protoreflect.FullName]string
+	events map[string]string
+}
+
+// NewRedactor creates a redactor for the given policy. It fails if the policy
+// refers to unknown fields, or hashes fields that are neither bytes nor strings.
+func NewRedactor(policy RedactionPolicy) (*Redactor, error) {
+	r := Redactor{
+		fields: make(map[protoreflect.FullName]string, len(policy.Fields)),
+		events: make(map[string]string, len(policy.Events)),
+	}
+
+	for _, rule := range policy.Fields {
+		name := protoreflect.FullName(rule.Field)
+		field, err := findField(name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch rule.Action {
+		case RedactStrip:
+		case RedactHash:
+			if field.IsList() || field.IsMap() || (field.Kind() != protoreflect.BytesKind && field.Kind() != protoreflect.StringKind) {
+				return nil, fmt.Errorf("could not hash field %s: only bytes and string fields can be hashed", name)
+			}
+		default:
+			return nil, fmt.Errorf("invalid action %q for field %s", rule.Action, name)
+		}
+
+		r.fields[name] = rule.Action
+	}
+
+	for _, rule := range policy.Events {
+		if rule.Action != RedactStrip && rule.Action != RedactHash {
+			return nil, fmt.Errorf("invalid action %q for event type %s", rule.Action, rule.Type)
+		}
+
+		r.events[rule.Type] = rule.Action
+	}
+
+	return &r, nil
+}
+
+// UnaryServerInterceptor returns an interceptor that redacts unary responses.
+func (r *Redactor) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Redact(resp)
+
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that redacts the messages sent
+// on streams.
+func (r *Redactor) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := redactedStream{
+			ServerStream: stream,
+			redactor:     r,
+		}
+
+		return handler(srv, &wrapped)
+	}
+}
+
+// Redact redacts the given response in place. Responses that are not protocol
+// buffer messages are left as they are.
+func (r *Redactor) Redact(resp interface{}) {
+	msg, ok := resp.(proto.Message)
+	if !ok || msg == nil {
+		return
+	}
+
+	r.redact(proto.MessageReflect(msg))
+}
+
+func (r *Redactor) redact(msg protoreflect.Message) {
+	if !msg.IsValid() {
+		return
+	}
+
+	// The populated fields are collected first, as the message should not be
+	// modified while its fields are being ranged over.
+	var fields []protoreflect.FieldDescriptor
+	msg.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, field)
+		return true
+	})
+
+	for _, field := range fields {
+		switch r.fields[field.FullName()] {
+		case RedactStrip:
+			msg.Clear(field)
+			continue
+		case RedactHash:
+			msg.Set(field, hashValue(field, msg.Get(field)))
+			continue
+		}
+
+		if field.Message() == nil {
+			continue
+		}
+
+		switch {
+		case field.IsList():
+			r.redactList(msg.Mutable(field).List())
+		case field.IsMap():
+			if field.MapValue().Message() == nil {
+				continue
+			}
+			msg.Mutable(field).Map().Range(func(_ protoreflect.MapKey, value protoreflect.Value) bool {
+				r.redact(value.Message())
+				return true
+			})
+		default:
+			r.redactField(msg, field)
+		}
+	}
+}
+
+// redactList redacts the messages of a list, and removes the stripped events.
+func (r *Redactor) redactList(list protoreflect.List) {
+	kept := 0
+	for i := 0; i < list.Len(); i++ {
+		value := list.Get(i)
+		if r.redactMessage(value.Message()) {
+			list.Set(kept, value)
+			kept++
+		}
+	}
+	list.Truncate(kept)
+}
+
+// redactField redacts a singular message field, and clears it if it holds a
+// stripped event.
+func (r *Redactor) redactField(msg protoreflect.Message, field protoreflect.FieldDescriptor) {
+	if !r.redactMessage(msg.Mutable(field).Message()) {
+		msg.Clear(field)
+	}
+}
+
+// redactMessage redacts a message, and returns whether it should be kept, which
+// is not the case of events whose type is stripped.
+func (r *Redactor) redactMessage(msg protoreflect.Message) bool {
+	if msg.Descriptor().FullName() != eventMessage || len(r.events) == 0 {
+		r.redact(msg)
+		return true
+	}
+
+	fields := msg.Descriptor().Fields()
+	typ := msg.Get(fields.ByName(eventType)).String()
+	switch r.events[typ] {
+	case RedactStrip:
+		return false
+	case RedactHash:
+		payload := fields.ByName(eventPayload)
+		msg.Set(payload, hashValue(payload, msg.Get(payload)))
+	}
+
+	r.redact(msg)
+	return true
+}
+
+// hashValue returns the SHA-256 hash of a bytes or string value, as raw bytes
+// or as a hexadecimal string respectively.
+func hashValue(field protoreflect.FieldDescriptor, value protoreflect.Value) protoreflect.Value {
+	if field.Kind() == protoreflect.StringKind {
+		hash := sha256.Sum256([]byte(value.String()))
+		return protoreflect.ValueOfString(hex.EncodeToString(hash[:]))
+	}
+
+	hash := sha256.Sum256(value.Bytes())
+	return protoreflect.ValueOfBytes(hash[:])
+}
+
+// findField looks up the descriptor of the field with the given full name.
+func findField(name protoreflect.FullName) (protoreflect.FieldDescriptor, error) {
+	descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(name.Parent())
+	if err != nil {
+		return nil, fmt.Errorf("could not find message for field %s: %w", name, err)
+	}
+	message, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("could not find message for field %s: %s is not a message", name, name.Parent())
+	}
+	field := message.Fields().ByName(name.Name())
+	if field == nil {
+		return nil, fmt.Errorf("could not find field %s", name)
+	}
+
+	return field, nil
+}
+
+type redactedStream struct {
+	grpc.ServerStream
+	redactor *Redactor
+}
+
+// SendMsg implements the grpc.ServerStream interface.
+func (r *redactedStream) SendMsg(m interface{}) error {
+	r.redactor.Redact(m)
+	return r.ServerStream.SendMsg(m)
+}
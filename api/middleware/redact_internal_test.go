@@ -0,0 +1,208 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+)
+
+func TestLoadRedactionPolicy(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "policy.json")
+		data := `{"fields":[{"field":"flow.entities.Transaction.script","action":"strip"}],"events":[{"type":"A.0.Test.Event","action":"hash"}]}`
+		err := os.WriteFile(path, []byte(data), 0600)
+		require.NoError(t, err)
+
+		policy, err := LoadRedactionPolicy(path)
+
+		require.NoError(t, err)
+		assert.Equal(t, []FieldRedaction{{Field: "flow.entities.Transaction.script", Action: RedactStrip}}, policy.Fields)
+		assert.Equal(t, []EventRedaction{{Type: "A.0.Test.Event", Action: RedactHash}}, policy.Events)
+	})
+
+	t.Run("handles missing file", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := LoadRedactionPolicy(filepath.Join(t.TempDir(), "missing.json"))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid policy", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "policy.json")
+		err := os.WriteFile(path, []byte("fields: []"), 0600)
+		require.NoError(t, err)
+
+		_, err = LoadRedactionPolicy(path)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestNewRedactor(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		policy := RedactionPolicy{
+			Fields: []FieldRedaction{
+				{Field: "flow.entities.Transaction.script", Action: RedactStrip},
+				{Field: "flow.access.TransactionResultResponse.error_message", Action: RedactHash},
+			},
+			Events: []EventRedaction{{Type: "A.0.Test.Event", Action: RedactStrip}},
+		}
+
+		_, err := NewRedactor(policy)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("handles unknown field", func(t *testing.T) {
+		t.Parallel()
+
+		policy := RedactionPolicy{Fields: []FieldRedaction{{Field: "flow.entities.Transaction.unknown", Action: RedactStrip}}}
+		_, err := NewRedactor(policy)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles unknown message", func(t *testing.T) {
+		t.Parallel()
+
+		policy := RedactionPolicy{Fields: []FieldRedaction{{Field: "flow.entities.Unknown.script", Action: RedactStrip}}}
+		_, err := NewRedactor(policy)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles hash of message field", func(t *testing.T) {
+		t.Parallel()
+
+		policy := RedactionPolicy{Fields: []FieldRedaction{{Field: "flow.access.TransactionResponse.transaction", Action: RedactHash}}}
+		_, err := NewRedactor(policy)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid field action", func(t *testing.T) {
+		t.Parallel()
+
+		policy := RedactionPolicy{Fields: []FieldRedaction{{Field: "flow.entities.Transaction.script", Action: "remove"}}}
+		_, err := NewRedactor(policy)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid event action", func(t *testing.T) {
+		t.Parallel()
+
+		policy := RedactionPolicy{Events: []EventRedaction{{Type: "A.0.Test.Event", Action: "remove"}}}
+		_, err := NewRedactor(policy)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestRedactor_UnaryServerInterceptor(t *testing.T) {
+	policy := RedactionPolicy{
+		Fields: []FieldRedaction{
+			{Field: "flow.entities.Transaction.script", Action: RedactStrip},
+			{Field: "flow.access.TransactionResultResponse.error_message", Action: RedactHash},
+		},
+		Events: []EventRedaction{
+			{Type: "A.0.Test.Stripped", Action: RedactStrip},
+			{Type: "A.0.Test.Hashed", Action: RedactHash},
+		},
+	}
+	redactor, err := NewRedactor(policy)
+	require.NoError(t, err)
+
+	interceptor := redactor.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetTransaction"}
+
+	t.Run("redacts fields", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+			tx := entities.Transaction{
+				Script:    []byte("script"),
+				Arguments: [][]byte{[]byte("argument")},
+			}
+			return &access.TransactionResponse{Transaction: &tx}, nil
+		})
+
+		require.NoError(t, err)
+		require.IsType(t, &access.TransactionResponse{}, resp)
+		tx := resp.(*access.TransactionResponse).Transaction
+		assert.Empty(t, tx.Script)
+		assert.Equal(t, [][]byte{[]byte("argument")}, tx.Arguments)
+	})
+
+	t.Run("redacts events", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+			result := access.TransactionResultResponse{
+				ErrorMessage: "error",
+				Events: []*entities.Event{
+					{Type: "A.0.Test.Kept", Payload: []byte("kept"), EventIndex: 0},
+					{Type: "A.0.Test.Stripped", Payload: []byte("stripped"), EventIndex: 1},
+					{Type: "A.0.Test.Hashed", Payload: []byte("hashed"), EventIndex: 2},
+				},
+			}
+			return &result, nil
+		})
+
+		require.NoError(t, err)
+		require.IsType(t, &access.TransactionResultResponse{}, resp)
+		result := resp.(*access.TransactionResultResponse)
+
+		errHash := sha256.Sum256([]byte("error"))
+		assert.Equal(t, hex.EncodeToString(errHash[:]), result.ErrorMessage)
+
+		payloadHash := sha256.Sum256([]byte("hashed"))
+		require.Len(t, result.Events, 2)
+		assert.Equal(t, "A.0.Test.Kept", result.Events[0].Type)
+		assert.Equal(t, []byte("kept"), result.Events[0].Payload)
+		assert.Equal(t, "A.0.Test.Hashed", result.Events[1].Type)
+		assert.Equal(t, payloadHash[:], result.Events[1].Payload)
+	})
+
+	t.Run("does not redact errors", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, assert.AnError
+		})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, resp)
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
+)
+
+// RequestIDHeader is the metadata header that carries the request ID, both on
+// incoming requests and on outgoing calls to the archive.
+const RequestIDHeader = "x-request-id"
+
+// requestIDTag is the tag under which the request ID is added to the logs.
+const requestIDTag = "request_id"
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID stored in the given context, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// RequestIDUnaryServerInterceptor returns an interceptor that reads the request ID
+// from the incoming metadata, or generates one if it is absent, and stores it in
+// the request context. It needs to come after the tags interceptor, so that the
+// request ID is included in the logs.
+func RequestIDUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// RequestIDStreamServerInterceptor returns an interceptor that reads the request
+// ID from the incoming metadata, or generates one if it is absent, and stores it
+// in the stream context.
+func RequestIDStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpcmiddleware.WrapServerStream(stream)
+		wrapped.WrappedContext = withRequestID(stream.Context())
+
+		return handler(srv, wrapped)
+	}
+}
+
+// RequestIDConn wraps a client connection so that every call made through it
+// carries the given request ID in its outgoing metadata. It is used to propagate
+// the request ID to the archive, whose index reader does not take a context.
+func RequestIDConn(conn grpc.ClientConnInterface, id string) grpc.ClientConnInterface {
+	r := requestIDConn{
+		ClientConnInterface: conn,
+		id:                  id,
+	}
+
+	return &r
+}
+
+type requestIDConn struct {
+	grpc.ClientConnInterface
+	id string
+}
+
+// Invoke implements the grpc.ClientConnInterface interface.
+func (r *requestIDConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, RequestIDHeader, r.id)
+	return r.ClientConnInterface.Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream implements the grpc.ClientConnInterface interface.
+func (r *requestIDConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, RequestIDHeader, r.id)
+	return r.ClientConnInterface.NewStream(ctx, desc, method, opts...)
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		values := md.Get(RequestIDHeader)
+		if len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	tags.Extract(ctx).Set(requestIDTag, id)
+
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
+)
+
+func TestRequestIDUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetBlockByHeight"}
+
+	t.Run("propagates request ID to archive calls", func(t *testing.T) {
+		t.Parallel()
+
+		md := metadata.Pairs(RequestIDHeader, "test-request")
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		ctx = tags.SetInContext(ctx, tags.NewTags())
+
+		conn := &recordingConn{}
+		handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+			id, ok := RequestIDFromContext(ctx)
+			require.True(t, ok)
+
+			err := RequestIDConn(conn, id).Invoke(ctx, "/archive.API/GetLast", nil, nil)
+			return nil, err
+		}
+
+		_, err := RequestIDUnaryServerInterceptor()(ctx, nil, info, handler)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"test-request"}, conn.md.Get(RequestIDHeader))
+		assert.Equal(t, "test-request", tags.Extract(ctx).Values()[requestIDTag])
+	})
+
+	t.Run("generates missing request ID", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := tags.SetInContext(context.Background(), tags.NewTags())
+
+		var id string
+		handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+			var ok bool
+			id, ok = RequestIDFromContext(ctx)
+			require.True(t, ok)
+			return nil, nil
+		}
+
+		_, err := RequestIDUnaryServerInterceptor()(ctx, nil, info, handler)
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, id)
+		assert.Equal(t, id, tags.Extract(ctx).Values()[requestIDTag])
+	})
+}
+
+func TestRequestIDStreamServerInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/flow.archive.access.ExtendedAPI/ExecuteScripts"}
+
+	md := metadata.Pairs(RequestIDHeader, "test-request")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	var id string
+	handler := func(_ interface{}, stream grpc.ServerStream) error {
+		id, _ = RequestIDFromContext(stream.Context())
+		return nil
+	}
+
+	err := RequestIDStreamServerInterceptor()(nil, &contextStream{ctx: ctx}, info, handler)
+
+	require.NoError(t, err)
+	assert.Equal(t, "test-request", id)
+}
+
+func TestRequestIDConn(t *testing.T) {
+	conn := &recordingConn{}
+
+	_, err := RequestIDConn(conn, "test-request").NewStream(context.Background(), &grpc.StreamDesc{}, "/archive.API/GetLast")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test-request"}, conn.md.Get(RequestIDHeader))
+}
+
+// recordingConn records the outgoing metadata of the last call made through it.
+type recordingConn struct {
+	md metadata.MD
+}
+
+func (r *recordingConn) Invoke(ctx context.Context, _ string, _ interface{}, _ interface{}, _ ...grpc.CallOption) error {
+	r.md, _ = metadata.FromOutgoingContext(ctx)
+	return nil
+}
+
+func (r *recordingConn) NewStream(ctx context.Context, _ *grpc.StreamDesc, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+	r.md, _ = metadata.FromOutgoingContext(ctx)
+	return nil, nil
+}
+
+// contextStream is a server stream that only provides a context.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (c *contextStream) Context() context.Context {
+	return c.ctx
+}
@@ -0,0 +1,211 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// LoadShedder rejects part of the requests for expensive methods while the
+// backend is degraded, so that the server stays responsive for cheap reads
+// instead of piling more load onto a struggling archive. The backend is
+// considered degraded when the error rate or the average latency of the most
+// recent unary requests exceeds its threshold.
+type LoadShedder struct {
+	expensive map[string]struct{}
+	errorRate float64
+	latency   time.Duration
+	fraction  float64
+	retry     time.Duration
+
+	mu       sync.Mutex
+	outcomes []outcome
+	next     int
+	filled   bool
+	debt     float64
+}
+
+// outcome is the outcome of a handled request, as observed by the shedder.
+type outcome struct {
+	failed   bool
+	duration time.Duration
+}
+
+// NewLoadShedder creates a shedder that watches the outcomes of the last
+// `window` unary requests, and considers the backend degraded when the share
+// of them that failed with a backend error exceeds `errorRate`, or when their
+// average duration exceeds `latency`. A threshold of zero is not checked. While
+// the backend is degraded, the given `fraction` of the requests for the
+// `expensive` methods are rejected, with a hint to retry after `retry`.
+// Methods are identified by their name, without the service prefix.
+func NewLoadShedder(expensive []string, window uint, errorRate float64, latency time.Duration, fraction float64, retry time.Duration) *LoadShedder {
+	methods := make(map[string]struct{}, len(expensive))
+	for _, method := range expensive {
+		methods[method] = struct{}{}
+	}
+
+	l := LoadShedder{
+		expensive: methods,
+		errorRate: errorRate,
+		latency:   latency,
+		fraction:  fraction,
+		retry:     retry,
+		outcomes:  make([]outcome, window),
+	}
+
+	return &l
+}
+
+// UnaryServerInterceptor returns an interceptor that sheds unary requests for
+// expensive methods while the backend is degraded, and observes the outcome of
+// the requests that are handled.
+func (l *LoadShedder) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		err := l.admit(info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		l.observe(err, time.Since(start))
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that sheds streams for
+// expensive methods while the backend is degraded. Streams are not observed,
+// as they are meant to stay open for as long as the client needs them.
+func (l *LoadShedder) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := l.admit(info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+// admit returns an unavailable error, with a retry hint, if a request for the
+// given method is to be shed.
+func (l *LoadShedder) admit(fullMethod string) error {
+	method := path.Base(fullMethod)
+	_, ok := l.expensive[method]
+	if !ok {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.degraded() {
+		l.debt = 0
+		return nil
+	}
+
+	// The requests to shed are spread evenly, rather than picked at random, so
+	// that exactly the configured fraction of them is rejected.
+	l.debt += l.fraction
+	if l.debt < 1 {
+		return nil
+	}
+	l.debt--
+
+	shedRequests.WithLabelValues(method).Inc()
+
+	st := status.Newf(codes.Unavailable, "backend is degraded, shedding requests for %s", method)
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(l.retry)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// observe records the outcome of a handled request.
+func (l *LoadShedder) observe(err error, duration time.Duration) {
+	if len(l.outcomes) == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.outcomes[l.next] = outcome{failed: backendFailure(err), duration: duration}
+	l.next = (l.next + 1) % len(l.outcomes)
+	if l.next == 0 {
+		l.filled = true
+	}
+}
+
+// degraded returns whether the observed outcomes exceed one of the thresholds.
+// Until the window has been filled, the backend is not considered degraded, so
+// that a few failures right after startup do not trigger shedding.
+func (l *LoadShedder) degraded() bool {
+	if !l.filled {
+		return false
+	}
+
+	var failed int
+	var total time.Duration
+	for _, outcome := range l.outcomes {
+		if outcome.failed {
+			failed++
+		}
+		total += outcome.duration
+	}
+
+	count := len(l.outcomes)
+	if l.errorRate > 0 && float64(failed)/float64(count) > l.errorRate {
+		return true
+	}
+	if l.latency > 0 && total/time.Duration(count) > l.latency {
+		return true
+	}
+
+	return false
+}
+
+// backendFailure returns whether the given error is caused by the server or
+// its backend, rather than by the request. Handlers wrap the errors of the
+// archive, so the gRPC status is looked up through the errors that wrap it.
+// Errors without a status, such as failing scripts, and statuses caused by the
+// request, such as unknown block IDs, are not counted, so that clients cannot
+// trigger shedding for everyone by sending bad requests.
+func backendFailure(err error) bool {
+	var st interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &st) {
+		return false
+	}
+
+	switch st.GRPCStatus().Code() {
+	case codes.Internal, codes.Unavailable, codes.DeadlineExceeded, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoadShedder_UnaryServerInterceptor(t *testing.T) {
+	script := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/ExecuteScriptAtBlockHeight"}
+	block := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetBlockByHeight"}
+	expensive := []string{"ExecuteScriptAtBlockHeight"}
+
+	succeeding := func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	failing := func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "archive unavailable")
+	}
+
+	// shed returns how many of the given number of requests are rejected.
+	shed := func(t *testing.T, interceptor grpc.UnaryServerInterceptor, info *grpc.UnaryServerInfo, requests int) int {
+		var rejected int
+		for i := 0; i < requests; i++ {
+			_, err := interceptor(context.Background(), nil, info, succeeding)
+			if status.Code(err) == codes.Unavailable {
+				rejected++
+			}
+		}
+		return rejected
+	}
+
+	t.Run("serves all requests with healthy backend", func(t *testing.T) {
+		t.Parallel()
+
+		shedder := NewLoadShedder(expensive, 10, 0.5, 0, 0.5, time.Second)
+		interceptor := shedder.UnaryServerInterceptor()
+
+		for i := 0; i < 10; i++ {
+			_, _ = interceptor(context.Background(), nil, block, succeeding)
+		}
+
+		assert.Zero(t, shed(t, interceptor, script, 10))
+	})
+
+	t.Run("sheds expensive requests on high error rate", func(t *testing.T) {
+		t.Parallel()
+
+		shedder := NewLoadShedder(expensive, 10, 0.5, 0, 0.5, time.Second)
+		interceptor := shedder.UnaryServerInterceptor()
+
+		for i := 0; i < 10; i++ {
+			_, err := interceptor(context.Background(), nil, block, failing)
+			require.Error(t, err)
+		}
+
+		// The requests that are let through succeed, but not enough of them
+		// to bring the error rate back under the threshold.
+		assert.Equal(t, 2, shed(t, interceptor, script, 4))
+	})
+
+	t.Run("keeps serving cheap requests on high error rate", func(t *testing.T) {
+		t.Parallel()
+
+		shedder := NewLoadShedder(expensive, 10, 0.5, 0, 1, time.Second)
+		interceptor := shedder.UnaryServerInterceptor()
+
+		for i := 0; i < 10; i++ {
+			_, _ = interceptor(context.Background(), nil, block, failing)
+		}
+
+		resp, err := interceptor(context.Background(), nil, block, succeeding)
+
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+	})
+
+	t.Run("sends retry hint", func(t *testing.T) {
+		t.Parallel()
+
+		shedder := NewLoadShedder(expensive, 10, 0.5, 0, 1, 3*time.Second)
+		interceptor := shedder.UnaryServerInterceptor()
+
+		for i := 0; i < 10; i++ {
+			_, _ = interceptor(context.Background(), nil, block, failing)
+		}
+
+		_, err := interceptor(context.Background(), nil, script, succeeding)
+
+		st := status.Convert(err)
+		require.Equal(t, codes.Unavailable, st.Code())
+		require.Len(t, st.Details(), 1)
+		info, ok := st.Details()[0].(*errdetails.RetryInfo)
+		require.True(t, ok)
+		assert.Equal(t, 3*time.Second, info.RetryDelay.AsDuration())
+	})
+
+	t.Run("sheds expensive requests on high latency", func(t *testing.T) {
+		t.Parallel()
+
+		shedder := NewLoadShedder(expensive, 2, 0, time.Millisecond, 1, time.Second)
+		interceptor := shedder.UnaryServerInterceptor()
+
+		slow := func(context.Context, interface{}) (interface{}, error) {
+			time.Sleep(5 * time.Millisecond)
+			return "ok", nil
+		}
+		for i := 0; i < 2; i++ {
+			_, _ = interceptor(context.Background(), nil, block, slow)
+		}
+
+		assert.Equal(t, 1, shed(t, interceptor, script, 1))
+	})
+
+	t.Run("ignores client errors", func(t *testing.T) {
+		t.Parallel()
+
+		shedder := NewLoadShedder(expensive, 10, 0.5, 0, 1, time.Second)
+		interceptor := shedder.UnaryServerInterceptor()
+
+		invalid := func(context.Context, interface{}) (interface{}, error) {
+			return nil, status.Error(codes.InvalidArgument, "invalid height")
+		}
+		for i := 0; i < 10; i++ {
+			_, _ = interceptor(context.Background(), nil, block, invalid)
+		}
+
+		assert.Zero(t, shed(t, interceptor, script, 10))
+	})
+
+	t.Run("ignores wrapped not found and script errors", func(t *testing.T) {
+		t.Parallel()
+
+		shedder := NewLoadShedder(expensive, 10, 0.5, 0, 1, time.Second)
+		interceptor := shedder.UnaryServerInterceptor()
+
+		// Handlers wrap the errors of the archive and of scripts, which then
+		// have no status of their own.
+		errs := []error{
+			status.Error(codes.NotFound, "block not found"),
+			fmt.Errorf("could not get height for block: %w", status.Error(codes.NotFound, "Key not found")),
+			fmt.Errorf("could not get height for block: %w", errors.New("Key not found")),
+			fmt.Errorf("could not execute script: %w", errors.New("cannot find declaration")),
+		}
+		for i := 0; i < 10; i++ {
+			err := errs[i%len(errs)]
+			_, _ = interceptor(context.Background(), nil, block, func(context.Context, interface{}) (interface{}, error) {
+				return nil, err
+			})
+		}
+
+		assert.Zero(t, shed(t, interceptor, script, 10))
+	})
+
+	t.Run("sheds expensive requests on wrapped backend errors", func(t *testing.T) {
+		t.Parallel()
+
+		shedder := NewLoadShedder(expensive, 10, 0.5, 0, 1, time.Second)
+		interceptor := shedder.UnaryServerInterceptor()
+
+		wrapped := func(context.Context, interface{}) (interface{}, error) {
+			return nil, fmt.Errorf("could not get header: %w", status.Error(codes.Unavailable, "archive unavailable"))
+		}
+		for i := 0; i < 10; i++ {
+			_, _ = interceptor(context.Background(), nil, block, wrapped)
+		}
+
+		assert.Equal(t, 1, shed(t, interceptor, script, 1))
+	})
+
+	t.Run("recovers once backend is healthy again", func(t *testing.T) {
+		t.Parallel()
+
+		shedder := NewLoadShedder(expensive, 10, 0.5, 0, 1, time.Second)
+		interceptor := shedder.UnaryServerInterceptor()
+
+		for i := 0; i < 10; i++ {
+			_, _ = interceptor(context.Background(), nil, block, failing)
+		}
+		require.Equal(t, 1, shed(t, interceptor, script, 1))
+
+		for i := 0; i < 10; i++ {
+			_, _ = interceptor(context.Background(), nil, block, succeeding)
+		}
+
+		assert.Zero(t, shed(t, interceptor, script, 10))
+	})
+}
+
+func TestLoadShedder_StreamServerInterceptor(t *testing.T) {
+	block := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetBlockByHeight"}
+	scripts := &grpc.StreamServerInfo{FullMethod: "/flow.extended.ExtendedAPI/ExecuteScripts"}
+
+	shedder := NewLoadShedder([]string{"ExecuteScripts"}, 10, 0.5, 0, 1, time.Second)
+
+	failing := func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "archive unavailable")
+	}
+	for i := 0; i < 10; i++ {
+		_, _ = shedder.UnaryServerInterceptor()(context.Background(), nil, block, failing)
+	}
+
+	called := false
+	handler := func(interface{}, grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+	err := shedder.StreamServerInterceptor()(nil, nil, scripts, handler)
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.False(t, called)
+}
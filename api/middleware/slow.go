@@ -0,0 +1,80 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// SlowUnaryServerInterceptor returns an interceptor that logs a warning for each
+// unary request that takes longer than the given threshold to handle, along with
+// the height it is for, if any. Streams are not covered, as they are meant to
+// stay open for as long as the client needs them.
+func SlowUnaryServerInterceptor(log zerolog.Logger, threshold time.Duration) grpc.UnaryServerInterceptor {
+	log = log.With().Str("component", "slow_requests").Logger()
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+		if duration < threshold {
+			return resp, err
+		}
+
+		event := log.Warn().
+			Str("method", info.FullMethod).
+			Str("code", status.Code(err).String()).
+			Dur("duration", duration)
+		height, ok := requestHeight(req)
+		if ok {
+			event = event.Uint64("height", height)
+		}
+		id, ok := RequestIDFromContext(ctx)
+		if ok {
+			event = event.Str("request_id", id)
+		}
+		event.Msg("slow request")
+
+		return resp, err
+	}
+}
+
+// requestHeight returns the height of the given request, which is the first of
+// its `height`, `block_height` or `start_height` fields that it has.
+func requestHeight(req interface{}) (uint64, bool) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return 0, false
+	}
+
+	reflected := proto.MessageReflect(msg)
+	fields := reflected.Descriptor().Fields()
+	for _, name := range []protoreflect.Name{"height", "block_height", "start_height"} {
+		field := fields.ByName(name)
+		if field == nil || field.Kind() != protoreflect.Uint64Kind {
+			continue
+		}
+		return reflected.Get(field).Uint(), true
+	}
+
+	return 0, false
+}
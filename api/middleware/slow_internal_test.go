@@ -0,0 +1,93 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestSlowUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/flow.access.AccessAPI/GetBlockByHeight"}
+
+	t.Run("logs slow requests", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		interceptor := SlowUnaryServerInterceptor(zerolog.New(&buf), 10*time.Millisecond)
+
+		req := &access.GetBlockByHeightRequest{Height: 42}
+		resp, err := interceptor(context.Background(), req, info, func(context.Context, interface{}) (interface{}, error) {
+			time.Sleep(20 * time.Millisecond)
+			return "ok", nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+
+		var line map[string]interface{}
+		err = json.Unmarshal(buf.Bytes(), &line)
+		require.NoError(t, err)
+		assert.Equal(t, "warn", line["level"])
+		assert.Equal(t, info.FullMethod, line["method"])
+		assert.Equal(t, float64(42), line["height"])
+		assert.GreaterOrEqual(t, line["duration"], float64(20))
+	})
+
+	t.Run("logs slow requests without height", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		interceptor := SlowUnaryServerInterceptor(zerolog.New(&buf), 10*time.Millisecond)
+
+		req := &access.GetLatestBlockRequest{}
+		_, err := interceptor(context.Background(), req, info, func(context.Context, interface{}) (interface{}, error) {
+			time.Sleep(20 * time.Millisecond)
+			return "ok", nil
+		})
+
+		require.NoError(t, err)
+
+		var line map[string]interface{}
+		err = json.Unmarshal(buf.Bytes(), &line)
+		require.NoError(t, err)
+		assert.NotContains(t, line, "height")
+	})
+
+	t.Run("does not log fast requests", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		interceptor := SlowUnaryServerInterceptor(zerolog.New(&buf), time.Minute)
+
+		req := &access.GetBlockByHeightRequest{Height: 42}
+		_, err := interceptor(context.Background(), req, info, func(context.Context, interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+
+		require.NoError(t, err)
+		assert.Empty(t, buf.Bytes())
+	})
+}
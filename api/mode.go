@@ -0,0 +1,59 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"fmt"
+)
+
+// Mode determines whether the server proxies requests that it cannot serve from
+// the index to its upstream access node.
+type Mode string
+
+// Supported modes.
+const (
+	// ModeArchiveOnly serves all requests from the index. Methods that are
+	// not implemented by the archive return an unimplemented error.
+	ModeArchiveOnly Mode = "archive-only"
+	// ModeHybrid proxies requests to the upstream access node for methods that
+	// are not implemented by the archive, and for requests for the latest state
+	// when the index is too stale for them.
+	ModeHybrid Mode = "hybrid"
+)
+
+// ProxyMethods are the Access API methods whose requests can be proxied to the
+// upstream access node, depending on the mode of the server.
+var ProxyMethods = []string{
+	"GetLatestBlockHeader",
+	"GetBlockHeaderByID",
+	"GetBlockHeaderByHeight",
+	"GetExecutionResultForBlockID",
+	"SendTransaction",
+	"GetLatestProtocolStateSnapshot",
+	"GetLatestBlock",
+	"GetAccountAtLatestBlock",
+	"ExecuteScriptAtLatestBlock",
+}
+
+// ParseMode parses the given mode name.
+func ParseMode(name string) (Mode, error) {
+	mode := Mode(name)
+	switch mode {
+	case ModeArchiveOnly, ModeHybrid:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q (must be %s or %s)", name, ModeArchiveOnly, ModeHybrid)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/onflow/flow-archive/models/archive"
+)
+
+// pinnedReader wraps an index reader so that it never reports a last height
+// above a pinned head, and does not find blocks or transactions above it, as
+// if indexing had stopped at that height.
+type pinnedReader struct {
+	archive.Reader
+
+	head uint64
+}
+
+// Last implements the archive.Reader interface. Until the index reaches the
+// pinned head, its own last height is returned.
+func (p *pinnedReader) Last() (uint64, error) {
+	last, err := p.Reader.Last()
+	if err != nil {
+		return 0, err
+	}
+
+	if last > p.head {
+		return p.head, nil
+	}
+
+	return last, nil
+}
+
+// HeightForBlock implements the archive.Reader interface, and does not find
+// blocks above the pinned head.
+func (p *pinnedReader) HeightForBlock(blockID flow.Identifier) (uint64, error) {
+	height, err := p.Reader.HeightForBlock(blockID)
+	if err != nil {
+		return 0, err
+	}
+
+	if height > p.head {
+		return 0, fmt.Errorf("block %x is above pinned head %d: %w", blockID, p.head, badger.ErrKeyNotFound)
+	}
+
+	return height, nil
+}
+
+// HeightForTransaction implements the archive.Reader interface, and does not
+// find transactions above the pinned head.
+func (p *pinnedReader) HeightForTransaction(txID flow.Identifier) (uint64, error) {
+	height, err := p.Reader.HeightForTransaction(txID)
+	if err != nil {
+		return 0, err
+	}
+
+	if height > p.head {
+		return 0, fmt.Errorf("transaction %x is above pinned head %d: %w", txID, p.head, badger.ErrKeyNotFound)
+	}
+
+	return height, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow-archive/testing/mocks"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+
+	"github.com/onflow/flow-archive-access/api/extended"
+)
+
+func TestServer_PinnedHead(t *testing.T) {
+	head := mocks.GenericHeight + 1
+
+	t.Run("reports pinned head as last height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return head + 10, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.PinnedHead = head
+
+		resp, err := s.GetIndexedHeightRange(context.Background(), &extended.GetIndexedHeightRangeRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, head, resp.Last)
+	})
+
+	t.Run("reports last indexed height below pinned head", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.PinnedHead = head + 10
+
+		resp, err := s.GetIndexedHeightRange(context.Background(), &extended.GetIndexedHeightRangeRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, resp.Last)
+	})
+
+	t.Run("serves latest block at pinned head", func(t *testing.T) {
+		t.Parallel()
+
+		var gotHeight uint64
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return head + 10, nil
+		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			gotHeight = height
+			return mocks.GenericHeader, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.PinnedHead = head
+
+		_, err := s.GetLatestBlock(context.Background(), &access.GetLatestBlockRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, head, gotHeight)
+	})
+
+	t.Run("rejects heights above pinned head", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return head + 10, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.PinnedHead = head
+
+		_, err := s.GetBlockByHeight(context.Background(), &access.GetBlockByHeightRequest{Height: head + 1})
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+
+	t.Run("does not find blocks and transactions above pinned head", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return head + 1, nil
+		}
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return head + 1, nil
+		}
+		pinned := &pinnedReader{Reader: index, head: head}
+
+		_, err := pinned.HeightForBlock(mocks.GenericHeader.ID())
+		assert.True(t, isNotFound(err))
+
+		_, err = pinned.HeightForTransaction(mocks.GenericTransactionIDs(1)[0])
+		assert.True(t, isNotFound(err))
+	})
+
+	t.Run("finds blocks and transactions at pinned head", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return head, nil
+		}
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return head, nil
+		}
+		pinned := &pinnedReader{Reader: index, head: head}
+
+		height, err := pinned.HeightForBlock(mocks.GenericHeader.ID())
+		require.NoError(t, err)
+		assert.Equal(t, head, height)
+
+		height, err = pinned.HeightForTransaction(mocks.GenericTransactionIDs(1)[0])
+		require.NoError(t, err)
+		assert.Equal(t, head, height)
+	})
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"sync"
+
+	"github.com/onflow/flow-go/ledger"
+
+	"github.com/onflow/flow-archive/models/archive"
+
+	"github.com/onflow/flow-archive-access/api/extended"
+)
+
+// registerRecorder wraps an index reader and records every register value that
+// is read through it, in the order in which it was first read.
+type registerRecorder struct {
+	archive.Reader
+
+	mu        sync.Mutex
+	seen      map[ledger.Path]struct{}
+	registers []*extended.Register
+}
+
+func newRegisterRecorder(index archive.Reader) *registerRecorder {
+	r := registerRecorder{
+		Reader: index,
+		seen:   make(map[ledger.Path]struct{}),
+	}
+
+	return &r
+}
+
+// Values implements the archive.Reader interface and records the values read.
+func (r *registerRecorder) Values(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+	values, err := r.Reader.Values(height, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, path := range paths {
+		_, ok := r.seen[path]
+		if ok || i >= len(values) {
+			continue
+		}
+		r.seen[path] = struct{}{}
+
+		register := extended.Register{
+			Path:  paths[i][:],
+			Value: values[i],
+		}
+		r.registers = append(r.registers, &register)
+	}
+
+	return values, nil
+}
+
+// Registers returns the registers recorded so far.
+func (r *registerRecorder) Registers() []*extended.Register {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.registers
+}
+
+// registerMeter wraps an index reader and counts the registers read through it,
+// along with the total size of their values, in the register read metrics. It
+// also keeps its own count, so that the reads of a single script execution can
+// be observed when the script has a dedicated invoker.
+type registerMeter struct {
+	archive.Reader
+
+	mu    sync.Mutex
+	reads int
+	bytes int
+}
+
+// MeterRegisters wraps the given index reader, so that the registers read
+// through it are counted in the register read metrics. It is meant to be given
+// to the script invoker, in which case only reads that miss the invoker's own
+// register cache are counted.
+func MeterRegisters(index archive.Reader) archive.Reader {
+	m := registerMeter{
+		Reader: index,
+	}
+
+	return &m
+}
+
+// Values implements the archive.Reader interface and counts the values read.
+func (m *registerMeter) Values(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+	values, err := m.Reader.Values(height, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var size int
+	for _, value := range values {
+		size += len(value)
+	}
+	registerReads.Add(float64(len(values)))
+	registerReadBytes.Add(float64(size))
+
+	m.mu.Lock()
+	m.reads += len(values)
+	m.bytes += size
+	m.mu.Unlock()
+
+	return values, nil
+}
+
+// observe records the registers read through the meter so far in the per-script
+// histograms, as those read by a single script execution.
+func (m *registerMeter) observe() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scriptRegisterReads.Observe(float64(m.reads))
+	scriptRegisterReadBytes.Observe(float64(m.bytes))
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onflow/cadence"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-archive/models/archive"
+	"github.com/onflow/flow-archive/testing/mocks"
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestMeterRegisters(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		paths := mocks.GenericLedgerPaths(2)
+		values := []ledger.Value{[]byte("one"), []byte("three")}
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(uint64, []ledger.Path) ([]ledger.Value, error) {
+			return values, nil
+		}
+		metered := MeterRegisters(index)
+
+		// The script reads its state through the metered index, like the
+		// archive invoker does.
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(height uint64, _ []byte, _ []cadence.Value) (cadence.Value, error) {
+			_, err := metered.Values(height, paths)
+			return cadence.NewInt(1), err
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		reads := testutil.ToFloat64(registerReads)
+		bytes := testutil.ToFloat64(registerReadBytes)
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, reads+2, testutil.ToFloat64(registerReads))
+		assert.Equal(t, bytes+8, testutil.ToFloat64(registerReadBytes))
+	})
+
+	t.Run("handles indexer failure on Values", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(uint64, []ledger.Path) ([]ledger.Value, error) {
+			return nil, mocks.GenericError
+		}
+		metered := MeterRegisters(index)
+
+		_, err := metered.Values(mocks.GenericHeight, mocks.GenericLedgerPaths(1))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_MeterScripts(t *testing.T) {
+	paths := mocks.GenericLedgerPaths(2)
+	values := []ledger.Value{[]byte("one"), []byte("three")}
+
+	// The script reads its state through the index of its invoker, like the
+	// archive invoker does.
+	factory := func(index archive.Reader) (Invoker, error) {
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(height uint64, _ []byte, _ []cadence.Value) (cadence.Value, error) {
+			_, err := index.Values(height, paths)
+			return cadence.NewInt(1), err
+		}
+		return invoker, nil
+	}
+
+	t.Run("records registers read by each script", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(uint64, []ledger.Path) ([]ledger.Value, error) {
+			return values, nil
+		}
+
+		shared := mocks.BaselineInvoker(t)
+		shared.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			t.Error("shared invoker should not be used")
+			return nil, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.invoker = shared
+		s.cfg.NewInvoker = factory
+		s.cfg.MeterScripts = true
+
+		reads := scriptHistogram(t, scriptRegisterReads)
+		bytes := scriptHistogram(t, scriptRegisterReadBytes)
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, reads.GetSampleCount()+1, scriptHistogram(t, scriptRegisterReads).GetSampleCount())
+		assert.Equal(t, reads.GetSampleSum()+2, scriptHistogram(t, scriptRegisterReads).GetSampleSum())
+		assert.Equal(t, bytes.GetSampleSum()+8, scriptHistogram(t, scriptRegisterReadBytes).GetSampleSum())
+	})
+}
+
+func scriptHistogram(t *testing.T, histogram prometheus.Histogram) *dto.Histogram {
+	t.Helper()
+
+	var metric dto.Metric
+	err := histogram.Write(&metric)
+	require.NoError(t, err)
+
+	return metric.GetHistogram()
+}
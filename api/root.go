@@ -0,0 +1,44 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/onflow/flow-archive/models/archive"
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// VerifyRoot returns the ID of the root block of the given index, which is the
+// block at its first indexed height. When a trusted root ID is given, it returns
+// an error if the root block of the index does not match it, so that a server is
+// never started on top of a wrong or tampered index.
+func VerifyRoot(index archive.Reader, trusted flow.Identifier) (flow.Identifier, error) {
+	first, err := index.First()
+	if err != nil {
+		return flow.ZeroID, fmt.Errorf("could not get first height: %w", err)
+	}
+	header, err := index.Header(first)
+	if err != nil {
+		return flow.ZeroID, fmt.Errorf("could not get root header at height %d: %w", first, err)
+	}
+
+	rootID := header.ID()
+	if trusted != flow.ZeroID && rootID != trusted {
+		return rootID, fmt.Errorf("root block ID %x at height %d does not match trusted root block ID %x", rootID, first, trusted)
+	}
+
+	return rootID, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-archive/testing/mocks"
+	"github.com/onflow/flow-go/model/flow"
+)
+
+func TestVerifyRoot(t *testing.T) {
+	rootID := mocks.GenericHeader.ID()
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+
+			return mocks.GenericHeader, nil
+		}
+
+		got, err := VerifyRoot(index, rootID)
+
+		require.NoError(t, err)
+		assert.Equal(t, rootID, got)
+	})
+
+	t.Run("detects root without trusted root", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := VerifyRoot(mocks.BaselineReader(t), flow.ZeroID)
+
+		require.NoError(t, err)
+		assert.Equal(t, rootID, got)
+	})
+
+	t.Run("handles mismatching root", func(t *testing.T) {
+		t.Parallel()
+
+		trusted := mocks.GenericBlockIDs(2)[1]
+
+		got, err := VerifyRoot(mocks.BaselineReader(t), trusted)
+
+		assert.Error(t, err)
+		assert.Equal(t, rootID, got)
+	})
+
+	t.Run("handles indexer failure on First", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		_, err := VerifyRoot(index, rootID)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on Header", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		_, err := VerifyRoot(index, rootID)
+
+		assert.Error(t, err)
+	})
+}
@@ -16,13 +16,27 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/onflow/flow-go/fvm/blueprints"
 
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/onflow/cadence"
 	"github.com/onflow/cadence/encoding/json"
 	"github.com/onflow/flow-archive/models/archive"
@@ -31,6 +45,21 @@ import (
 	"github.com/onflow/flow-go/model/flow"
 	"github.com/onflow/flow/protobuf/go/flow/access"
 	"github.com/onflow/flow/protobuf/go/flow/entities"
+
+	"github.com/onflow/flow-archive-access/api/extended"
+	"github.com/onflow/flow-archive-access/api/middleware"
+	"github.com/onflow/flow-archive-access/api/workerpool"
+)
+
+// sealSearchDistance is the maximum number of heights after a block within which
+// its seal is looked up.
+const sealSearchDistance = 100
+
+// minTimestamp and maxTimestampSkew bound the block timestamps that are valid
+// when timestamps are validated. Flow did not produce blocks before 2019.
+var (
+	minTimestamp     = time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	maxTimestampSkew = 24 * time.Hour
 )
 
 // Server is a simple implementation of the generated AccessAPIServer interface.
@@ -38,23 +67,63 @@ import (
 // This is generally an on-disk interface, but could be a GRPC-based index as
 // well, in which case there is a double redirection.
 type Server struct {
+	cfg     Config
 	index   archive.Reader
 	codec   archive.Codec
 	invoker Invoker
+	scripts chan struct{}
+
+	// Concurrent identical script executions and account lookups share a
+	// single call to the invoker.
+	scriptCalls  singleflight.Group
+	accountCalls singleflight.Group
+
+	// Concurrent lookups of the same block, such as a newly indexed block that
+	// every client asks for at once, share a single call to the index.
+	heightCalls singleflight.Group
+	headerCalls singleflight.Group
+
+	// The indexed range is cached for GetIndexedHeightRange: the first height
+	// never changes, while the last one is refreshed periodically.
+	rangeMu     sync.Mutex
+	first       uint64
+	firstCached bool
+	last        uint64
+	lastRefresh time.Time
+
+	shutdown chan struct{}
+	once     sync.Once
 }
 
 // NewServer creates a new server, using the provided index reader as a backend
 // for data retrieval.
-func NewServer(index archive.Reader, codec archive.Codec, invoker Invoker) *Server {
+func NewServer(index archive.Reader, codec archive.Codec, invoker Invoker, options ...Option) *Server {
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
 	s := Server{
-		index:   index,
-		codec:   codec,
-		invoker: invoker,
+		cfg:      cfg,
+		index:    index,
+		codec:    codec,
+		invoker:  invoker,
+		scripts:  make(chan struct{}, cfg.ScriptWorkers),
+		shutdown: make(chan struct{}),
 	}
 
 	return &s
 }
 
+// Shutdown ends the streams that are being served, so that the gRPC server can
+// stop gracefully without waiting for clients to close them. Scripts that are
+// already executing still have their results sent before the streams end.
+func (s *Server) Shutdown() {
+	s.once.Do(func() {
+		close(s.shutdown)
+	})
+}
+
 // Ping implements the Ping endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#ping
 func (s *Server) Ping(_ context.Context, _ *access.PingRequest) (*access.PingResponse, error) {
@@ -63,32 +132,114 @@ func (s *Server) Ping(_ context.Context, _ *access.PingRequest) (*access.PingRes
 
 // GetLatestBlockHeader implements the GetLatestBlockHeader endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getlatestblockheader
-func (s *Server) GetLatestBlockHeader(ctx context.Context, _ *access.GetLatestBlockHeaderRequest) (*access.BlockHeaderResponse, error) {
-	return nil, errors.New("GetLatestBlockHeader is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
+func (s *Server) GetLatestBlockHeader(ctx context.Context, in *access.GetLatestBlockHeaderRequest) (*access.BlockHeaderResponse, error) {
+	if s.proxies("GetLatestBlockHeader") {
+		return s.cfg.Upstream.GetLatestBlockHeader(ctx, in)
+	}
+
+	return nil, status.Error(codes.Unimplemented, "GetLatestBlockHeader is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
 }
 
 // GetBlockHeaderByID implements the GetBlockHeaderByID endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getblockheaderbyid
 func (s *Server) GetBlockHeaderByID(ctx context.Context, in *access.GetBlockHeaderByIDRequest) (*access.BlockHeaderResponse, error) {
-	return nil, errors.New("GetBlockHeaderByID is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
+	if s.proxies("GetBlockHeaderByID") {
+		return s.cfg.Upstream.GetBlockHeaderByID(ctx, in)
+	}
+
+	return nil, status.Error(codes.Unimplemented, "GetBlockHeaderByID is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
 }
 
 // GetBlockHeaderByHeight implements the GetBlockHeaderByHeight endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getblockheaderbyheight
-func (s *Server) GetBlockHeaderByHeight(_ context.Context, in *access.GetBlockHeaderByHeightRequest) (*access.BlockHeaderResponse, error) {
-	return nil, errors.New("GetBlockHeaderByHeight is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
+func (s *Server) GetBlockHeaderByHeight(ctx context.Context, in *access.GetBlockHeaderByHeightRequest) (*access.BlockHeaderResponse, error) {
+	if s.proxies("GetBlockHeaderByHeight") {
+		return s.cfg.Upstream.GetBlockHeaderByHeight(ctx, in)
+	}
+
+	return nil, status.Error(codes.Unimplemented, "GetBlockHeaderByHeight is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
+}
+
+// GetBlockHeadersByHeights returns the block headers at the given heights. The
+// headers are looked up concurrently, and a failed lookup is reported in the
+// corresponding result instead of failing the whole request.
+func (s *Server) GetBlockHeadersByHeights(ctx context.Context, in *extended.GetBlockHeadersByHeightsRequest) (*extended.BlockHeadersResponse, error) {
+	if uint(len(in.Heights)) > s.cfg.MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "too many heights (%d > %d)", len(in.Heights), s.cfg.MaxBatchSize)
+	}
+
+	index := s.reader(ctx)
+
+	first, last, err := s.heightRange(index)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := workerpool.Map(ctx, s.cfg.BatchWorkers, in.Heights, func(_ context.Context, height uint64) (*extended.BlockHeaderResult, error) {
+		result := extended.BlockHeaderResult{
+			Height: height,
+		}
+
+		if height < first || height > last {
+			result.Code = uint32(codes.OutOfRange)
+			result.Error = fmt.Sprintf("height %d is outside of served range [%d, %d]", height, first, last)
+			return &result, nil
+		}
+
+		header, err := s.header(index, height)
+		if isNotFound(err) {
+			result.Code = uint32(codes.NotFound)
+			result.Error = fmt.Sprintf("header at height %d not found", height)
+			return &result, nil
+		}
+		if err != nil {
+			result.Code = uint32(codes.Internal)
+			result.Error = fmt.Sprintf("could not retrieve header: %s", err)
+			return &result, nil
+		}
+
+		block, err := convert.BlockHeaderToMessage(header, nil)
+		if err != nil {
+			result.Code = uint32(codes.Internal)
+			result.Error = fmt.Sprintf("could not convert header: %s", err)
+			return &result, nil
+		}
+
+		result.Header = &access.BlockHeaderResponse{Block: block}
+		return &result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := extended.BlockHeadersResponse{
+		Results: results,
+	}
+
+	return &resp, nil
 }
 
 // GetLatestBlock implements the GetLatestBlock endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getlatestblock
 func (s *Server) GetLatestBlock(ctx context.Context, in *access.GetLatestBlockRequest) (*access.BlockResponse, error) {
-	height, err := s.index.Last()
+	index := s.reader(ctx)
+
+	height, err := lastHeight(index)
+	if err != nil {
+		return nil, err
+	}
+
+	stale, err := s.tooStale(ctx, index, "GetLatestBlock", height)
 	if err != nil {
-		return nil, fmt.Errorf("could not get last height: %w", err)
+		return nil, err
+	}
+	if stale {
+		return s.cfg.Upstream.GetLatestBlock(ctx, in)
 	}
 
 	req := &access.GetBlockByHeightRequest{
-		Height: height,
+		Height:            height,
+		FullBlockResponse: in.FullBlockResponse,
 	}
 
 	return s.GetBlockByHeight(ctx, req)
@@ -97,14 +248,17 @@ func (s *Server) GetLatestBlock(ctx context.Context, in *access.GetLatestBlockRe
 // GetBlockByID implements the GetBlockByID endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getblockbyid
 func (s *Server) GetBlockByID(ctx context.Context, in *access.GetBlockByIDRequest) (*access.BlockResponse, error) {
+	index := s.reader(ctx)
+
 	blockID := flow.HashToID(in.Id)
-	height, err := s.index.HeightForBlock(blockID)
+	height, err := s.heightForBlock(index, blockID)
 	if err != nil {
 		return nil, fmt.Errorf("could not get height for block %x: %w", blockID, err)
 	}
 
 	req := access.GetBlockByHeightRequest{
-		Height: height,
+		Height:            height,
+		FullBlockResponse: in.FullBlockResponse,
 	}
 
 	return s.GetBlockByHeight(ctx, &req)
@@ -112,44 +266,85 @@ func (s *Server) GetBlockByID(ctx context.Context, in *access.GetBlockByIDReques
 
 // GetBlockByHeight implements the GetBlockByHeight endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getblockbyheight
-func (s *Server) GetBlockByHeight(_ context.Context, in *access.GetBlockByHeightRequest) (*access.BlockResponse, error) {
-	header, err := s.index.Header(in.Height)
+//
+// Full block responses also include the block header and full seals. Their
+// execution receipts and results are always empty, as they are not indexed.
+// Only light responses are cached, as they are the ones most clients request.
+func (s *Server) GetBlockByHeight(ctx context.Context, in *access.GetBlockByHeightRequest) (*access.BlockResponse, error) {
+	index := s.reader(ctx)
+
+	err := s.checkWindow(index, in.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	// Statistics are sent in the response headers, so that they can be added
+	// to the responses of the Access API, including cached ones.
+	withStats, err := headerFlag(ctx, BlockStatsHeader)
+	if err != nil {
+		return nil, err
+	}
+	if withStats {
+		err = setBlockStats(ctx, index, in.Height)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !in.FullBlockResponse {
+		cached, ok := s.cachedBlock(in.Height)
+		if ok {
+			return cached, nil
+		}
+	}
+
+	header, err := s.header(index, in.Height)
 	if err != nil {
 		return nil, fmt.Errorf("could not get header for height %d: %w", in.Height, err)
 	}
 
-	sealIDs, err := s.index.SealsByHeight(in.Height)
+	sealIDs, err := index.SealsByHeight(in.Height)
 	if err != nil {
 		return nil, fmt.Errorf("could not get seals for height %d: %w", in.Height, err)
 	}
+	if len(sealIDs) == 0 && !s.cfg.AllowUnsealedBlocks {
+		return nil, status.Errorf(codes.Unavailable, "seals for height %d have not been indexed yet", in.Height)
+	}
 
 	seals := make([]*entities.BlockSeal, 0, len(sealIDs))
 	for _, sealID := range sealIDs {
-		seal, err := s.index.Seal(sealID)
+		seal, err := index.Seal(sealID)
 		if err != nil {
 			return nil, fmt.Errorf("could not get seal with ID %x: %w", sealID, err)
 		}
 
-		blockID := seal.BlockID
-		resultID := seal.ResultID
-
-		// See https://github.com/onflow/flow-go/blob/v0.17.4/engine/common/rpc/convert/convert.go#L180-L188
-		entity := entities.BlockSeal{
-			BlockId:                    blockID[:],
-			ExecutionReceiptId:         resultID[:],
-			ExecutionReceiptSignatures: [][]byte{}, // filling seals signature with zero
+		// Seals are only returned in full when configured to, as the approval
+		// signatures make up most of the size of a block response, and most
+		// clients do not verify them.
+		if s.cfg.FullSeals || in.FullBlockResponse {
+			seals = append(seals, fullSealToMessage(seal))
+			continue
 		}
-		seals = append(seals, &entity)
+		seals = append(seals, sealToMessage(seal))
 	}
 
-	collIDs, err := s.index.CollectionsByHeight(in.Height)
+	collIDs, err := index.CollectionsByHeight(in.Height)
 	if err != nil {
 		return nil, fmt.Errorf("could not get collections for height %d: %w", in.Height, err)
 	}
 
+	// Without strict block assembly, collections whose guarantee is missing
+	// from a partially indexed block are left out, and the incomplete block is
+	// not cached, so that it is assembled again once the guarantee is indexed.
+	complete := true
 	collections := make([]*entities.CollectionGuarantee, 0, len(collIDs))
 	for _, collID := range collIDs {
-		guarantee, err := s.index.Guarantee(collID)
+		guarantee, err := index.Guarantee(collID)
+		if isNotFound(err) && !s.cfg.StrictBlockAssembly {
+			s.cfg.Log.Warn().Uint64("height", in.Height).Hex("collection_id", collID[:]).Msg("skipping collection with missing guarantee")
+			complete = false
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("could not get collection with ID %x: %w", collID, err)
 		}
@@ -166,7 +361,7 @@ func (s *Server) GetBlockByHeight(_ context.Context, in *access.GetBlockByHeight
 		Id:                   blockID[:],
 		Height:               in.Height,
 		ParentId:             header.ParentID[:],
-		Timestamp:            timestamppb.New(header.Timestamp),
+		Timestamp:            s.timestamp(header),
 		CollectionGuarantees: collections,
 		BlockSeals:           seals,
 		Signatures:           [][]byte{header.ParentVoterSigData},
@@ -176,18 +371,194 @@ func (s *Server) GetBlockByHeight(_ context.Context, in *access.GetBlockByHeight
 		Block: &block,
 	}
 
+	if in.FullBlockResponse {
+		// The voters of the parent block are not known to the archive, so
+		// they are left out of the header.
+		block.BlockHeader, err = convert.BlockHeaderToMessage(header, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert header for height %d: %w", in.Height, err)
+		}
+		block.BlockHeader.Timestamp = block.Timestamp
+
+		return &resp, nil
+	}
+
+	if complete {
+		s.cacheBlock(index, in.Height, &resp)
+	}
+
+	return &resp, nil
+}
+
+// GetSealByBlockID returns the seal for the block with the given ID. Seals are
+// indexed by the height of the block whose payload includes them, which is
+// always above the height of the sealed block, so we look for the seal in the
+// blocks that follow the requested one, up to the last indexed height.
+func (s *Server) GetSealByBlockID(ctx context.Context, in *extended.GetSealByBlockIDRequest) (*extended.SealResponse, error) {
+	index := s.reader(ctx)
+
+	blockID := flow.HashToID(in.BlockId)
+	height, err := s.heightForBlock(index, blockID)
+	if isNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "block %x not found", blockID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get height for block %x: %w", blockID, err)
+	}
+
+	seal, sealHeight, err := findSeal(index, blockID, height)
+	if err != nil {
+		return nil, err
+	}
+	if seal == nil {
+		return nil, status.Errorf(codes.NotFound, "no seal found for block %x", blockID)
+	}
+
+	entity := sealToMessage(seal)
+	entity.ResultId = convert.IdentifierToMessage(seal.ResultID)
+	entity.FinalState = seal.FinalState[:]
+
+	resp := extended.SealResponse{
+		Seal:   entity,
+		Height: sealHeight,
+	}
+
+	return &resp, nil
+}
+
+// findSeal looks up the seal of the block with the given ID and height in the
+// blocks that follow it, within the seal search distance, and returns it with
+// the height of the block that includes it. It returns a nil seal if none of
+// the indexed blocks within that distance includes it.
+func findSeal(index archive.Reader, blockID flow.Identifier, height uint64) (*flow.Seal, uint64, error) {
+	last, err := index.Last()
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not get last height: %w", err)
+	}
+
+	end := height + sealSearchDistance
+	if end > last {
+		end = last
+	}
+
+	for sealHeight := height + 1; sealHeight <= end; sealHeight++ {
+		sealIDs, err := index.SealsByHeight(sealHeight)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not get seals for height %d: %w", sealHeight, err)
+		}
+
+		for _, sealID := range sealIDs {
+			seal, err := index.Seal(sealID)
+			if err != nil {
+				return nil, 0, fmt.Errorf("could not get seal with ID %x: %w", sealID, err)
+			}
+
+			if seal.BlockID == blockID {
+				return seal, sealHeight, nil
+			}
+		}
+	}
+
+	return nil, 0, nil
+}
+
+// GetStateCommitmentAtBlockHeight returns the execution state commitment for the
+// block at the given height, which register proofs can be verified against.
+func (s *Server) GetStateCommitmentAtBlockHeight(ctx context.Context, in *extended.GetStateCommitmentAtBlockHeightRequest) (*extended.StateCommitmentResponse, error) {
+	index := s.reader(ctx)
+
+	err := s.checkHeight(index, in.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := index.Commit(in.BlockHeight)
+	if err != nil {
+		return nil, fmt.Errorf("could not get state commitment for height %d: %w", in.BlockHeight, err)
+	}
+
+	resp := extended.StateCommitmentResponse{
+		BlockHeight:     in.BlockHeight,
+		StateCommitment: commit[:],
+	}
+
+	return &resp, nil
+}
+
+// GetLatestHeights returns the height of the last sealed block in the index,
+// the first height that is served and, if the server was configured with an
+// upstream access node, the height of its latest finalized block.
+func (s *Server) GetLatestHeights(ctx context.Context, _ *extended.GetLatestHeightsRequest) (*extended.LatestHeightsResponse, error) {
+	index := s.reader(ctx)
+
+	first, sealed, err := s.heightRange(index)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := extended.LatestHeightsResponse{
+		SealedHeight: sealed,
+		FirstHeight:  first,
+	}
+
+	if s.cfg.Upstream == nil {
+		return &resp, nil
+	}
+
+	header, err := s.cfg.Upstream.GetLatestBlockHeader(ctx, &access.GetLatestBlockHeaderRequest{IsSealed: false})
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "could not get latest finalized block from upstream: %s", err)
+	}
+	finalized := header.GetBlock().GetHeight()
+	resp.FinalizedHeight = &finalized
+
+	return &resp, nil
+}
+
+// GetIndexedHeightRange returns the range of heights served by the archive.
+func (s *Server) GetIndexedHeightRange(ctx context.Context, _ *extended.GetIndexedHeightRangeRequest) (*extended.IndexedHeightRangeResponse, error) {
+	index := s.reader(ctx)
+
+	first, last, err := s.indexedRange(index)
+	if err != nil {
+		return nil, err
+	}
+
+	window := s.cfg.HeightWindow
+	if window > 0 && last-first >= window {
+		first = last - window + 1
+	}
+
+	resp := extended.IndexedHeightRangeResponse{
+		First: first,
+		Last:  last,
+	}
+
 	return &resp, nil
 }
 
 // GetCollectionByID implements the GetCollectionByID endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getcollectionbyid
-func (s *Server) GetCollectionByID(_ context.Context, in *access.GetCollectionByIDRequest) (*access.CollectionResponse, error) {
+func (s *Server) GetCollectionByID(ctx context.Context, in *access.GetCollectionByIDRequest) (*access.CollectionResponse, error) {
+	index := s.reader(ctx)
+
 	collID := flow.HashToID(in.Id)
-	collection, err := s.index.Collection(collID)
+	collection, err := index.Collection(collID)
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve collection with ID %x: %w", in.Id, err)
 	}
 
+	withReference, err := headerFlag(ctx, ReferenceBlockHeader)
+	if err != nil {
+		return nil, err
+	}
+	if withReference {
+		err = setReferenceBlock(ctx, index, collID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	collEntity := entities.Collection{
 		Id: in.Id,
 	}
@@ -202,11 +573,69 @@ func (s *Server) GetCollectionByID(_ context.Context, in *access.GetCollectionBy
 	return &resp, err
 }
 
+// GetCollectionForTransaction returns the collection that includes the given
+// transaction. The index does not map transactions to their collections, so the
+// collections of the block that includes the transaction are looked up in turn.
+func (s *Server) GetCollectionForTransaction(ctx context.Context, in *extended.GetCollectionForTransactionRequest) (*access.CollectionResponse, error) {
+	index := s.reader(ctx)
+
+	txID := flow.HashToID(in.TransactionId)
+	height, err := s.transactionHeight(index, txID)
+	if isNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "transaction %x not found", txID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get height for transaction %x: %w", txID, err)
+	}
+
+	collIDs, err := index.CollectionsByHeight(height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get collections for height %d: %w", height, err)
+	}
+
+	for _, collID := range collIDs {
+		collection, err := index.Collection(collID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get collection with ID %x: %w", collID, err)
+		}
+
+		found := false
+		for _, id := range collection.Transactions {
+			if id == txID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		collEntity := entities.Collection{
+			Id: conv.IDToHash(collID),
+		}
+		for _, id := range collection.Transactions {
+			collEntity.TransactionIds = append(collEntity.TransactionIds, conv.IDToHash(id))
+		}
+
+		resp := access.CollectionResponse{
+			Collection: &collEntity,
+		}
+
+		return &resp, nil
+	}
+
+	// Only the system transaction is part of a block without being part of
+	// any of its collections.
+	return nil, status.Errorf(codes.NotFound, "transaction %x is not part of any collection", txID)
+}
+
 // GetTransaction implements the GetTransaction endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#gettransaction
-func (s *Server) GetTransaction(_ context.Context, in *access.GetTransactionRequest) (*access.TransactionResponse, error) {
+func (s *Server) GetTransaction(ctx context.Context, in *access.GetTransactionRequest) (*access.TransactionResponse, error) {
+	index := s.reader(ctx)
+
 	txID := flow.HashToID(in.Id)
-	tx, err := s.index.Transaction(txID)
+	tx, err := index.Transaction(txID)
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve transaction: %w", err)
 	}
@@ -218,22 +647,102 @@ func (s *Server) GetTransaction(_ context.Context, in *access.GetTransactionRequ
 	return &resp, nil
 }
 
+// GetTransactions returns the transactions with the given IDs, which are looked
+// up concurrently. A transaction that cannot be looked up does not fail the
+// request; its error is reported in the corresponding result instead.
+func (s *Server) GetTransactions(ctx context.Context, in *extended.GetTransactionsRequest) (*extended.GetTransactionsResponse, error) {
+	if uint(len(in.Ids)) > s.cfg.MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "too many transaction IDs (%d > %d)", len(in.Ids), s.cfg.MaxBatchSize)
+	}
+	for i, id := range in.Ids {
+		if len(id) != len(flow.ZeroID) {
+			return nil, status.Errorf(codes.InvalidArgument, "transaction ID at index %d has invalid length %d", i, len(id))
+		}
+	}
+
+	index := s.reader(ctx)
+
+	results, err := workerpool.Map(ctx, s.cfg.BatchWorkers, in.Ids, func(_ context.Context, id []byte) (*extended.TransactionResult, error) {
+		result := extended.TransactionResult{
+			Id: id,
+		}
+
+		txID := flow.HashToID(id)
+		tx, err := index.Transaction(txID)
+		if isNotFound(err) {
+			result.Code = uint32(codes.NotFound)
+			result.Error = fmt.Sprintf("transaction %x not found", txID)
+			return &result, nil
+		}
+		if err != nil {
+			result.Code = uint32(codes.Internal)
+			result.Error = fmt.Sprintf("could not retrieve transaction: %s", err)
+			return &result, nil
+		}
+
+		result.Transaction = convert.TransactionToMessage(*tx)
+		return &result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := extended.GetTransactionsResponse{
+		Results: results,
+	}
+
+	return &resp, nil
+}
+
 // GetTransactionResult implements the GetTransactionResult endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#gettransactionresult
-func (s *Server) GetTransactionResult(_ context.Context, in *access.GetTransactionRequest) (*access.TransactionResultResponse, error) {
+func (s *Server) GetTransactionResult(ctx context.Context, in *access.GetTransactionRequest) (*access.TransactionResultResponse, error) {
+	index := s.reader(ctx)
+
+	omitEvents, err := headerFlag(ctx, OmitEventsHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	withCode, err := headerFlag(ctx, ErrorCodeHeader)
+	if err != nil {
+		return nil, err
+	}
+
 	txID := flow.HashToID(in.Id)
-	result, err := s.index.Result(txID)
+	result, err := index.Result(txID)
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve transaction result: %w", err)
 	}
 
-	// We also need the height of the transaction we're looking at.
-	height, err := s.index.HeightForTransaction(txID)
+	height, err := s.transactionHeight(index, txID)
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve block height: %w", err)
 	}
 
-	block, err := s.index.Header(height)
+	resp, err := s.transactionResult(index, txID, height, result, !omitEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	// The error code is sent in the response headers, since the version of the
+	// Access API that is implemented has no field for it.
+	if withCode {
+		code := transactionErrorCode(result.ErrorMessage)
+		err = grpc.SetHeader(ctx, metadata.Pairs(TransactionErrorCodeHeader, strconv.FormatUint(uint64(code), 10)))
+		if err != nil {
+			return nil, fmt.Errorf("could not set error code header: %w", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// transactionResult builds the response for the given transaction result, as
+// included in the block at the given height. The events are only read and
+// included if `withEvents` is set.
+func (s *Server) transactionResult(index archive.Reader, txID flow.Identifier, height uint64, result *flow.TransactionResult, withEvents bool) (*access.TransactionResultResponse, error) {
+	block, err := s.header(index, height)
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve block header: %w", err)
 	}
@@ -245,7 +754,7 @@ func (s *Server) GetTransactionResult(_ context.Context, in *access.GetTransacti
 	}
 
 	status := entities.TransactionStatus_SEALED
-	sealedHeight, err := s.index.Last()
+	sealedHeight, err := index.Last()
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve last height: %w", err)
 	}
@@ -254,21 +763,25 @@ func (s *Server) GetTransactionResult(_ context.Context, in *access.GetTransacti
 		status = entities.TransactionStatus_EXECUTED
 	}
 
-	events, err := s.index.Events(height)
-	if err != nil {
-		return nil, fmt.Errorf("could not retrieve events: %w", err)
-	}
-
 	resp := access.TransactionResultResponse{
 		Status:        status,
 		StatusCode:    statusCode,
 		ErrorMessage:  result.ErrorMessage,
-		Events:        convert.EventsToMessages(events),
 		BlockId:       blockID[:],
 		TransactionId: convert.IdentifierToMessage(txID),
 		BlockHeight:   height,
 	}
 
+	if !withEvents {
+		return &resp, nil
+	}
+
+	events, err := blockEvents(index, height)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve events: %w", err)
+	}
+	resp.Events = convert.EventsToMessages(events)
+
 	return &resp, nil
 }
 
@@ -279,6 +792,13 @@ func (s *Server) GetTransactionResultByIndex(ctx context.Context, in *access.Get
 	}
 
 	resp, err := s.GetTransactionResultsByBlockID(ctx, &req)
+	if isNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "could not get transaction results for block %x: %s", in.BlockId, err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not get transaction results for block %x: %s", in.BlockId, err)
+	}
+
 	for _, result := range resp.TransactionResults {
 		for _, event := range result.Events {
 			if event.TransactionIndex == in.Index {
@@ -286,33 +806,70 @@ func (s *Server) GetTransactionResultByIndex(ctx context.Context, in *access.Get
 			}
 		}
 	}
-	return nil, fmt.Errorf("could not get transactions for index %x: %w", in.Index, err)
+
+	return nil, status.Errorf(codes.NotFound, "no transaction result with index %d in block %x", in.Index, in.BlockId)
 }
 
 // GetTransactionResultsByBlockID implements the GetTransactionResultsByBlockID endpoint from the Flow Access API.
 func (s *Server) GetTransactionResultsByBlockID(ctx context.Context, in *access.GetTransactionsByBlockIDRequest) (*access.TransactionResultsResponse, error) {
-	blockId := flow.HashToID(in.BlockId)
-	height, err := s.index.HeightForBlock(blockId)
+	return s.transactionResults(ctx, in.BlockId, false)
+}
+
+// GetFilteredTransactionResultsByBlockID returns the transaction results for the
+// block with the given ID, like GetTransactionResultsByBlockID, but can be asked
+// to only return the results of failed transactions.
+func (s *Server) GetFilteredTransactionResultsByBlockID(ctx context.Context, in *extended.GetFilteredTransactionResultsByBlockIDRequest) (*access.TransactionResultsResponse, error) {
+	return s.transactionResults(ctx, in.BlockId, in.FailedOnly)
+}
+
+// transactionResults returns the transaction results for the block with the
+// given ID. If `failedOnly` is set, successful transactions are skipped before
+// their results are built.
+func (s *Server) transactionResults(ctx context.Context, id []byte, failedOnly bool) (*access.TransactionResultsResponse, error) {
+	index := s.reader(ctx)
+
+	blockId := flow.HashToID(id)
+	height, err := s.heightForBlock(index, blockId)
 	if err != nil {
 		return nil, fmt.Errorf("could not get height for block %x: %w", blockId, err)
 	}
 
-	transactions, err := s.index.TransactionsByHeight(height)
+	transactions, err := index.TransactionsByHeight(height)
 	if err != nil {
 		return nil, fmt.Errorf("could not get transactions for height %x: %w", height, err)
 	}
 
+	// The collections of the block are resolved once for all of its
+	// transactions, rather than once per transaction result.
+	collections, err := transactionCollections(index, height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get collections for height %d: %w", height, err)
+	}
+
 	var transactionResults []*access.TransactionResultResponse
 	for _, transaction := range transactions {
-		req := access.GetTransactionRequest{
-			Id: convert.IdentifierToMessage(transaction),
+		result, err := index.Result(transaction)
+		if err != nil {
+			return nil, fmt.Errorf("could not get transaction for id %x: %w", transaction, err)
+		}
+
+		if failedOnly && result.ErrorMessage == "" {
+			continue
 		}
-		response, err := s.GetTransactionResult(ctx, &req)
+
+		response, err := s.transactionResult(index, transaction, height, result, true)
 		if err != nil {
 			return nil, fmt.Errorf("could not get transaction for id %x: %w", transaction, err)
 		}
-		transactionResults = append(transactionResults, response)
-	}
+
+		// The system transaction is not part of any collection.
+		collID, ok := collections[transaction]
+		if ok {
+			response.CollectionId = collID[:]
+		}
+
+		transactionResults = append(transactionResults, response)
+	}
 
 	resp := access.TransactionResultsResponse{
 		TransactionResults: transactionResults,
@@ -320,20 +877,40 @@ func (s *Server) GetTransactionResultsByBlockID(ctx context.Context, in *access.
 	return &resp, nil
 }
 
+// transactionCollections maps the IDs of the transactions at the given height
+// to the IDs of the collections that contain them.
+func transactionCollections(index archive.Reader, height uint64) (map[flow.Identifier]flow.Identifier, error) {
+	collIDs, err := index.CollectionsByHeight(height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get collections: %w", err)
+	}
+
+	collections := make(map[flow.Identifier]flow.Identifier)
+	for _, collID := range collIDs {
+		collection, err := index.Collection(collID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get collection with ID %x: %w", collID, err)
+		}
+
+		for _, txID := range collection.Transactions {
+			collections[txID] = collID
+		}
+	}
+
+	return collections, nil
+}
+
 // GetTransactionsByBlockID implements the GetTransactionsByBlockID endpoint from the Flow Access API.
 func (s *Server) GetTransactionsByBlockID(ctx context.Context, in *access.GetTransactionsByBlockIDRequest) (*access.TransactionsResponse, error) {
+	index := s.reader(ctx)
+
 	blockId := flow.HashToID(in.BlockId)
-	height, err := s.index.HeightForBlock(blockId)
+	height, err := s.heightForBlock(index, blockId)
 	if err != nil {
 		return nil, fmt.Errorf("could not get height for block %x: %w", blockId, err)
 	}
 
-	header, err := s.index.Header(height)
-	if err != nil {
-		return nil, fmt.Errorf("could not retrieve block header at height %d: %w", height, err)
-	}
-
-	transactions, err := s.index.TransactionsByHeight(height)
+	transactions, err := index.TransactionsByHeight(height)
 	if err != nil {
 		return nil, fmt.Errorf("could not get transactions for height %x: %w", height, err)
 	}
@@ -351,12 +928,30 @@ func (s *Server) GetTransactionsByBlockID(ctx context.Context, in *access.GetTra
 		transactionsEntity = append(transactionsEntity, resp.Transaction)
 	}
 
-	chain := header.ChainID.Chain()
-	systemTx, err := blueprints.SystemChunkTransaction(chain)
-	if err != nil {
-		return nil, fmt.Errorf("could not get system transaction for height %x: %w", height, err)
+	// The system transaction depends on the chain, which is derived from the
+	// block header unless it is overridden. Custom networks can have chain IDs
+	// that do not map to a known chain, in which case it is left out.
+	if s.cfg.IncludeSystemTx {
+		chainID := s.cfg.SystemTxChain
+		if chainID == "" {
+			header, err := s.header(index, height)
+			if err != nil {
+				return nil, fmt.Errorf("could not retrieve block header at height %d: %w", height, err)
+			}
+			chainID = header.ChainID
+		}
+
+		chain, err := ParseChain(chainID)
+		if err != nil {
+			s.cfg.Log.Warn().Err(err).Uint64("height", height).Msg("skipping system transaction")
+		} else {
+			systemTx, err := blueprints.SystemChunkTransaction(chain)
+			if err != nil {
+				return nil, fmt.Errorf("could not get system transaction for height %x: %w", height, err)
+			}
+			transactionsEntity = append(transactionsEntity, convert.TransactionToMessage(*systemTx))
+		}
 	}
-	transactionsEntity = append(transactionsEntity, convert.TransactionToMessage(*systemTx))
 
 	resp := access.TransactionsResponse{
 		Transactions: transactionsEntity,
@@ -364,6 +959,106 @@ func (s *Server) GetTransactionsByBlockID(ctx context.Context, in *access.GetTra
 	return &resp, nil
 }
 
+// GetBlockTransactions returns the transactions of the block with the given ID.
+// Depending on the request, each transaction is annotated with its result, and
+// with the collection that includes it and its index within the block.
+func (s *Server) GetBlockTransactions(ctx context.Context, in *extended.GetBlockTransactionsRequest) (*extended.BlockTransactionsResponse, error) {
+	index := s.reader(ctx)
+
+	blockID := flow.HashToID(in.BlockId)
+	height, err := s.heightForBlock(index, blockID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get height for block %x: %w", blockID, err)
+	}
+
+	transactions, err := s.blockTransactions(index, height, in.IncludeResults, in.IncludeOrdering)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := extended.BlockTransactionsResponse{
+		Transactions: transactions,
+	}
+
+	return &resp, nil
+}
+
+// blockTransactions returns the transactions of the block at the given height,
+// with their results if `withResults` is set, and with their collection and
+// index within the block if `withOrdering` is set.
+func (s *Server) blockTransactions(index archive.Reader, height uint64, withResults bool, withOrdering bool) ([]*extended.BlockTransaction, error) {
+	txIDs, err := index.TransactionsByHeight(height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transactions for height %d: %w", height, err)
+	}
+
+	type position struct {
+		collID flow.Identifier
+		index  uint32
+	}
+	positions := make(map[flow.Identifier]position)
+	var txIndex uint32
+	if withOrdering {
+		collIDs, err := index.CollectionsByHeight(height)
+		if err != nil {
+			return nil, fmt.Errorf("could not get collections for height %d: %w", height, err)
+		}
+
+		for _, collID := range collIDs {
+			collection, err := index.Collection(collID)
+			if err != nil {
+				return nil, fmt.Errorf("could not get collection with ID %x: %w", collID, err)
+			}
+
+			for _, txID := range collection.Transactions {
+				positions[txID] = position{collID: collID, index: txIndex}
+				txIndex++
+			}
+		}
+	}
+
+	transactions := make([]*extended.BlockTransaction, 0, len(txIDs))
+	for _, txID := range txIDs {
+		tx, err := index.Transaction(txID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get transaction with ID %x: %w", txID, err)
+		}
+
+		transaction := extended.BlockTransaction{
+			Transaction: convert.TransactionToMessage(*tx),
+		}
+
+		if withResults {
+			result, err := index.Result(txID)
+			if err != nil {
+				return nil, fmt.Errorf("could not get result for transaction %x: %w", txID, err)
+			}
+
+			transaction.Result, err = s.transactionResult(index, txID, height, result, true)
+			if err != nil {
+				return nil, fmt.Errorf("could not get result for transaction %x: %w", txID, err)
+			}
+		}
+
+		if withOrdering {
+			// The system transaction is not part of any collection, and comes
+			// after the transactions of the block's collections.
+			pos, ok := positions[txID]
+			if ok {
+				transaction.CollectionId = pos.collID[:]
+				transaction.Index = pos.index
+			} else {
+				transaction.Index = txIndex
+				txIndex++
+			}
+		}
+
+		transactions = append(transactions, &transaction)
+	}
+
+	return transactions, nil
+}
+
 // GetAccount implements the GetAccount endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getaccount
 func (s *Server) GetAccount(ctx context.Context, in *access.GetAccountRequest) (*access.GetAccountResponse, error) {
@@ -386,9 +1081,31 @@ func (s *Server) GetAccount(ctx context.Context, in *access.GetAccountRequest) (
 // GetAccountAtLatestBlock implements the GetAccountAtLatestBlock endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getaccountatlatestblock
 func (s *Server) GetAccountAtLatestBlock(ctx context.Context, in *access.GetAccountAtLatestBlockRequest) (*access.AccountResponse, error) {
-	height, err := s.index.Last()
+	index := s.reader(ctx)
+
+	height, err := lastHeight(index)
+	if err != nil {
+		return nil, err
+	}
+
+	stale, err := s.tooStale(ctx, index, "GetAccountAtLatestBlock", height)
 	if err != nil {
-		return nil, fmt.Errorf("could not get height: %w", err)
+		return nil, err
+	}
+	if stale {
+		resp, err := s.cfg.Upstream.GetAccountAtLatestBlock(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+
+		// The header is not forwarded to the upstream, so its contracts are
+		// left out here instead.
+		err = omitContracts(ctx, resp.Account)
+		if err != nil {
+			return nil, err
+		}
+
+		return resp, nil
 	}
 
 	// Simply call the height-specific endpoint with the latest height.
@@ -402,17 +1119,37 @@ func (s *Server) GetAccountAtLatestBlock(ctx context.Context, in *access.GetAcco
 
 // GetAccountAtBlockHeight implements the GetAccountAtBlockHeight endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#getaccountatblockheight
-func (s *Server) GetAccountAtBlockHeight(_ context.Context, in *access.GetAccountAtBlockHeightRequest) (*access.AccountResponse, error) {
-	account, err := s.invoker.Account(in.BlockHeight, flow.BytesToAddress(in.Address))
+func (s *Server) GetAccountAtBlockHeight(ctx context.Context, in *access.GetAccountAtBlockHeightRequest) (*access.AccountResponse, error) {
+	err := s.checkWindow(s.reader(ctx), in.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	address := flow.BytesToAddress(in.Address)
+	s.countAddress(address)
+
+	key := fmt.Sprintf("%d/%s", in.BlockHeight, address)
+	shared, err, _ := s.accountCalls.Do(key, func() (interface{}, error) {
+		return s.invoker.Account(in.BlockHeight, address)
+	})
+	if isAccountNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "account %s not found at height %d", address, in.BlockHeight)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("could not get account: %w", err)
 	}
 
+	account := shared.(*flow.Account)
 	accountMsg, err := convert.AccountToMessage(account)
 	if err != nil {
 		return nil, fmt.Errorf("could not convert account to RPC message: %w", err)
 	}
 
+	err = omitContracts(ctx, accountMsg)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := access.AccountResponse{
 		Account: accountMsg,
 	}
@@ -420,28 +1157,98 @@ func (s *Server) GetAccountAtBlockHeight(_ context.Context, in *access.GetAccoun
 	return &resp, nil
 }
 
+// GetAccountRegistersAtBlockHeight returns the raw registers that were read from
+// the index in order to build the account with the given address at the given
+// height. It uses a dedicated invoker for each request, so that no register is
+// served from the shared register cache without being recorded.
+func (s *Server) GetAccountRegistersAtBlockHeight(ctx context.Context, in *access.GetAccountAtBlockHeightRequest) (*extended.AccountRegistersResponse, error) {
+	if s.cfg.NewInvoker == nil {
+		return nil, status.Error(codes.Unimplemented, "raw register lookups are not enabled on this server")
+	}
+
+	index := s.reader(ctx)
+
+	err := s.checkWindow(index, in.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := newRegisterRecorder(index)
+	invoker, err := s.cfg.NewInvoker(recorder)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize invoker: %w", err)
+	}
+
+	address := flow.BytesToAddress(in.Address)
+	_, err = invoker.Account(in.BlockHeight, address)
+	if isAccountNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "account %s not found at height %d", address, in.BlockHeight)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get account: %w", err)
+	}
+
+	registers := recorder.Registers()
+	truncated := uint(len(registers)) > s.cfg.MaxRegisters
+	if truncated {
+		registers = registers[:s.cfg.MaxRegisters]
+	}
+
+	resp := extended.AccountRegistersResponse{
+		Registers: registers,
+		Truncated: truncated,
+	}
+
+	return &resp, nil
+}
+
 // ExecuteScriptAtLatestBlock implements the ExecuteScriptAtLatestBlock endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#executescriptatlatestblock
 func (s *Server) ExecuteScriptAtLatestBlock(ctx context.Context, in *access.ExecuteScriptAtLatestBlockRequest) (*access.ExecuteScriptResponse, error) {
-	height, err := s.index.Last()
+	index := s.reader(ctx)
+
+	height, err := lastHeight(index)
 	if err != nil {
-		return nil, fmt.Errorf("could not get last height: %w", err)
+		return nil, err
+	}
+
+	stale, err := s.tooStale(ctx, index, "ExecuteScriptAtLatestBlock", height)
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		return s.cfg.Upstream.ExecuteScriptAtLatestBlock(ctx, in)
 	}
 
+	// The script is executed at the height resolved above, even if the index
+	// advances in the meantime, and that height is sent back to the client,
+	// since the response does not have a field for it.
 	req := &access.ExecuteScriptAtBlockHeightRequest{
 		BlockHeight: height,
 		Script:      in.Script,
 		Arguments:   in.Arguments,
 	}
 
-	return s.ExecuteScriptAtBlockHeight(ctx, req)
+	resp, err := s.ExecuteScriptAtBlockHeight(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = grpc.SetHeader(ctx, metadata.Pairs(ScriptHeightHeader, strconv.FormatUint(height, 10)))
+	if err != nil {
+		return nil, fmt.Errorf("could not set script height header: %w", err)
+	}
+
+	return resp, nil
 }
 
 // ExecuteScriptAtBlockID implements the ExecuteScriptAtBlockID endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#executescriptatblockid
 func (s *Server) ExecuteScriptAtBlockID(ctx context.Context, in *access.ExecuteScriptAtBlockIDRequest) (*access.ExecuteScriptResponse, error) {
+	index := s.reader(ctx)
+
 	blockID := flow.HashToID(in.BlockId)
-	height, err := s.index.HeightForBlock(blockID)
+	height, err := s.heightForBlock(index, blockID)
 	if err != nil {
 		return nil, fmt.Errorf("could not get height for block ID %x: %w", blockID, err)
 	}
@@ -457,21 +1264,75 @@ func (s *Server) ExecuteScriptAtBlockID(ctx context.Context, in *access.ExecuteS
 
 // ExecuteScriptAtBlockHeight implements the ExecuteScriptAtBlockHeight endpoint from the Flow Access API.
 // See https://docs.onflow.org/access-api/#executescriptatblockheight
-func (s *Server) ExecuteScriptAtBlockHeight(_ context.Context, in *access.ExecuteScriptAtBlockHeightRequest) (*access.ExecuteScriptResponse, error) {
-	var args []cadence.Value
-	for _, arg := range in.Arguments {
-		val, err := json.Decode(nil, arg)
-		if err != nil {
-			return nil, fmt.Errorf("could not decode script argument: %w", err)
+func (s *Server) ExecuteScriptAtBlockHeight(ctx context.Context, in *access.ExecuteScriptAtBlockHeightRequest) (*access.ExecuteScriptResponse, error) {
+	// Unlike other lookups, scripts are not left for the index to reject, since
+	// the invoker reads registers at the requested height, which would quietly
+	// return empty values rather than failing for heights that are not indexed.
+	index := s.reader(ctx)
+
+	err := s.checkHeight(index, in.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := decodeArguments(in.Arguments, s.cfg.StrictArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first address argument is counted as the account that the script
+	// is about, since it usually is the account whose state is read.
+	for _, arg := range args {
+		address, ok := arg.(cadence.Address)
+		if ok {
+			s.countAddress(flow.Address(address))
+			break
 		}
+	}
 
-		args = append(args, val)
+	// Scripts can only be charged for, or metered on, the registers they read
+	// with a dedicated invoker, since the shared one serves most reads from its
+	// cache. Rather than running them free of charge, they are rejected when a
+	// budget is set without a way to create one.
+	invoker := s.invoker
+	budget := s.budget()
+	if budget != nil && s.cfg.NewInvoker == nil {
+		return nil, errBudgetUnenforceable
+	}
+	var meter *registerMeter
+	if (budget != nil || s.cfg.MeterScripts) && s.cfg.NewInvoker != nil {
+		reader := index
+		if budget != nil {
+			reader = &budgetReader{Reader: index, budget: budget}
+		}
+		meter = &registerMeter{Reader: reader}
+		invoker, err = s.cfg.NewInvoker(meter)
+		if err != nil {
+			return nil, fmt.Errorf("could not create invoker: %w", err)
+		}
 	}
 
-	value, err := s.invoker.Script(in.BlockHeight, in.Script, args)
+	// Executions with a dedicated invoker are not shared with concurrent
+	// identical requests, since each request has to be charged for, and
+	// metered on, its own reads.
+	var shared interface{}
+	if meter != nil {
+		shared, err = invoker.Script(in.BlockHeight, in.Script, args)
+		meter.observe()
+	} else {
+		shared, err, _ = s.scriptCalls.Do(scriptKey(in.BlockHeight, in.Script, in.Arguments), func() (interface{}, error) {
+			return invoker.Script(in.BlockHeight, in.Script, args)
+		})
+	}
+	// The invoker wraps the errors of the registers it reads, so the budget
+	// is checked directly.
+	if budget.exceeded() || errors.Is(err, errBudgetExceeded) {
+		return nil, errBudgetExceeded
+	}
 	if err != nil {
 		return nil, fmt.Errorf("could not execute script: %w", err)
 	}
+	value, _ := shared.(cadence.Value)
 
 	result, err := json.Encode(value)
 	if err != nil {
@@ -485,131 +1346,1277 @@ func (s *Server) ExecuteScriptAtBlockHeight(_ context.Context, in *access.Execut
 	return &resp, nil
 }
 
-// GetEventsForHeightRange implements the GetEventsForHeightRange endpoint from the Flow Access API.
-// See https://docs.onflow.org/access-api/#geteventsforheightrange
-func (s *Server) GetEventsForHeightRange(_ context.Context, in *access.GetEventsForHeightRangeRequest) (*access.EventsResponse, error) {
-	var types []flow.EventType
-	if in.Type != "" {
-		types = append(types, flow.EventType(in.Type))
-	}
+// ExecuteScripts executes each script received on the stream at its requested
+// height, using the server's bounded pool of script workers, and streams back
+// the results as they complete. A failing script does not end the stream; its
+// error is reported in the corresponding response instead.
+func (s *Server) ExecuteScripts(stream extended.ExtendedAPI_ExecuteScriptsServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// Panics in the goroutines below are not recovered by the interceptors, so
+	// they are recovered here and reported as internal errors.
+	method, _ := grpc.MethodFromServerStream(stream)
+
+	// The stream can only be written to from one goroutine at a time, so all
+	// results go through a single sender.
+	results := make(chan *extended.ExecuteScriptsResponse)
+	sent := make(chan error, 1)
+	go func() {
+		var err error
+		defer func() {
+			r := recover()
+			if r != nil {
+				err = middleware.Recovered(ctx, s.cfg.Log, method, r)
+				cancel()
+				// The scripts still in flight send their results regardless.
+				for range results {
+				}
+			}
+			sent <- err
+		}()
 
-	var events []*access.EventsResponse_Result
-	for height := in.StartHeight; height <= in.EndHeight; height++ {
-		ee, err := s.index.Events(height, types...)
-		if err != nil {
-			return nil, fmt.Errorf("could not get events at height %d: %w", height, err)
+		for result := range results {
+			if err != nil {
+				continue
+			}
+			err = stream.Send(result)
+			if err != nil {
+				err = fmt.Errorf("could not send script result: %w", err)
+				cancel()
+			}
 		}
+	}()
+
+	// Requests are received in a separate goroutine, so that the stream can be
+	// ended on shutdown even while waiting for the client's next request.
+	requests := make(chan *extended.ExecuteScriptsRequest)
+	received := make(chan error, 1)
+	go func() {
+		defer func() {
+			r := recover()
+			if r != nil {
+				received <- middleware.Recovered(ctx, s.cfg.Log, method, r)
+			}
+		}()
 
-		header, err := s.index.Header(height)
-		if err != nil {
-			return nil, fmt.Errorf("could not get header at height %d: %w", height, err)
+		for {
+			in, err := stream.Recv()
+			if err != nil {
+				received <- fmt.Errorf("could not receive script: %w", err)
+				return
+			}
+			select {
+			case requests <- in:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+
+	// The next request is nil when the stream ends, whether because the client
+	// closed it, or because of an error.
+	next := func() (*extended.ExecuteScriptsRequest, error) {
+		select {
+		case in := <-requests:
+			return in, nil
+		case err := <-received:
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, err
+		case <-s.shutdown:
+			return nil, errShuttingDown
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
 
-		timestamp := timestamppb.New(header.Timestamp)
-
-		messages := make([]*entities.Event, 0, len(ee))
-		for _, event := range ee {
-			messages = append(messages, convert.EventToMessage(event))
+	var wg sync.WaitGroup
+	var err error
+	for index := uint64(0); ; index++ {
+		var in *extended.ExecuteScriptsRequest
+		in, err = next()
+		if in == nil {
+			break
 		}
 
-		blockID := header.ID()
-		result := access.EventsResponse_Result{
-			BlockId:        blockID[:],
-			BlockHeight:    height,
-			BlockTimestamp: timestamp,
-			Events:         messages,
+		select {
+		case s.scripts <- struct{}{}:
+		case <-s.shutdown:
+			err = errShuttingDown
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			break
 		}
 
-		events = append(events, &result)
+		wg.Add(1)
+		go func(index uint64, in *extended.ExecuteScriptsRequest) {
+			defer wg.Done()
+			defer func() { <-s.scripts }()
+
+			result := extended.ExecuteScriptsResponse{
+				Index: index,
+			}
+			defer func() {
+				r := recover()
+				if r != nil {
+					err := middleware.Recovered(ctx, s.cfg.Log, method, r)
+					result.Code = uint32(status.Code(err))
+					result.Error = err.Error()
+				}
+				results <- &result
+			}()
+
+			req := access.ExecuteScriptAtBlockHeightRequest{
+				BlockHeight: in.BlockHeight,
+				Script:      in.Script,
+				Arguments:   in.Arguments,
+			}
+			resp, err := s.ExecuteScriptAtBlockHeight(ctx, &req)
+			if err != nil {
+				result.Code = uint32(status.Code(err))
+				result.Error = err.Error()
+			} else {
+				result.Value = resp.Value
+			}
+		}(index, in)
 	}
 
-	resp := access.EventsResponse{
-		Results: events,
+	wg.Wait()
+	close(results)
+
+	sendErr := <-sent
+	if sendErr != nil {
+		return sendErr
 	}
 
-	return &resp, nil
+	return err
 }
 
-// GetEventsForBlockIDs implements the GetEventsForBlockIDs endpoint from the Flow Access API.
-// See https://docs.onflow.org/access-api/#geteventsforblockids
-func (s *Server) GetEventsForBlockIDs(_ context.Context, in *access.GetEventsForBlockIDsRequest) (*access.EventsResponse, error) {
-	var types []flow.EventType
-	if in.Type != "" {
-		types = append(types, flow.EventType(in.Type))
+// SubscribeExecutionData streams the execution data of each block from the
+// requested start height onwards. Once it has caught up with the index, it
+// polls the index for new heights until the client ends the stream or the
+// server shuts down. Blocks are sent one at a time, so that a slow client holds
+// up its own stream rather than having its responses buffered on the server.
+func (s *Server) SubscribeExecutionData(in *extended.SubscribeExecutionDataRequest, stream extended.ExtendedAPI_SubscribeExecutionDataServer) error {
+	ctx := stream.Context()
+	index := s.reader(ctx)
+
+	first, _, err := s.heightRange(index)
+	if err != nil {
+		return err
+	}
+	if in.StartHeight < first {
+		return status.Errorf(codes.OutOfRange, "start height %d is below first served height %d", in.StartHeight, first)
 	}
 
-	var events []*access.EventsResponse_Result
-	for _, id := range in.BlockIds {
-		blockID := flow.HashToID(id)
-		height, err := s.index.HeightForBlock(blockID)
-		if err != nil {
-			return nil, fmt.Errorf("could not get height of block with ID %x: %w", id, err)
-		}
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
 
-		ee, err := s.index.Events(height, types...)
-		if err != nil {
-			return nil, fmt.Errorf("could not get events at height %d: %w", height, err)
-		}
+	for height := in.StartHeight; ; height++ {
+		for {
+			last, err := lastHeight(index)
+			if err != nil {
+				return err
+			}
+			if height <= last {
+				break
+			}
 
-		header, err := s.index.Header(height)
-		if err != nil {
-			return nil, fmt.Errorf("could not get header at height %d: %w", height, err)
+			select {
+			case <-ticker.C:
+			case <-s.shutdown:
+				return errShuttingDown
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 
-		timestamp := timestamppb.New(header.Timestamp)
-
-		messages := make([]*entities.Event, 0, len(ee))
-		for _, event := range ee {
-			messages = append(messages, convert.EventToMessage(event))
+		// While catching up, the stream does not wait on the ticker, so it
+		// checks whether it should end before reading each block.
+		select {
+		case <-s.shutdown:
+			return errShuttingDown
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
 		}
 
-		result := access.EventsResponse_Result{
-			BlockId:        blockID[:],
-			BlockHeight:    height,
-			BlockTimestamp: timestamp,
-			Events:         messages,
+		resp, err := s.executionData(index, height)
+		if err != nil {
+			return fmt.Errorf("could not get execution data for height %d: %w", height, err)
 		}
 
-		events = append(events, &result)
+		err = stream.Send(resp)
+		if err != nil {
+			return fmt.Errorf("could not send execution data for height %d: %w", height, err)
+		}
 	}
+}
 
-	resp := access.EventsResponse{
-		Results: events,
+// executionData returns the execution data of the block at the given height,
+// as far as it is held by the index. The register updates of the block are not
+// included, since the index does not record which registers each block wrote.
+func (s *Server) executionData(index archive.Reader, height uint64) (*extended.ExecutionDataResponse, error) {
+	header, err := s.header(index, height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get header: %w", err)
 	}
+	blockID := header.ID()
 
-	return &resp, nil
-}
-
-// GetNetworkParameters implements the GetNetworkParameters endpoint from the Flow Access API.
-// See https://docs.onflow.org/access-api/#getnetworkparameters
-func (s *Server) GetNetworkParameters(_ context.Context, _ *access.GetNetworkParametersRequest) (*access.GetNetworkParametersResponse, error) {
-	root, err := s.index.First()
+	transactions, err := s.blockTransactions(index, height, true, true)
 	if err != nil {
-		return nil, fmt.Errorf("could not get first indexed height: %w", err)
+		return nil, err
 	}
 
-	header, err := s.index.Header(root)
+	events, err := blockEvents(index, height)
 	if err != nil {
-		return nil, fmt.Errorf("could not get header: %w", err)
+		return nil, fmt.Errorf("could not get events: %w", err)
 	}
 
-	return &access.GetNetworkParametersResponse{ChainId: header.ChainID.String()}, nil
-}
+	resp := extended.ExecutionDataResponse{
+		BlockId:      blockID[:],
+		BlockHeight:  height,
+		Transactions: transactions,
+		Events:       convert.EventsToMessages(events),
+	}
 
-// GetExecutionResultForBlockID is not implemented.
-// See https://docs.onflow.org/access-api/#getexecutionresultforblockid
-func (s *Server) GetExecutionResultForBlockID(_ context.Context, req *access.GetExecutionResultForBlockIDRequest) (*access.ExecutionResultForBlockIDResponse, error) {
-	return nil, errors.New("GetExecutionResultForBlockID is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
+	return &resp, nil
 }
 
-// SendTransaction is not implemented.
-// See https://docs.onflow.org/access-api/#sendtransaction
-func (s *Server) SendTransaction(ctx context.Context, in *access.SendTransactionRequest) (*access.SendTransactionResponse, error) {
-	return nil, errors.New("SendTransaction is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
-}
+// GetEventsForHeightRange implements the GetEventsForHeightRange endpoint from the Flow Access API.
+// See https://docs.onflow.org/access-api/#geteventsforheightrange
+func (s *Server) GetEventsForHeightRange(ctx context.Context, in *access.GetEventsForHeightRangeRequest) (*access.EventsResponse, error) {
+	index := s.reader(ctx)
 
-// GetLatestProtocolStateSnapshot is not implemented.
-// See https://docs.onflow.org/access-api/#getlatestprotocolstatesnapshotrequest
-func (s *Server) GetLatestProtocolStateSnapshot(ctx context.Context, in *access.GetLatestProtocolStateSnapshotRequest) (*access.ProtocolStateSnapshotResponse, error) {
-	return nil, errors.New("GetLatestProtocolSnapshot is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
+	var types []flow.EventType
+	if in.Type != "" {
+		types = append(types, flow.EventType(in.Type))
+	}
+	err := s.checkEventType(in.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.StartHeight > in.EndHeight {
+		return nil, status.Errorf(codes.InvalidArgument, "start height %d is above end height %d", in.StartHeight, in.EndHeight)
+	}
+
+	err = s.checkWindow(index, in.StartHeight)
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkWindow(index, in.EndHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := s.budget()
+	if budget != nil {
+		index = &budgetReader{Reader: index, budget: budget}
+	}
+
+	// The reads for each height are retried on their own when the archive is
+	// transiently unavailable, so that a blip does not discard the heights that
+	// were already read.
+	var events []*access.EventsResponse_Result
+	for height := in.StartHeight; height <= in.EndHeight; height++ {
+		var ee []flow.Event
+		var header *flow.Header
+		err := s.retryTransient(ctx, func() error {
+			var err error
+			ee, err = blockEvents(index, height, types...)
+			if errors.Is(err, errBudgetExceeded) {
+				return err
+			}
+			if err != nil {
+				return fmt.Errorf("could not get events at height %d: %w", height, err)
+			}
+
+			header, err = s.header(index, height)
+			if err != nil {
+				return fmt.Errorf("could not get header at height %d: %w", height, err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp := s.timestamp(header)
+
+		ee = s.allowedEvents(ee)
+		messages := make([]*entities.Event, 0, len(ee))
+		for _, event := range ee {
+			messages = append(messages, convert.EventToMessage(event))
+		}
+
+		blockID := header.ID()
+		result := access.EventsResponse_Result{
+			BlockId:        blockID[:],
+			BlockHeight:    height,
+			BlockTimestamp: timestamp,
+			Events:         messages,
+		}
+
+		events = append(events, &result)
+	}
+
+	resp := access.EventsResponse{
+		Results: events,
+	}
+
+	return &resp, nil
+}
+
+// GetEventCountsForHeightRange returns the number of events of each type in the
+// given height range. The heights are read concurrently, and their events are
+// only counted, so that clients do not have to transfer every payload to count
+// them. Events of types that cannot be queried are not counted.
+func (s *Server) GetEventCountsForHeightRange(ctx context.Context, in *extended.GetEventCountsForHeightRangeRequest) (*extended.EventCountsResponse, error) {
+	index := s.reader(ctx)
+
+	if in.StartHeight > in.EndHeight {
+		return nil, status.Errorf(codes.InvalidArgument, "start height %d is above end height %d", in.StartHeight, in.EndHeight)
+	}
+	if in.EndHeight-in.StartHeight >= uint64(s.cfg.MaxBatchSize) {
+		return nil, status.Errorf(codes.InvalidArgument, "too many heights (%d > %d)", in.EndHeight-in.StartHeight+1, s.cfg.MaxBatchSize)
+	}
+
+	err := s.checkWindow(index, in.StartHeight)
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkWindow(index, in.EndHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := s.budget()
+	if budget != nil {
+		index = &budgetReader{Reader: index, budget: budget}
+	}
+
+	heights := make([]uint64, 0, in.EndHeight-in.StartHeight+1)
+	for height := in.StartHeight; height <= in.EndHeight; height++ {
+		heights = append(heights, height)
+	}
+
+	counts, err := workerpool.Map(ctx, s.cfg.BatchWorkers, heights, func(_ context.Context, height uint64) (map[flow.EventType]uint64, error) {
+		events, err := index.Events(height)
+		if errors.Is(err, errBudgetExceeded) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not get events at height %d: %w", height, err)
+		}
+
+		counts := make(map[flow.EventType]uint64)
+		for _, event := range s.allowedEvents(events) {
+			counts[event.Type]++
+		}
+
+		return counts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := extended.EventCountsResponse{
+		Counts: make(map[string]uint64),
+	}
+	for _, heightCounts := range counts {
+		for typ, count := range heightCounts {
+			resp.Counts[string(typ)] += count
+			resp.Total += count
+		}
+	}
+
+	return &resp, nil
+}
+
+// GetDecodedEventsForHeightRange returns the same events as the
+// GetEventsForHeightRange endpoint, along with the values of their fields
+// decoded from their JSON-CDC payloads.
+func (s *Server) GetDecodedEventsForHeightRange(ctx context.Context, in *access.GetEventsForHeightRangeRequest) (*extended.DecodedEventsResponse, error) {
+	if !s.cfg.DecodeEvents {
+		return nil, status.Error(codes.Unimplemented, "event decoding is not enabled on this server")
+	}
+
+	events, err := s.GetEventsForHeightRange(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*extended.DecodedEventsResponse_Result, 0, len(events.Results))
+	for _, result := range events.Results {
+		decoded := make([]*extended.DecodedEvent, 0, len(result.Events))
+		for _, event := range result.Events {
+			// Unless decoding is strict, an event that cannot be decoded is
+			// returned with its stored payload only, rather than failing the
+			// events that can be.
+			fields, err := decodeEventFields(event.Payload)
+			if err != nil && s.cfg.StrictDecoding {
+				return nil, fmt.Errorf("could not decode event %d of transaction %x at height %d: %w", event.EventIndex, event.TransactionId, result.BlockHeight, err)
+			}
+			if err != nil {
+				s.cfg.Log.Warn().
+					Err(err).
+					Uint64("height", result.BlockHeight).
+					Hex("transaction", event.TransactionId).
+					Uint32("index", event.EventIndex).
+					Msg("returning event without decoded fields")
+				eventDecodeFailures.Inc()
+			}
+
+			decoded = append(decoded, &extended.DecodedEvent{
+				Event:  event,
+				Fields: fields,
+			})
+		}
+
+		results = append(results, &extended.DecodedEventsResponse_Result{
+			BlockId:        result.BlockId,
+			BlockHeight:    result.BlockHeight,
+			BlockTimestamp: result.BlockTimestamp,
+			Events:         decoded,
+		})
+	}
+
+	resp := extended.DecodedEventsResponse{
+		Results: results,
+	}
+
+	return &resp, nil
+}
+
+// GetEventsForBlockIDs implements the GetEventsForBlockIDs endpoint from the Flow Access API.
+// See https://docs.onflow.org/access-api/#geteventsforblockids
+func (s *Server) GetEventsForBlockIDs(ctx context.Context, in *access.GetEventsForBlockIDsRequest) (*access.EventsResponse, error) {
+	index := s.reader(ctx)
+
+	var types []flow.EventType
+	if in.Type != "" {
+		types = append(types, flow.EventType(in.Type))
+	}
+	err := s.checkEventType(in.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint(len(in.BlockIds)) > s.cfg.MaxBlockIDs {
+		return nil, status.Errorf(codes.InvalidArgument, "too many block IDs (%d > %d)", len(in.BlockIds), s.cfg.MaxBlockIDs)
+	}
+
+	for i, id := range in.BlockIds {
+		if len(id) != len(flow.ZeroID) {
+			return nil, status.Errorf(codes.InvalidArgument, "block ID at index %d has invalid length %d", i, len(id))
+		}
+	}
+
+	budget := s.budget()
+	if budget != nil {
+		index = &budgetReader{Reader: index, budget: budget}
+	}
+
+	var events []*access.EventsResponse_Result
+	for _, id := range in.BlockIds {
+		blockID := flow.HashToID(id)
+		height, err := s.heightForBlock(index, blockID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get height of block with ID %x: %w", id, err)
+		}
+
+		err = s.checkWindow(index, height)
+		if err != nil {
+			return nil, err
+		}
+
+		ee, err := blockEvents(index, height, types...)
+		if errors.Is(err, errBudgetExceeded) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not get events at height %d: %w", height, err)
+		}
+
+		header, err := s.header(index, height)
+		if err != nil {
+			return nil, fmt.Errorf("could not get header at height %d: %w", height, err)
+		}
+
+		timestamp := s.timestamp(header)
+
+		ee = s.allowedEvents(ee)
+		messages := make([]*entities.Event, 0, len(ee))
+		for _, event := range ee {
+			messages = append(messages, convert.EventToMessage(event))
+		}
+
+		result := access.EventsResponse_Result{
+			BlockId:        blockID[:],
+			BlockHeight:    height,
+			BlockTimestamp: timestamp,
+			Events:         messages,
+		}
+
+		events = append(events, &result)
+	}
+
+	resp := access.EventsResponse{
+		Results: events,
+	}
+
+	return &resp, nil
+}
+
+// GetEventsForTransaction returns the events emitted by the transaction with the
+// given ID, which are read from the events of the block that includes it.
+func (s *Server) GetEventsForTransaction(ctx context.Context, in *extended.GetEventsForTransactionRequest) (*extended.EventsForTransactionResponse, error) {
+	index := s.reader(ctx)
+
+	txID := flow.HashToID(in.TransactionId)
+	height, err := s.transactionHeight(index, txID)
+	if isNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "transaction %x not found", txID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get height for transaction %x: %w", txID, err)
+	}
+
+	header, err := s.header(index, height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get header at height %d: %w", height, err)
+	}
+
+	events, err := blockEvents(index, height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get events at height %d: %w", height, err)
+	}
+
+	var filtered []flow.Event
+	for _, event := range events {
+		if event.TransactionID == txID {
+			filtered = append(filtered, event)
+		}
+	}
+
+	messages := make([]*entities.Event, 0, len(filtered))
+	for _, event := range filtered {
+		messages = append(messages, convert.EventToMessage(event))
+	}
+
+	blockID := header.ID()
+	resp := extended.EventsForTransactionResponse{
+		BlockId:     blockID[:],
+		BlockHeight: height,
+		Events:      messages,
+	}
+
+	return &resp, nil
+}
+
+// GetNetworkParameters implements the GetNetworkParameters endpoint from the Flow Access API.
+// See https://docs.onflow.org/access-api/#getnetworkparameters
+func (s *Server) GetNetworkParameters(ctx context.Context, _ *access.GetNetworkParametersRequest) (*access.GetNetworkParametersResponse, error) {
+	index := s.reader(ctx)
+
+	// The first served height is used rather than the first indexed one, as
+	// older heights might have been pruned when a height window is configured.
+	root, _, err := s.heightRange(index)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := s.header(index, root)
+	if err != nil {
+		return nil, fmt.Errorf("could not get header: %w", err)
+	}
+
+	return &access.GetNetworkParametersResponse{ChainId: header.ChainID.String()}, nil
+}
+
+// GetExecutionResultForBlockID is not implemented, because the archive only
+// indexes the IDs and final states of execution results, through their seals,
+// and not the results themselves with their chunks and service events. Those
+// are available from the GetSealByBlockID endpoint of the extended API.
+// See https://docs.onflow.org/access-api/#getexecutionresultforblockid
+func (s *Server) GetExecutionResultForBlockID(ctx context.Context, in *access.GetExecutionResultForBlockIDRequest) (*access.ExecutionResultForBlockIDResponse, error) {
+	if s.proxies("GetExecutionResultForBlockID") {
+		return s.cfg.Upstream.GetExecutionResultForBlockID(ctx, in)
+	}
+
+	return nil, status.Error(codes.Unimplemented, "GetExecutionResultForBlockID is not implemented, as execution result chunks and service events are not indexed; please use GetSealByBlockID for the result ID and final state, or the Flow Access API on a Flow access node directly")
+}
+
+// SendTransaction implements the SendTransaction endpoint from the Flow Access API,
+// if the server was configured with a submitter to forward transactions to, or
+// to proxy them to its upstream access node.
+// See https://docs.onflow.org/access-api/#sendtransaction
+func (s *Server) SendTransaction(ctx context.Context, in *access.SendTransactionRequest) (*access.SendTransactionResponse, error) {
+	if s.cfg.Submitter != nil {
+		return s.cfg.Submitter.SendTransaction(ctx, in)
+	}
+	if s.proxies("SendTransaction") {
+		return s.cfg.Upstream.SendTransaction(ctx, in)
+	}
+
+	return nil, status.Error(codes.Unimplemented, "SendTransaction is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
+}
+
+// GetLatestProtocolStateSnapshot returns the latest snapshot of the snapshot
+// source that is not above the last indexed height, so that it is consistent
+// with the rest of the archive. Without a snapshot source, it can be proxied to
+// the upstream access node.
+// See https://docs.onflow.org/access-api/#getlatestprotocolstatesnapshotrequest
+func (s *Server) GetLatestProtocolStateSnapshot(ctx context.Context, in *access.GetLatestProtocolStateSnapshotRequest) (*access.ProtocolStateSnapshotResponse, error) {
+	if s.cfg.Snapshots != nil {
+		last, err := lastHeight(s.reader(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot, err := s.cfg.Snapshots.SnapshotAtHeight(last)
+		if isNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "no snapshot stored at or below height %d", last)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not get snapshot at height %d: %w", last, err)
+		}
+
+		resp := access.ProtocolStateSnapshotResponse{
+			SerializedSnapshot: snapshot,
+		}
+
+		return &resp, nil
+	}
+
+	if s.proxies("GetLatestProtocolStateSnapshot") {
+		return s.cfg.Upstream.GetLatestProtocolStateSnapshot(ctx, in)
+	}
+
+	return nil, status.Error(codes.Unimplemented, "GetLatestProtocolSnapshot is not implemented by the Flow DPS API; please use the Flow Access API on a Flow access node directly")
+}
+
+// checkHeight returns an out of range error if the given height is not within
+// the range of heights served by the archive.
+func (s *Server) checkHeight(index archive.Reader, height uint64) error {
+	first, last, err := s.heightRange(index)
+	if err != nil {
+		return err
+	}
+
+	if height < first || height > last {
+		return status.Errorf(codes.OutOfRange, "height %d is outside of served range [%d, %d]", height, first, last)
+	}
+
+	return nil
+}
+
+// tooStale returns whether the block at the given latest indexed height is older
+// than the upstream's latest finalized block by more than the maximum staleness
+// requested by the client. When the method is not proxied, or without the
+// header, data is always served from the index. Since the header is only a hint,
+// the index is also used when the upstream cannot be reached, which is logged
+// and counted so that it does not go unnoticed.
+func (s *Server) tooStale(ctx context.Context, index archive.Reader, method string, last uint64) (bool, error) {
+	maxStaleness, ok, err := headerDuration(ctx, MaxStalenessHeader)
+	if err != nil {
+		return false, err
+	}
+	if !ok || !s.proxies(method) {
+		return false, nil
+	}
+
+	header, err := s.header(index, last)
+	if err != nil {
+		return false, fmt.Errorf("could not get header at height %d: %w", last, err)
+	}
+
+	latest, err := s.cfg.Upstream.GetLatestBlockHeader(ctx, &access.GetLatestBlockHeaderRequest{IsSealed: false})
+	if err != nil {
+		stalenessCheckFailures.Inc()
+		s.cfg.Log.Warn().Str("method", method).Err(err).Msg("could not get latest block from upstream, serving request from index")
+		return false, nil
+	}
+	finalized := latest.GetBlock().GetTimestamp().AsTime()
+
+	return finalized.Sub(header.Timestamp) > maxStaleness, nil
+}
+
+// proxies returns whether requests for the given method are proxied to the
+// upstream access node, according to the overrides, or otherwise to the mode of
+// the server. Requests are never proxied without an upstream.
+func (s *Server) proxies(method string) bool {
+	if s.cfg.Upstream == nil {
+		return false
+	}
+
+	proxy, ok := s.cfg.ProxyOverrides[method]
+	if ok {
+		return proxy
+	}
+
+	return s.cfg.Mode == ModeHybrid
+}
+
+// checkWindow checks the given height against the range of served heights, but
+// only when the server is limited to a window of heights or its head is pinned.
+// Otherwise, heights outside of the indexed range are left for the index to
+// reject.
+func (s *Server) checkWindow(index archive.Reader, height uint64) error {
+	if s.cfg.HeightWindow == 0 && s.cfg.PinnedHead == 0 {
+		return nil
+	}
+
+	return s.checkHeight(index, height)
+}
+
+// heightRange returns the range of heights served by the archive, which is the
+// indexed range, limited to its most recent heights if a window is configured.
+func (s *Server) heightRange(index archive.Reader) (uint64, uint64, error) {
+	return windowRange(index, s.cfg.HeightWindow)
+}
+
+// windowRange returns the range of heights indexed by the given index, limited
+// to its most recent heights if the window is not zero.
+func windowRange(index archive.Reader, window uint64) (uint64, uint64, error) {
+	last, err := lastHeight(index)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	first, err := index.First()
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not get first height: %w", err)
+	}
+
+	if window > 0 && last-first >= window {
+		first = last - window + 1
+	}
+
+	return first, last, nil
+}
+
+// indexedRange returns the cached range of heights indexed by the archive. The
+// first height is only read once, while the last height is read again once the
+// refresh interval has elapsed since it was last read.
+func (s *Server) indexedRange(index archive.Reader) (uint64, uint64, error) {
+	s.rangeMu.Lock()
+	defer s.rangeMu.Unlock()
+
+	if s.lastRefresh.IsZero() || time.Since(s.lastRefresh) >= s.cfg.RangeRefresh {
+		last, err := lastHeight(index)
+		if err != nil {
+			return 0, 0, err
+		}
+		s.last = last
+		s.lastRefresh = time.Now()
+	}
+
+	if !s.firstCached {
+		first, err := index.First()
+		if err != nil {
+			return 0, 0, fmt.Errorf("could not get first height: %w", err)
+		}
+		s.first = first
+		s.firstCached = true
+	}
+
+	return s.first, s.last, nil
+}
+
+// lastHeight returns the last height indexed by the archive, or an unavailable
+// error if the archive has not indexed any blocks yet.
+func lastHeight(index archive.Reader) (uint64, error) {
+	last, err := index.Last()
+	if isNotFound(err) {
+		return 0, errNoIndexedBlocks
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not get last height: %w", err)
+	}
+
+	// An empty index reports a last height of zero, which is also the height of
+	// the root block on some networks, so the root block is checked as well.
+	if last == 0 {
+		_, err = index.Header(0)
+		if isNotFound(err) {
+			return 0, errNoIndexedBlocks
+		}
+		if err != nil {
+			return 0, fmt.Errorf("could not get root header: %w", err)
+		}
+	}
+
+	return last, nil
+}
+
+// heightForBlock returns the height of the block with the given ID from the
+// index, sharing the call with any concurrent lookup of the same block. The
+// shared call is made through the reader of the request that started it, so it
+// carries the request ID of that request to the archive. Such lookups are not
+// charged to request budgets, so sharing them across readers is safe.
+func (s *Server) heightForBlock(index archive.Reader, blockID flow.Identifier) (uint64, error) {
+	height, err, _ := s.heightCalls.Do(blockID.String(), func() (interface{}, error) {
+		return index.HeightForBlock(blockID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return height.(uint64), nil
+}
+
+// transactionHeight returns the height of the block that includes the given
+// transaction. When duplicates are resolved, the blocks in which the
+// transaction could have been included before its indexed height, from its
+// reference block on, are looked up, as only its first inclusion is executed
+// successfully. The first inclusion whose block is sealed, as found by a seal
+// for it in the blocks that follow it, is used, or the first inclusion if none
+// is. Transactions found in several blocks are counted in the duplicate
+// transactions metric.
+func (s *Server) transactionHeight(index archive.Reader, txID flow.Identifier) (uint64, error) {
+	height, err := index.HeightForTransaction(txID)
+	if err != nil || !s.cfg.ResolveDuplicates {
+		return height, err
+	}
+
+	// System transactions have no body in the index, and are never duplicated.
+	tx, err := index.Transaction(txID)
+	if isNotFound(err) {
+		return height, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not get transaction: %w", err)
+	}
+
+	first, err := index.First()
+	if err != nil {
+		return 0, fmt.Errorf("could not get first height: %w", err)
+	}
+	last, err := lastHeight(index)
+	if err != nil {
+		return 0, err
+	}
+
+	// A reference block that is not indexed is older than the indexed range,
+	// in which case the transaction expires at the latest one expiry after it.
+	start := first
+	reference, err := s.heightForBlock(index, tx.ReferenceBlockID)
+	if err != nil && !isNotFound(err) {
+		return 0, fmt.Errorf("could not get height of reference block %x: %w", tx.ReferenceBlockID, err)
+	}
+	if err == nil && reference+1 > start {
+		start = reference + 1
+	}
+	// Inclusions after the indexed height are never the first one, so only the
+	// blocks up to it are looked up, which are few for most transactions.
+	end := start + flow.DefaultTransactionExpiry - 1
+	if end > last {
+		end = last
+	}
+	if end > height {
+		end = height
+	}
+
+	var heights []uint64
+	for candidate := start; candidate <= end; candidate++ {
+		txIDs, err := index.TransactionsByHeight(candidate)
+		if err != nil {
+			return 0, fmt.Errorf("could not get transactions for height %d: %w", candidate, err)
+		}
+		for _, id := range txIDs {
+			if id == txID {
+				heights = append(heights, candidate)
+				break
+			}
+		}
+	}
+
+	if len(heights) == 0 {
+		return height, nil
+	}
+	if len(heights) == 1 {
+		return heights[0], nil
+	}
+
+	duplicateTransactions.Inc()
+	s.cfg.Log.Warn().Hex("transaction_id", txID[:]).Uints64("heights", heights).Uint64("indexed_height", height).Msg("transaction included in several blocks")
+
+	for _, candidate := range heights {
+		header, err := s.header(index, candidate)
+		if err != nil {
+			return 0, fmt.Errorf("could not get header for height %d: %w", candidate, err)
+		}
+		seal, _, err := findSeal(index, header.ID(), candidate)
+		if err != nil {
+			return 0, fmt.Errorf("could not get seal for height %d: %w", candidate, err)
+		}
+		if seal != nil {
+			return candidate, nil
+		}
+	}
+
+	return heights[0], nil
+}
+
+// header returns the header at the given height from the index, sharing the
+// call with any concurrent lookup of the same height, like heightForBlock does.
+// The returned header can be shared between requests, so it must not be
+// modified.
+func (s *Server) header(index archive.Reader, height uint64) (*flow.Header, error) {
+	header, err, _ := s.headerCalls.Do(strconv.FormatUint(height, 10), func() (interface{}, error) {
+		return index.Header(height)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return header.(*flow.Header), nil
+}
+
+// timestamp converts the timestamp of the given header into its RPC message
+// representation. When timestamps are validated, timestamps outside of the
+// valid bounds are clamped to them, and a warning is logged, so that blocks
+// with corrupt timestamps are still returned without breaking clients.
+func (s *Server) timestamp(header *flow.Header) *timestamppb.Timestamp {
+	timestamp := header.Timestamp
+	if !s.cfg.ValidateTimestamps {
+		return timestamppb.New(timestamp)
+	}
+
+	max := time.Now().Add(maxTimestampSkew)
+	switch {
+	case timestamp.Before(minTimestamp):
+		s.cfg.Log.Warn().Uint64("height", header.Height).Time("timestamp", timestamp).Msg("clamping block timestamp that is too early")
+		timestamp = minTimestamp
+	case timestamp.After(max):
+		s.cfg.Log.Warn().Uint64("height", header.Height).Time("timestamp", timestamp).Msg("clamping block timestamp that is too far in the future")
+		timestamp = max
+	}
+
+	return timestamppb.New(timestamp)
+}
+
+// retryTransient calls the given function until it succeeds, fails with an
+// error that is not transient, or has been retried as many times as configured.
+// It waits for the configured backoff before the first retry, and twice as long
+// before each following one, unless the context is done first.
+func (s *Server) retryTransient(ctx context.Context, fn func() error) error {
+	backoff := s.cfg.RangeBackoff
+	for retry := uint(0); ; retry++ {
+		err := fn()
+		if err == nil || !isTransient(err) || retry >= s.cfg.RangeRetries {
+			return err
+		}
+
+		s.cfg.Log.Debug().Err(err).Uint("retry", retry+1).Dur("backoff", backoff).Msg("retrying transient archive failure")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		backoff *= 2
+	}
+}
+
+// budget returns a new budget for a request, or nil if the cost of requests is
+// not limited.
+func (s *Server) budget() *requestBudget {
+	if s.cfg.RequestBudget == 0 {
+		return nil
+	}
+
+	return newRequestBudget(s.cfg.RequestBudget)
+}
+
+// RequestIndexFactory returns an index factory, for use with WithIndexFactory,
+// that gets a reader for the request ID of each request from the given function,
+// so that the ID is propagated to the archive. Requests without an ID use the
+// given index.
+func RequestIndexFactory(index archive.Reader, forRequest func(id string) archive.Reader) func(ctx context.Context) archive.Reader {
+	return func(ctx context.Context) archive.Reader {
+		id, ok := middleware.RequestIDFromContext(ctx)
+		if !ok {
+			return index
+		}
+		return forRequest(id)
+	}
+}
+
+// reader returns the index reader to use for the request with the given context.
+// If the head is pinned, the reader does not go beyond it.
+func (s *Server) reader(ctx context.Context) archive.Reader {
+	index := s.index
+	if s.cfg.NewIndex != nil {
+		index = s.cfg.NewIndex(ctx)
+	}
+
+	if s.cfg.PinnedHead > 0 {
+		index = &pinnedReader{Reader: index, head: s.cfg.PinnedHead}
+	}
+
+	return index
+}
+
+// cachedBlock returns the block response for the given height from the block
+// cache, if it is enabled and contains it, or else from the disk cache. Blocks
+// found on disk are added to the block cache, so that they are served from
+// memory afterwards.
+func (s *Server) cachedBlock(height uint64) (*access.BlockResponse, bool) {
+	if s.cfg.BlockCache == nil && s.cfg.DiskCache == nil {
+		return nil, false
+	}
+
+	var data []byte
+	if s.cfg.BlockCache != nil {
+		value, ok := s.cfg.BlockCache.Get(height)
+		if ok {
+			data = value.([]byte)
+		}
+	}
+	if data == nil && s.cfg.DiskCache != nil {
+		value, ok := s.cfg.DiskCache.Get(blockKey(height))
+		if ok {
+			data = value
+			if s.cfg.BlockCache != nil {
+				s.cfg.BlockCache.Set(height, data, uint64(len(data)))
+			}
+		}
+	}
+	if data == nil {
+		blockCacheMisses.Inc()
+		return nil, false
+	}
+
+	var resp access.BlockResponse
+	err := proto.Unmarshal(data, &resp)
+	if err != nil {
+		blockCacheMisses.Inc()
+		return nil, false
+	}
+
+	blockCacheHits.Inc()
+	return &resp, true
+}
+
+// cacheBlock stores the block response for the given height in the block cache
+// and written through to the disk cache, if they are enabled. The latest height
+// is skipped, so that only blocks which have been built upon by the archive are
+// cached. Failing to cache a block does not fail the request, as the response
+// is already available.
+func (s *Server) cacheBlock(index archive.Reader, height uint64, resp *access.BlockResponse) {
+	if s.cfg.BlockCache == nil && s.cfg.DiskCache == nil {
+		return
+	}
+
+	last, err := index.Last()
+	if err != nil || height >= last {
+		return
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		return
+	}
+
+	if s.cfg.BlockCache != nil {
+		s.cfg.BlockCache.Set(height, data, uint64(len(data)))
+	}
+	if s.cfg.DiskCache != nil {
+		err = s.cfg.DiskCache.Set(blockKey(height), data)
+		if err != nil {
+			s.cfg.Log.Debug().Uint64("height", height).Err(err).Msg("could not write block to disk cache")
+		}
+	}
+}
+
+// blockKey returns the key of the block response for the given height in the
+// disk cache.
+func blockKey(height uint64) []byte {
+	return []byte(fmt.Sprintf("block/%d", height))
+}
+
+// blockEvents returns the events at the given height, ordered by transaction
+// index and then by event index, which is the order in which they were emitted
+// in the block. Their indices are the ones stored in the index, but the index
+// does not return the events in any particular order.
+func blockEvents(index archive.Reader, height uint64, types ...flow.EventType) ([]flow.Event, error) {
+	events, err := index.Events(height, types...)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].TransactionIndex != events[j].TransactionIndex {
+			return events[i].TransactionIndex < events[j].TransactionIndex
+		}
+		return events[i].EventIndex < events[j].EventIndex
+	})
+
+	return events, nil
+}
+
+// omitContracts clears the contracts of the given account, if the client asked
+// for them to be omitted with the corresponding metadata header.
+func omitContracts(ctx context.Context, account *entities.Account) error {
+	omit, err := headerFlag(ctx, OmitContractsHeader)
+	if err != nil {
+		return err
+	}
+	if omit && account != nil {
+		account.Code = nil
+		account.Contracts = nil
+	}
+
+	return nil
+}
+
+// setBlockStats sets the response headers with the number of transactions and
+// events at the given height, and the total computation used by its transactions.
+func setBlockStats(ctx context.Context, index archive.Reader, height uint64) error {
+	txIDs, err := index.TransactionsByHeight(height)
+	if err != nil {
+		return fmt.Errorf("could not get transactions for height %d: %w", height, err)
+	}
+
+	var computation uint64
+	for _, txID := range txIDs {
+		result, err := index.Result(txID)
+		if err != nil {
+			return fmt.Errorf("could not get result for transaction %x: %w", txID, err)
+		}
+		computation += result.ComputationUsed
+	}
+
+	events, err := index.Events(height)
+	if err != nil {
+		return fmt.Errorf("could not get events for height %d: %w", height, err)
+	}
+
+	stats := metadata.Pairs(
+		BlockTransactionsHeader, strconv.Itoa(len(txIDs)),
+		BlockEventsHeader, strconv.Itoa(len(events)),
+		BlockComputationHeader, strconv.FormatUint(computation, 10),
+	)
+	err = grpc.SetHeader(ctx, stats)
+	if err != nil {
+		return fmt.Errorf("could not set block statistics headers: %w", err)
+	}
+
+	return nil
+}
+
+// setReferenceBlock sets the response headers with the ID and the height of the
+// reference block of the collection with the given ID. Reference blocks that
+// are not indexed, such as those from before the first indexed height, only have
+// their ID sent.
+func setReferenceBlock(ctx context.Context, index archive.Reader, collID flow.Identifier) error {
+	guarantee, err := index.Guarantee(collID)
+	if err != nil {
+		return fmt.Errorf("could not get guarantee for collection %x: %w", collID, err)
+	}
+
+	refID := guarantee.ReferenceBlockID
+	reference := metadata.Pairs(ReferenceBlockIDHeader, refID.String())
+
+	height, err := index.HeightForBlock(refID)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("could not get height for reference block %x: %w", refID, err)
+	}
+	if err == nil {
+		reference.Set(ReferenceBlockHeightHeader, strconv.FormatUint(height, 10))
+	}
+
+	err = grpc.SetHeader(ctx, reference)
+	if err != nil {
+		return fmt.Errorf("could not set reference block headers: %w", err)
+	}
+
+	return nil
+}
+
+// countAddress counts a request for the given account address, if address
+// accounting is enabled.
+func (s *Server) countAddress(address flow.Address) {
+	if s.cfg.Addresses == nil {
+		return
+	}
+
+	s.cfg.Addresses.Add(string(address[:]))
+}
+
+// scriptKey returns a key that identifies the execution of the given script with
+// the given encoded arguments at the given height.
+func scriptKey(height uint64, script []byte, arguments [][]byte) string {
+	hash := sha256.New()
+	_ = binary.Write(hash, binary.BigEndian, height)
+	for _, data := range append([][]byte{script}, arguments...) {
+		_ = binary.Write(hash, binary.BigEndian, uint64(len(data)))
+		_, _ = hash.Write(data)
+	}
+
+	return string(hash.Sum(nil))
+}
+
+// decodeArguments decodes the given JSON-CDC script arguments. All arguments are
+// decoded even when some are invalid, so that all problems are reported at once
+// in a single invalid argument error, with the index of each invalid argument.
+// Unless decoding is strict, static types encoded as plain type IDs, as older
+// versions of the format did, are accepted.
+func decodeArguments(arguments [][]byte, strict bool) ([]cadence.Value, error) {
+	var options []json.Option
+	if !strict {
+		options = append(options, json.WithAllowUnstructuredStaticTypes(true))
+	}
+
+	var args []cadence.Value
+	var problems []string
+	for i, arg := range arguments {
+		val, err := json.Decode(nil, arg, options...)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("argument %d: %s", i, err))
+			continue
+		}
+
+		args = append(args, val)
+	}
+
+	if len(problems) > 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "could not decode script arguments: %s", strings.Join(problems, "; "))
+	}
+
+	return args, nil
+}
+
+// decodeEventFields decodes the given JSON-CDC event payload, and maps the name
+// of each of its fields to the Cadence string representation of its value.
+func decodeEventFields(payload []byte) (map[string]string, error) {
+	value, err := json.Decode(nil, payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode payload: %w", err)
+	}
+
+	event, ok := value.(cadence.Event)
+	if !ok {
+		return nil, fmt.Errorf("unexpected payload type %T", value)
+	}
+
+	fields := make(map[string]string, len(event.Fields))
+	for i, field := range event.EventType.Fields {
+		if i >= len(event.Fields) {
+			break
+		}
+		fields[field.Identifier] = event.Fields[i].String()
+	}
+
+	return fields, nil
 }
@@ -16,14 +16,37 @@ package api
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/dgraph-io/badger/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/onflow/cadence"
 	"github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/flow-go/crypto"
 	"github.com/onflow/flow-go/engine/common/rpc/convert"
+	"github.com/onflow/flow-go/fvm/blueprints"
+	fvmErrors "github.com/onflow/flow-go/fvm/errors"
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
 	"github.com/onflow/flow/protobuf/go/flow/access"
@@ -31,6 +54,11 @@ import (
 
 	"github.com/onflow/flow-archive/models/archive"
 	"github.com/onflow/flow-archive/testing/mocks"
+
+	"github.com/onflow/flow-archive-access/api/cache"
+	"github.com/onflow/flow-archive-access/api/extended"
+	"github.com/onflow/flow-archive-access/api/middleware"
+	"github.com/onflow/flow-archive-access/api/topk"
 )
 
 func TestNewServer(t *testing.T) {
@@ -56,6 +84,94 @@ func TestServer_Ping(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestServer_GetCollectionForTransaction(t *testing.T) {
+	header := mocks.GenericHeader
+	txIDs := mocks.GenericTransactionIDs(5)
+	collIDs := mocks.GenericCollectionIDs(2)
+	collections := map[flow.Identifier]*flow.LightCollection{
+		collIDs[0]: {Transactions: txIDs[:2]},
+		collIDs[1]: {Transactions: txIDs[2:4]},
+	}
+
+	baselineIndex := func(t *testing.T) *mocks.Reader {
+		index := mocks.BaselineReader(t)
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height, nil
+		}
+		index.CollectionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Equal(t, header.Height, height)
+
+			return collIDs, nil
+		}
+		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
+			return collections[collID], nil
+		}
+		return index
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = baselineIndex(t)
+
+		req := &extended.GetCollectionForTransactionRequest{TransactionId: txIDs[3][:]}
+		resp, err := s.GetCollectionForTransaction(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, collIDs[1][:], resp.Collection.Id)
+		assert.Equal(t, [][]byte{txIDs[2][:], txIDs[3][:]}, resp.Collection.TransactionIds)
+	})
+
+	t.Run("handles system transaction", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = baselineIndex(t)
+
+		// The last transaction of the block is in none of its collections.
+		req := &extended.GetCollectionForTransactionRequest{TransactionId: txIDs[4][:]}
+		_, err := s.GetCollectionForTransaction(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles unknown transaction", func(t *testing.T) {
+		t.Parallel()
+
+		index := baselineIndex(t)
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return 0, badger.ErrKeyNotFound
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetCollectionForTransactionRequest{TransactionId: txIDs[0][:]}
+		_, err := s.GetCollectionForTransaction(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles indexer failure on Collection", func(t *testing.T) {
+		t.Parallel()
+
+		index := baselineIndex(t)
+		index.CollectionFunc = func(flow.Identifier) (*flow.LightCollection, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetCollectionForTransactionRequest{TransactionId: txIDs[0][:]}
+		_, err := s.GetCollectionForTransaction(context.Background(), req)
+
+		assert.Error(t, err)
+		assert.NotEqual(t, codes.NotFound, status.Code(err))
+	})
+}
+
 func TestServer_GetTransaction(t *testing.T) {
 	tx := mocks.GenericTransaction(0)
 	txID := tx.ID()
@@ -81,6 +197,47 @@ func TestServer_GetTransaction(t *testing.T) {
 		assert.Equal(t, tx.ReferenceBlockID[:], resp.Transaction.ReferenceBlockId)
 	})
 
+	t.Run("returns proposer, payer and authorizers", func(t *testing.T) {
+		t.Parallel()
+
+		addresses := mocks.GenericAddresses(4)
+		tx := flow.TransactionBody{
+			ReferenceBlockID: mocks.GenericHeader.ID(),
+			ProposalKey: flow.ProposalKey{
+				Address:        addresses[0],
+				KeyIndex:       2,
+				SequenceNumber: 42,
+			},
+			Payer:       addresses[1],
+			Authorizers: []flow.Address{addresses[0], addresses[2], addresses[3]},
+		}
+		txID := tx.ID()
+
+		index := mocks.BaselineReader(t)
+		index.TransactionFunc = func(gotTxID flow.Identifier) (*flow.TransactionBody, error) {
+			assert.Equal(t, txID, gotTxID)
+
+			return &tx, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		resp, err := s.GetTransaction(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotNil(t, resp.Transaction.ProposalKey)
+		assert.Equal(t, addresses[0].Bytes(), resp.Transaction.ProposalKey.Address)
+		assert.Equal(t, uint32(2), resp.Transaction.ProposalKey.KeyId)
+		assert.Equal(t, uint64(42), resp.Transaction.ProposalKey.SequenceNumber)
+		assert.Equal(t, addresses[1].Bytes(), resp.Transaction.Payer)
+		require.Len(t, resp.Transaction.Authorizers, len(tx.Authorizers))
+		for i, authorizer := range tx.Authorizers {
+			assert.Equal(t, authorizer.Bytes(), resp.Transaction.Authorizers[i])
+		}
+	})
+
 	t.Run("handles indexer error on transaction", func(t *testing.T) {
 		t.Parallel()
 
@@ -99,6 +256,118 @@ func TestServer_GetTransaction(t *testing.T) {
 	})
 }
 
+func TestServer_GetTransactions(t *testing.T) {
+	txs := mocks.GenericTransactions(3)
+	missing := mocks.GenericTransaction(4).ID()
+	failing := mocks.GenericTransaction(5).ID()
+
+	txMap := make(map[flow.Identifier]*flow.TransactionBody)
+	for _, tx := range txs {
+		txMap[tx.ID()] = tx
+	}
+
+	index := mocks.BaselineReader(t)
+	index.TransactionFunc = func(txID flow.Identifier) (*flow.TransactionBody, error) {
+		switch txID {
+		case missing:
+			return nil, badger.ErrKeyNotFound
+		case failing:
+			return nil, mocks.GenericError
+		}
+
+		tx, ok := txMap[txID]
+		require.True(t, ok)
+		return tx, nil
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.BatchWorkers = 2
+
+		var ids [][]byte
+		for _, tx := range txs {
+			txID := tx.ID()
+			ids = append(ids, txID[:])
+		}
+		ids = append(ids, missing[:], failing[:])
+
+		req := &extended.GetTransactionsRequest{Ids: ids}
+		resp, err := s.GetTransactions(context.Background(), req)
+
+		require.NoError(t, err)
+		require.Len(t, resp.Results, len(ids))
+		for i, tx := range txs {
+			result := resp.Results[i]
+			assert.Equal(t, ids[i], result.Id)
+			assert.Zero(t, result.Code)
+			require.NotNil(t, result.Transaction)
+			assert.Equal(t, tx.ReferenceBlockID[:], result.Transaction.ReferenceBlockId)
+		}
+		assert.Equal(t, uint32(codes.NotFound), resp.Results[3].Code)
+		assert.Nil(t, resp.Results[3].Transaction)
+		assert.Equal(t, uint32(codes.Internal), resp.Results[4].Code)
+		assert.NotEmpty(t, resp.Results[4].Error)
+	})
+
+	t.Run("recovers from panicking transaction", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.TransactionFunc = func(flow.Identifier) (*flow.TransactionBody, error) {
+			panic("failure")
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.BatchWorkers = 2
+
+		txID := txs[0].ID()
+		req := &extended.GetTransactionsRequest{Ids: [][]byte{txID[:]}}
+		info := &grpc.UnaryServerInfo{FullMethod: "/flow.extended.ExtendedAPI/GetTransactions"}
+		recovery := middleware.RecoveryUnaryServerInterceptor(zerolog.Nop())
+		_, err := recovery(context.Background(), req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.GetTransactions(ctx, req.(*extended.GetTransactionsRequest))
+		})
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+
+	t.Run("handles malformed transaction ID", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+
+		txID := txs[0].ID()
+		req := &extended.GetTransactionsRequest{Ids: [][]byte{txID[:], txID[:8]}}
+		_, err := s.GetTransactions(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "index 1")
+	})
+
+	t.Run("handles too many transaction IDs", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.MaxBatchSize = 2
+
+		var ids [][]byte
+		for _, tx := range txs {
+			txID := tx.ID()
+			ids = append(ids, txID[:])
+		}
+		req := &extended.GetTransactionsRequest{Ids: ids}
+		_, err := s.GetTransactions(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
 func TestServer_GetTransactionResult(t *testing.T) {
 	header := mocks.GenericHeader
 	blockID := header.ID()
@@ -145,112 +414,187 @@ func TestServer_GetTransactionResult(t *testing.T) {
 		assert.Equal(t, uint32(1), resp.StatusCode)
 		assert.Equal(t, convert.IdentifierToMessage(txID), resp.TransactionId)
 		assert.Equal(t, header.Height, resp.BlockHeight)
+		assert.Len(t, resp.Events, 4)
 	})
 
-	t.Run("nominal case with status executed and an error message", func(t *testing.T) {
+	t.Run("resolves duplicate inclusions to the first one", func(t *testing.T) {
 		t.Parallel()
 
-		height := header.Height + 999
-		failedResult := mocks.GenericResult(0)
-		failedResult.ErrorMessage = "dummy error"
-
+		included := map[uint64]bool{header.Height + 2: true, header.Height + 5: true}
 		index := mocks.BaselineReader(t)
-		index.ResultFunc = func(gotTxID flow.Identifier) (*flow.TransactionResult, error) {
-			assert.Equal(t, txID, gotTxID)
-
-			return failedResult, nil
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
 		}
-		index.HeightForTransactionFunc = func(gotTxID flow.Identifier) (uint64, error) {
-			assert.Equal(t, txID, gotTxID)
-
-			return height, nil
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height + 5, nil
 		}
-		index.HeaderFunc = func(gotHeight uint64) (*flow.Header, error) {
-			assert.Equal(t, height, gotHeight)
+		index.HeightForBlockFunc = func(gotBlockID flow.Identifier) (uint64, error) {
+			assert.Equal(t, tx.ReferenceBlockID, gotBlockID)
 
-			return header, nil
+			return header.Height, nil
 		}
-		index.EventsFunc = func(gotHeight uint64, types ...flow.EventType) ([]flow.Event, error) {
-			assert.Equal(t, height, gotHeight)
-			assert.Empty(t, types)
+		index.TransactionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Greater(t, height, header.Height)
+			assert.LessOrEqual(t, height, header.Height+10)
 
-			return mocks.GenericEvents(4), nil
+			if included[height] {
+				return []flow.Identifier{txID}, nil
+			}
+			return []flow.Identifier{}, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.ResolveDuplicates = true
+
+		duplicates := testutil.ToFloat64(duplicateTransactions)
 
 		req := &access.GetTransactionRequest{Id: txID[:]}
 		resp, err := s.GetTransactionResult(context.Background(), req)
 
 		require.NoError(t, err)
-		assert.Equal(t, failedResult.ErrorMessage, resp.ErrorMessage)
-		assert.Equal(t, blockID[:], resp.BlockId)
-		assert.Equal(t, entities.TransactionStatus_EXECUTED, resp.Status)
-		assert.Equal(t, uint32(0), resp.StatusCode)
+		assert.Equal(t, header.Height+2, resp.BlockHeight)
+		assert.Equal(t, duplicates+1, testutil.ToFloat64(duplicateTransactions))
 	})
 
-	t.Run("handles indexer error on result", func(t *testing.T) {
+	t.Run("prefers the first sealed inclusion", func(t *testing.T) {
 		t.Parallel()
 
+		// The block at the first inclusion only carries seals for earlier
+		// blocks, while the block at the second inclusion is sealed later on.
+		included := map[uint64]bool{header.Height + 2: true, header.Height + 5: true}
+		headers := make(map[uint64]*flow.Header)
+		for height := header.Height; height <= header.Height+10; height++ {
+			h := *header
+			h.Height = height
+			headers[height] = &h
+		}
+		sealed := mocks.GenericSeal(0)
+		sealed.BlockID = headers[header.Height+5].ID()
+		other := mocks.GenericSeal(1)
+		other.BlockID = headers[header.Height+1].ID()
+		seals := map[flow.Identifier]*flow.Seal{
+			sealed.ID(): sealed,
+			other.ID():  other,
+		}
+
 		index := mocks.BaselineReader(t)
-		index.ResultFunc = func(txID flow.Identifier) (*flow.TransactionResult, error) {
-			return nil, mocks.GenericError
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height + 5, nil
+		}
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height, nil
+		}
+		index.TransactionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			if included[height] {
+				return []flow.Identifier{txID}, nil
+			}
+			return []flow.Identifier{}, nil
+		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			return headers[height], nil
+		}
+		index.SealsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			switch height {
+			case header.Height + 2:
+				return []flow.Identifier{other.ID()}, nil
+			case header.Height + 7:
+				return []flow.Identifier{sealed.ID()}, nil
+			default:
+				return []flow.Identifier{}, nil
+			}
+		}
+		index.SealFunc = func(sealID flow.Identifier) (*flow.Seal, error) {
+			return seals[sealID], nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.ResolveDuplicates = true
 
 		req := &access.GetTransactionRequest{Id: txID[:]}
-		_, err := s.GetTransactionResult(context.Background(), req)
+		resp, err := s.GetTransactionResult(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		assert.Equal(t, header.Height+5, resp.BlockHeight)
 	})
 
-	t.Run("handles indexer error on HeightForTransaction", func(t *testing.T) {
+	t.Run("does not look up blocks after the indexed height", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
 		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
-			return 0, mocks.GenericError
+			return header.Height + 1, nil
+		}
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height, nil
+		}
+		index.TransactionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Equal(t, header.Height+1, height)
+
+			return []flow.Identifier{txID}, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.ResolveDuplicates = true
 
 		req := &access.GetTransactionRequest{Id: txID[:]}
-		_, err := s.GetTransactionResult(context.Background(), req)
+		resp, err := s.GetTransactionResult(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		assert.Equal(t, header.Height+1, resp.BlockHeight)
 	})
 
-	t.Run("handles indexer error on Header", func(t *testing.T) {
+	t.Run("uses indexed height unless duplicates are resolved", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.HeaderFunc = func(uint64) (*flow.Header, error) {
-			return nil, mocks.GenericError
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height + 5, nil
+		}
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			t.Error("transactions by height should not be looked up")
+
+			return nil, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
 		req := &access.GetTransactionRequest{Id: txID[:]}
-		_, err := s.GetTransactionResult(context.Background(), req)
+		resp, err := s.GetTransactionResult(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		assert.Equal(t, header.Height+5, resp.BlockHeight)
 	})
 
-	t.Run("handles indexer error on last", func(t *testing.T) {
+	t.Run("handles indexer failure on TransactionsByHeight when resolving duplicates", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
 		index.LastFunc = func() (uint64, error) {
-			return 0, mocks.GenericError
+			return header.Height + 10, nil
+		}
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height + 5, nil
+		}
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.ResolveDuplicates = true
 
 		req := &access.GetTransactionRequest{Id: txID[:]}
 		_, err := s.GetTransactionResult(context.Background(), req)
@@ -258,23 +602,297 @@ func TestServer_GetTransactionResult(t *testing.T) {
 		assert.Error(t, err)
 	})
 
-	t.Run("handles indexer error on events", func(t *testing.T) {
+	t.Run("nominal case with status executed and an error message", func(t *testing.T) {
 		t.Parallel()
 
+		height := header.Height + 999
+		failedResult := mocks.GenericResult(0)
+		failedResult.ErrorMessage = "dummy error"
+
 		index := mocks.BaselineReader(t)
-		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
-			return nil, mocks.GenericError
+		index.ResultFunc = func(gotTxID flow.Identifier) (*flow.TransactionResult, error) {
+			assert.Equal(t, txID, gotTxID)
+
+			return failedResult, nil
+		}
+		index.HeightForTransactionFunc = func(gotTxID flow.Identifier) (uint64, error) {
+			assert.Equal(t, txID, gotTxID)
+
+			return height, nil
+		}
+		index.HeaderFunc = func(gotHeight uint64) (*flow.Header, error) {
+			assert.Equal(t, height, gotHeight)
+
+			return header, nil
+		}
+		index.EventsFunc = func(gotHeight uint64, types ...flow.EventType) ([]flow.Event, error) {
+			assert.Equal(t, height, gotHeight)
+			assert.Empty(t, types)
+
+			return mocks.GenericEvents(4), nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
 		req := &access.GetTransactionRequest{Id: txID[:]}
-		_, err := s.GetTransactionResult(context.Background(), req)
-
-		assert.Error(t, err)
-	})
-}
+		resp, err := s.GetTransactionResult(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, failedResult.ErrorMessage, resp.ErrorMessage)
+		assert.Equal(t, blockID[:], resp.BlockId)
+		assert.Equal(t, entities.TransactionStatus_EXECUTED, resp.Status)
+		assert.Equal(t, uint32(0), resp.StatusCode)
+	})
+
+	t.Run("nominal case with events omitted", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ResultFunc = func(flow.Identifier) (*flow.TransactionResult, error) {
+			return result, nil
+		}
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height, nil
+		}
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return header, nil
+		}
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			t.Error("events should not be read")
+
+			return mocks.GenericEvents(4), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(OmitEventsHeader, "true"))
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		resp, err := s.GetTransactionResult(ctx, req)
+
+		require.NoError(t, err)
+		assert.Empty(t, resp.Events)
+		assert.Equal(t, result.ErrorMessage, resp.ErrorMessage)
+		assert.Equal(t, blockID[:], resp.BlockId)
+		assert.Equal(t, entities.TransactionStatus_SEALED, resp.Status)
+		assert.Equal(t, uint32(1), resp.StatusCode)
+	})
+
+	t.Run("handles invalid omit events header", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(OmitEventsHeader, "maybe"))
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(ctx, req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("handles indexer error on result", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ResultFunc = func(txID flow.Identifier) (*flow.TransactionResult, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer error on HeightForTransaction", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer error on Header", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer error on last", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer error on events", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("orders events by transaction and event index", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return shuffledEvents(), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		resp, err := s.GetTransactionResult(context.Background(), req)
+
+		require.NoError(t, err)
+		require.Len(t, resp.Events, 9)
+		assertEventOrder(t, resp.Events)
+	})
+
+	t.Run("sends error code of reverted transaction", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ResultFunc = func(flow.Identifier) (*flow.TransactionResult, error) {
+			reverted := flow.TransactionResult{
+				TransactionID: txID,
+				ErrorMessage:  "[Error Code: 1101] error caused by: 1 error occurred:\n\t* transaction execute failed: [Error Code: 1101] cadence runtime error: pre-condition failed",
+			}
+			return &reverted, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		var stream headerStream
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ErrorCodeHeader, "true"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1101"}, stream.header.Get(TransactionErrorCodeHeader))
+	})
+
+	t.Run("sends unknown failure code for error message without code", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ResultFunc = func(flow.Identifier) (*flow.TransactionResult, error) {
+			return &flow.TransactionResult{TransactionID: txID, ErrorMessage: "execution failed"}, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		var stream headerStream
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ErrorCodeHeader, "true"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2000"}, stream.header.Get(TransactionErrorCodeHeader))
+	})
+
+	t.Run("sends zero error code for successful transaction", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ResultFunc = func(flow.Identifier) (*flow.TransactionResult, error) {
+			return &flow.TransactionResult{TransactionID: txID}, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		var stream headerStream
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ErrorCodeHeader, "true"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0"}, stream.header.Get(TransactionErrorCodeHeader))
+	})
+
+	t.Run("does not send error code by default", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		var stream headerStream
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), &stream)
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(ctx, req)
+
+		require.NoError(t, err)
+		assert.Empty(t, stream.header)
+	})
+
+	t.Run("handles invalid error code header", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ErrorCodeHeader, "maybe"))
+
+		req := &access.GetTransactionRequest{Id: txID[:]}
+		_, err := s.GetTransactionResult(ctx, req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
 
 func TestServer_GetTransactionResultByIndex(t *testing.T) {
 	blockID := mocks.GenericBlock.BlockID
@@ -329,6 +947,69 @@ func TestServer_GetTransactionResultByIndex(t *testing.T) {
 		assert.Equal(t, resp.TransactionId, convert.IdentifierToMessage(txResults[0].TransactionID))
 		assert.Equal(t, resp.BlockHeight, header.Height)
 	})
+
+	t.Run("handles indexer failure on HeightForBlock", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionByIndexRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
+			Index:   0,
+		}
+
+		var err error
+		require.NotPanics(t, func() {
+			_, err = s.GetTransactionResultByIndex(context.Background(), req)
+		})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+
+	t.Run("handles unknown block", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, badger.ErrKeyNotFound
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionByIndexRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
+			Index:   0,
+		}
+		_, err := s.GetTransactionResultByIndex(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles unknown index", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionByIndexRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
+			Index:   0,
+		}
+		_, err := s.GetTransactionResultByIndex(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
 }
 
 func TestServer_GetTransactionResultsByBlockID(t *testing.T) {
@@ -383,35 +1064,33 @@ func TestServer_GetTransactionResultsByBlockID(t *testing.T) {
 			assert.Equal(t, resp.TransactionResults[i].TransactionId, convert.IdentifierToMessage(txResults[i].TransactionID))
 		}
 	})
-}
 
-func TestServer_GetTransactionsByBlockID(t *testing.T) {
-	txs := mocks.GenericTransactions(3)
-	blockID := mocks.GenericBlock.BlockID
-	header := mocks.GenericHeader
+	t.Run("resolves collections once per block", func(t *testing.T) {
+		t.Parallel()
 
-	var txIDs []flow.Identifier
-	txMap := make(map[flow.Identifier]*flow.TransactionBody)
-	for _, tx := range txs {
-		txMap[tx.ID()] = tx
-		txIDs = append(txIDs, tx.ID())
-	}
+		// Each transaction is in its own collection.
+		collIDs := mocks.GenericCollectionIDs(len(txIDs))
+		collections := make(map[flow.Identifier]*flow.LightCollection)
+		for i, collID := range collIDs {
+			collections[collID] = &flow.LightCollection{Transactions: []flow.Identifier{txIDs[i]}}
+		}
 
-	t.Run("nominal case", func(t *testing.T) {
-		t.Parallel()
+		var byHeightCalled, collCalled int
 		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
-			return header.Height, nil
-		}
-		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
-			return header, nil
-		}
-		index.TransactionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
 			return txIDs, nil
 		}
-		index.TransactionFunc = func(txID flow.Identifier) (*flow.TransactionBody, error) {
+		index.ResultFunc = func(txID flow.Identifier) (*flow.TransactionResult, error) {
 			return txMap[txID], nil
 		}
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			byHeightCalled++
+			return collIDs, nil
+		}
+		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
+			collCalled++
+			return collections[collID], nil
+		}
 
 		s := baselineServer(t)
 		s.index = index
@@ -419,395 +1098,479 @@ func TestServer_GetTransactionsByBlockID(t *testing.T) {
 		req := &access.GetTransactionsByBlockIDRequest{
 			BlockId: convert.IdentifierToMessage(blockID),
 		}
-		resp, err := s.GetTransactionsByBlockID(context.Background(), req)
+		resp, err := s.GetTransactionResultsByBlockID(context.Background(), req)
 
 		require.NoError(t, err)
-
-		// excludes the last transaction (system tx)
-		for i := 0; i < len(resp.Transactions)-1; i++ {
-			assert.Equal(t, resp.Transactions[i].ReferenceBlockId, convert.IdentifierToMessage(txs[i].ReferenceBlockID))
-			assert.Equal(t, resp.Transactions[i].Arguments, txs[i].Arguments)
+		require.Len(t, resp.TransactionResults, len(txIDs))
+		for i, result := range resp.TransactionResults {
+			assert.Equal(t, collIDs[i][:], result.CollectionId)
 		}
+		assert.Equal(t, 1, byHeightCalled)
+		assert.Equal(t, len(collIDs), collCalled)
 	})
-}
-
-func TestServer_GetEventsForBlockIDs(t *testing.T) {
-	header := mocks.GenericHeader
-	events := mocks.GenericEvents(6)
-	types := mocks.GenericEventTypes(1)
-	blockIDs := mocks.GenericBlockIDs(4)
-	blocks := map[flow.Identifier]uint64{
-		blockIDs[0]: mocks.GenericHeight,
-		blockIDs[1]: mocks.GenericHeight + 1,
-		blockIDs[2]: mocks.GenericHeight + 2,
-		blockIDs[3]: mocks.GenericHeight + 3,
-	}
 
-	t.Run("nominal case with event type", func(t *testing.T) {
+	t.Run("leaves out collection ID of transactions without collection", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
-			assert.Contains(t, blocks, blockID)
-
-			return blocks[blockID], nil
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
 		}
-		index.EventsFunc = func(height uint64, gotTypes ...flow.EventType) ([]flow.Event, error) {
-			assert.InDelta(t, header.Height, height, float64(len(blocks)))
-			assert.Equal(t, types, gotTypes)
-
-			return events, nil
+		index.ResultFunc = func(txID flow.Identifier) (*flow.TransactionResult, error) {
+			return txMap[txID], nil
 		}
-		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
-			assert.InDelta(t, header.Height, height, float64(len(blocks)))
-
-			return header, nil
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		var ids [][]byte
-		for _, id := range blockIDs {
-			ids = append(ids, id[:])
-		}
-		req := &access.GetEventsForBlockIDsRequest{
-			Type:     string(types[0]),
-			BlockIds: ids,
+		req := &access.GetTransactionsByBlockIDRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
 		}
-		resp, err := s.GetEventsForBlockIDs(context.Background(), req)
+		resp, err := s.GetTransactionResultsByBlockID(context.Background(), req)
 
 		require.NoError(t, err)
-		assert.Len(t, resp.Results, len(blockIDs))
-		for _, block := range resp.Results {
-			assert.Len(t, block.Events, len(events))
+		require.Len(t, resp.TransactionResults, len(txIDs))
+		for _, result := range resp.TransactionResults {
+			assert.Empty(t, result.CollectionId)
 		}
 	})
 
-	t.Run("nominal case without event type", func(t *testing.T) {
+	t.Run("handles indexer failure on Collection", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
-			assert.Contains(t, blocks, blockID)
+		index.CollectionFunc = func(flow.Identifier) (*flow.LightCollection, error) {
+			return nil, mocks.GenericError
+		}
 
-			return blocks[blockID], nil
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetTransactionsByBlockIDRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
 		}
-		index.EventsFunc = func(height uint64, types ...flow.EventType) ([]flow.Event, error) {
-			assert.InDelta(t, header.Height, height, float64(len(blocks)))
-			assert.Empty(t, types)
+		_, err := s.GetTransactionResultsByBlockID(context.Background(), req)
 
-			return events, nil
+		assert.Error(t, err)
+	})
+
+	t.Run("uses the height of the requested block when duplicates are resolved", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height, nil
 		}
-		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
-			assert.InDelta(t, header.Height, height, float64(len(blocks)))
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
+		}
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			t.Error("height for transaction should not be looked up")
 
-			return header, nil
+			return header.Height + 5, nil
+		}
+		index.ResultFunc = func(txID flow.Identifier) (*flow.TransactionResult, error) {
+			return txMap[txID], nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.ResolveDuplicates = true
 
-		var ids [][]byte
-		for _, id := range blockIDs {
-			ids = append(ids, id[:])
-		}
-		req := &access.GetEventsForBlockIDsRequest{
-			Type:     "",
-			BlockIds: ids,
-		}
-		resp, err := s.GetEventsForBlockIDs(context.Background(), req)
+		req := &access.GetTransactionsByBlockIDRequest{BlockId: blockID[:]}
+		resp, err := s.GetTransactionResultsByBlockID(context.Background(), req)
 
 		require.NoError(t, err)
-		assert.Len(t, resp.Results, len(blockIDs))
-		for _, block := range resp.Results {
-			assert.Len(t, block.Events, len(events))
+		require.Len(t, resp.TransactionResults, len(txIDs))
+		for _, result := range resp.TransactionResults {
+			assert.Equal(t, header.Height, result.BlockHeight)
 		}
 	})
+}
 
-	t.Run("handles indexer error on HeightForBlock", func(t *testing.T) {
-		t.Parallel()
+func TestServer_GetFilteredTransactionResultsByBlockID(t *testing.T) {
+	blockID := mocks.GenericBlock.BlockID
+	header := mocks.GenericHeader
+	txResults := mocks.GenericResults(4)
+	txResults[1].ErrorMessage = "execution reverted"
+	txResults[3].ErrorMessage = "out of gas"
 
-		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
-			return 0, mocks.GenericError
-		}
+	var txIDs []flow.Identifier
+	txMap := make(map[flow.Identifier]*flow.TransactionResult)
+	for _, tx := range txResults {
+		txMap[tx.ID()] = tx
+		txIDs = append(txIDs, tx.ID())
+	}
+
+	index := mocks.BaselineReader(t)
+	index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+		return header.Height, nil
+	}
+	index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+		return txIDs, nil
+	}
+	index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+		return header.Height, nil
+	}
+	index.ResultFunc = func(txID flow.Identifier) (*flow.TransactionResult, error) {
+		return txMap[txID], nil
+	}
+
+	t.Run("returns only failed transactions", func(t *testing.T) {
+		t.Parallel()
 
 		s := baselineServer(t)
 		s.index = index
 
-		var ids [][]byte
-		for _, id := range blockIDs {
-			ids = append(ids, id[:])
-		}
-		req := &access.GetEventsForBlockIDsRequest{
-			BlockIds: ids,
-			Type:     string(mocks.GenericEventType(0)),
+		req := &extended.GetFilteredTransactionResultsByBlockIDRequest{
+			BlockId:    convert.IdentifierToMessage(blockID),
+			FailedOnly: true,
 		}
-		_, err := s.GetEventsForBlockIDs(context.Background(), req)
+		resp, err := s.GetFilteredTransactionResultsByBlockID(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		require.Len(t, resp.TransactionResults, 2)
+		assert.Equal(t, convert.IdentifierToMessage(txResults[1].TransactionID), resp.TransactionResults[0].TransactionId)
+		assert.Equal(t, txResults[1].ErrorMessage, resp.TransactionResults[0].ErrorMessage)
+		assert.Equal(t, convert.IdentifierToMessage(txResults[3].TransactionID), resp.TransactionResults[1].TransactionId)
+		assert.Equal(t, txResults[3].ErrorMessage, resp.TransactionResults[1].ErrorMessage)
 	})
 
-	t.Run("handles indexer error on Events", func(t *testing.T) {
+	t.Run("returns all transactions by default", func(t *testing.T) {
 		t.Parallel()
 
-		index := mocks.BaselineReader(t)
-		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
-			return nil, mocks.GenericError
-		}
-
 		s := baselineServer(t)
 		s.index = index
 
-		var ids [][]byte
-		for _, id := range blockIDs {
-			ids = append(ids, id[:])
-		}
-		req := &access.GetEventsForBlockIDsRequest{
-			BlockIds: ids,
-			Type:     string(mocks.GenericEventType(0)),
+		req := &extended.GetFilteredTransactionResultsByBlockIDRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
 		}
-		_, err := s.GetEventsForBlockIDs(context.Background(), req)
+		resp, err := s.GetFilteredTransactionResultsByBlockID(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		require.Len(t, resp.TransactionResults, len(txResults))
+		for i, result := range txResults {
+			assert.Equal(t, convert.IdentifierToMessage(result.TransactionID), resp.TransactionResults[i].TransactionId)
+		}
 	})
 
-	t.Run("handles indexer error on Header", func(t *testing.T) {
+	t.Run("handles indexer failure on Result", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+		index.ResultFunc = func(flow.Identifier) (*flow.TransactionResult, error) {
 			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		var ids [][]byte
-		for _, id := range blockIDs {
-			ids = append(ids, id[:])
+		req := &extended.GetFilteredTransactionResultsByBlockIDRequest{
+			BlockId:    convert.IdentifierToMessage(blockID),
+			FailedOnly: true,
 		}
-		req := &access.GetEventsForBlockIDsRequest{
-			BlockIds: ids,
-			Type:     string(mocks.GenericEventType(0)),
-		}
-		_, err := s.GetEventsForBlockIDs(context.Background(), req)
+		_, err := s.GetFilteredTransactionResultsByBlockID(context.Background(), req)
 
 		assert.Error(t, err)
 	})
 }
 
-func TestServer_GetEventsForHeightRange(t *testing.T) {
+func TestServer_GetTransactionsByBlockID(t *testing.T) {
+	txs := mocks.GenericTransactions(3)
+	blockID := mocks.GenericBlock.BlockID
 	header := mocks.GenericHeader
-	events := mocks.GenericEvents(6)
-	types := mocks.GenericEventTypes(1)
+
+	var txIDs []flow.Identifier
+	txMap := make(map[flow.Identifier]*flow.TransactionBody)
+	for _, tx := range txs {
+		txMap[tx.ID()] = tx
+		txIDs = append(txIDs, tx.ID())
+	}
 
 	t.Run("nominal case", func(t *testing.T) {
 		t.Parallel()
-
 		index := mocks.BaselineReader(t)
-		index.EventsFunc = func(h uint64, gotTypes ...flow.EventType) ([]flow.Event, error) {
-			// Expect height to be between GenericHeight and GenericHeight + 3 since there are four
-			// given blockIDs.
-			assert.InDelta(t, header.Height, h, 3)
-			assert.Equal(t, types, gotTypes)
-
-			return events, nil
+		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
+			return header.Height, nil
 		}
-		index.HeaderFunc = func(h uint64) (*flow.Header, error) {
-			// Expect height to be between GenericHeight and GenericHeight + 3 since there are four
-			// given blockIDs.
-			assert.InDelta(t, header.Height, h, 3)
-
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
 			return header, nil
 		}
+		index.TransactionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
+		}
+		index.TransactionFunc = func(txID flow.Identifier) (*flow.TransactionBody, error) {
+			return txMap[txID], nil
+		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetEventsForHeightRangeRequest{
-			StartHeight: header.Height,
-			EndHeight:   header.Height + 3,
-			Type:        string(types[0]),
+		req := &access.GetTransactionsByBlockIDRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
 		}
-		resp, err := s.GetEventsForHeightRange(context.Background(), req)
+		resp, err := s.GetTransactionsByBlockID(context.Background(), req)
 
 		require.NoError(t, err)
-		assert.Len(t, resp.Results, 4)
-		for _, block := range resp.Results {
-			assert.Len(t, block.Events, len(events))
+
+		// excludes the last transaction (system tx)
+		for i := 0; i < len(resp.Transactions)-1; i++ {
+			assert.Equal(t, resp.Transactions[i].ReferenceBlockId, convert.IdentifierToMessage(txs[i].ReferenceBlockID))
+			assert.Equal(t, resp.Transactions[i].Arguments, txs[i].Arguments)
 		}
+		assert.Len(t, resp.Transactions, len(txs)+1)
 	})
 
-	t.Run("nominal case without event type", func(t *testing.T) {
+	t.Run("excludes system transaction", func(t *testing.T) {
 		t.Parallel()
-
 		index := mocks.BaselineReader(t)
-		index.EventsFunc = func(h uint64, types ...flow.EventType) ([]flow.Event, error) {
-			// Expect height to be between GenericHeight and GenericHeight + 3 since there are four
-			// given blockIDs.
-			assert.InDelta(t, header.Height, h, 3)
-			assert.Empty(t, types)
-
-			return events, nil
+		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
+			return header.Height, nil
 		}
-		index.HeaderFunc = func(h uint64) (*flow.Header, error) {
-			// Expect height to be between GenericHeight and GenericHeight + 3 since there are four
-			// given blockIDs.
-			assert.InDelta(t, header.Height, h, 3)
-
-			return header, nil
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			t.Fatal("unexpected call to Header")
+			return nil, nil
+		}
+		index.TransactionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
+		}
+		index.TransactionFunc = func(txID flow.Identifier) (*flow.TransactionBody, error) {
+			return txMap[txID], nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.IncludeSystemTx = false
 
-		req := &access.GetEventsForHeightRangeRequest{
-			StartHeight: header.Height,
-			EndHeight:   header.Height + 3,
-			Type:        "",
+		req := &access.GetTransactionsByBlockIDRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
 		}
-		resp, err := s.GetEventsForHeightRange(context.Background(), req)
-
-		assert.NoError(t, err)
+		resp, err := s.GetTransactionsByBlockID(context.Background(), req)
 
-		assert.Len(t, resp.Results, 4)
-		for _, block := range resp.Results {
-			assert.Len(t, block.Events, len(events))
+		require.NoError(t, err)
+		require.Len(t, resp.Transactions, len(txs))
+		for i, tx := range txs {
+			assert.Equal(t, convert.IdentifierToMessage(tx.ReferenceBlockID), resp.Transactions[i].ReferenceBlockId)
 		}
 	})
 
-	t.Run("handles indexer error on Header", func(t *testing.T) {
+	t.Run("skips system transaction for unknown chain", func(t *testing.T) {
 		t.Parallel()
 
+		custom := *header
+		custom.ChainID = "flow-custom"
 		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
+			return header.Height, nil
+		}
 		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
-			return nil, mocks.GenericError
+			return &custom, nil
+		}
+		index.TransactionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
+		}
+		index.TransactionFunc = func(txID flow.Identifier) (*flow.TransactionBody, error) {
+			return txMap[txID], nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetEventsForHeightRangeRequest{
-			StartHeight: header.Height,
-			EndHeight:   header.Height + 3,
-			Type:        string(types[0]),
+		req := &access.GetTransactionsByBlockIDRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
 		}
-		_, err := s.GetEventsForHeightRange(context.Background(), req)
+		resp, err := s.GetTransactionsByBlockID(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		assert.Len(t, resp.Transactions, len(txs))
 	})
 
-	t.Run("handles indexer error on Events", func(t *testing.T) {
+	t.Run("builds system transaction for overridden chain", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
-			return nil, mocks.GenericError
+		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
+			return header.Height, nil
+		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			t.Fatal("unexpected call to Header")
+			return nil, nil
+		}
+		index.TransactionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
+		}
+		index.TransactionFunc = func(txID flow.Identifier) (*flow.TransactionBody, error) {
+			return txMap[txID], nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.SystemTxChain = flow.Emulator
 
-		req := &access.GetEventsForHeightRangeRequest{
-			StartHeight: header.Height,
-			EndHeight:   header.Height + 3,
-			Type:        string(types[0]),
+		req := &access.GetTransactionsByBlockIDRequest{
+			BlockId: convert.IdentifierToMessage(blockID),
 		}
-		_, err := s.GetEventsForHeightRange(context.Background(), req)
+		resp, err := s.GetTransactionsByBlockID(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		require.Len(t, resp.Transactions, len(txs)+1)
+
+		systemTx, err := blueprints.SystemChunkTransaction(flow.Emulator.Chain())
+		require.NoError(t, err)
+		assert.Equal(t, convert.TransactionToMessage(*systemTx), resp.Transactions[len(txs)])
 	})
 }
 
-func TestServer_GetNetworkParameters(t *testing.T) {
+func TestServer_GetBlockTransactions(t *testing.T) {
+	blockID := mocks.GenericBlock.BlockID
 	header := mocks.GenericHeader
+	txs := mocks.GenericTransactions(5)
+	collIDs := mocks.GenericCollectionIDs(2)
 
-	t.Run("nominal case", func(t *testing.T) {
-		t.Parallel()
+	var txIDs []flow.Identifier
+	txMap := make(map[flow.Identifier]*flow.TransactionBody)
+	for _, tx := range txs {
+		txMap[tx.ID()] = tx
+		txIDs = append(txIDs, tx.ID())
+	}
+	collections := map[flow.Identifier]*flow.LightCollection{
+		collIDs[0]: {Transactions: txIDs[:2]},
+		collIDs[1]: {Transactions: txIDs[2:]},
+	}
 
+	baselineIndex := func(t *testing.T) *mocks.Reader {
 		index := mocks.BaselineReader(t)
-		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
-			assert.Equal(t, header.Height, mocks.GenericHeight)
-
-			return header, nil
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return header.Height, nil
+		}
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
+		}
+		index.TransactionFunc = func(txID flow.Identifier) (*flow.TransactionBody, error) {
+			return txMap[txID], nil
+		}
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return collIDs, nil
+		}
+		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
+			return collections[collID], nil
+		}
+		index.ResultFunc = func(txID flow.Identifier) (*flow.TransactionResult, error) {
+			return &flow.TransactionResult{TransactionID: txID}, nil
 		}
+		return index
+	}
+
+	t.Run("annotates multi-collection block", func(t *testing.T) {
+		t.Parallel()
 
 		s := baselineServer(t)
-		s.index = index
+		s.index = baselineIndex(t)
 
-		req := &access.GetNetworkParametersRequest{}
-		resp, err := s.GetNetworkParameters(context.Background(), req)
+		req := &extended.GetBlockTransactionsRequest{
+			BlockId:         blockID[:],
+			IncludeOrdering: true,
+		}
+		resp, err := s.GetBlockTransactions(context.Background(), req)
 
 		require.NoError(t, err)
-		assert.Equal(t, archive.FlowTestnet.String(), resp.ChainId)
+		require.Len(t, resp.Transactions, len(txs))
+		for i, tx := range resp.Transactions {
+			assert.Equal(t, txs[i].ReferenceBlockID[:], tx.Transaction.ReferenceBlockId)
+			assert.Equal(t, uint32(i), tx.Index)
+			assert.Nil(t, tx.Result)
+		}
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, collIDs[0][:], resp.Transactions[i].CollectionId)
+		}
+		for i := 2; i < len(txs); i++ {
+			assert.Equal(t, collIDs[1][:], resp.Transactions[i].CollectionId)
+		}
 	})
 
-	t.Run("handles indexer failure on first", func(t *testing.T) {
+	t.Run("places system transaction after collections", func(t *testing.T) {
 		t.Parallel()
 
-		index := mocks.BaselineReader(t)
-		index.FirstFunc = func() (uint64, error) {
-			return 0, mocks.GenericError
+		// The last transaction is not part of any collection, like the system
+		// transaction of a block.
+		index := baselineIndex(t)
+		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
+			if collID == collIDs[1] {
+				return &flow.LightCollection{Transactions: txIDs[2 : len(txIDs)-1]}, nil
+			}
+			return collections[collID], nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetNetworkParametersRequest{}
-		_, err := s.GetNetworkParameters(context.Background(), req)
+		req := &extended.GetBlockTransactionsRequest{
+			BlockId:         blockID[:],
+			IncludeResults:  true,
+			IncludeOrdering: true,
+		}
+		resp, err := s.GetBlockTransactions(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		require.Len(t, resp.Transactions, len(txs))
+		for i, tx := range resp.Transactions {
+			assert.Equal(t, uint32(i), tx.Index)
+			require.NotNil(t, tx.Result)
+		}
+		assert.Equal(t, collIDs[1][:], resp.Transactions[len(txs)-2].CollectionId)
+		assert.Empty(t, resp.Transactions[len(txs)-1].CollectionId)
 	})
 
-	t.Run("handles indexer failure on header", func(t *testing.T) {
+	t.Run("includes results", func(t *testing.T) {
 		t.Parallel()
 
-		index := mocks.BaselineReader(t)
-		index.HeaderFunc = func(uint64) (*flow.Header, error) {
-			return nil, mocks.GenericError
-		}
-
 		s := baselineServer(t)
-		s.index = index
+		s.index = baselineIndex(t)
 
-		req := &access.GetNetworkParametersRequest{}
-		_, err := s.GetNetworkParameters(context.Background(), req)
+		req := &extended.GetBlockTransactionsRequest{
+			BlockId:        blockID[:],
+			IncludeResults: true,
+		}
+		resp, err := s.GetBlockTransactions(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		require.Len(t, resp.Transactions, len(txs))
+		for i, tx := range resp.Transactions {
+			require.NotNil(t, tx.Result)
+			assert.Equal(t, txIDs[i][:], tx.Result.TransactionId)
+		}
 	})
-}
-
-func TestServer_GetCollectionByID(t *testing.T) {
-	collection := mocks.GenericCollection(0)
-	collID := collection.ID()
 
-	t.Run("nominal case", func(t *testing.T) {
+	t.Run("skips collection reads by default", func(t *testing.T) {
 		t.Parallel()
 
-		index := mocks.BaselineReader(t)
-		index.CollectionFunc = func(gotCollID flow.Identifier) (*flow.LightCollection, error) {
-			assert.Equal(t, collID, gotCollID)
-
-			return collection, nil
+		index := baselineIndex(t)
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			t.Fatal("unexpected call to CollectionsByHeight")
+			return nil, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetCollectionByIDRequest{Id: collID[:]}
-		resp, err := s.GetCollectionByID(context.Background(), req)
+		req := &extended.GetBlockTransactionsRequest{BlockId: blockID[:]}
+		resp, err := s.GetBlockTransactions(context.Background(), req)
 
 		require.NoError(t, err)
-		require.NotNil(t, resp.Collection)
-		assert.Len(t, resp.Collection.TransactionIds, 2)
+		require.Len(t, resp.Transactions, len(txs))
+		for _, tx := range resp.Transactions {
+			assert.Empty(t, tx.CollectionId)
+			assert.Nil(t, tx.Result)
+		}
 	})
 
-	t.Run("handles indexer failure on collection", func(t *testing.T) {
+	t.Run("handles indexer failure on Collection", func(t *testing.T) {
 		t.Parallel()
 
-		index := mocks.BaselineReader(t)
+		index := baselineIndex(t)
 		index.CollectionFunc = func(flow.Identifier) (*flow.LightCollection, error) {
 			return nil, mocks.GenericError
 		}
@@ -815,900 +1578,5367 @@ func TestServer_GetCollectionByID(t *testing.T) {
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetCollectionByIDRequest{Id: collID[:]}
-		_, err := s.GetCollectionByID(context.Background(), req)
+		req := &extended.GetBlockTransactionsRequest{
+			BlockId:         blockID[:],
+			IncludeOrdering: true,
+		}
+		_, err := s.GetBlockTransactions(context.Background(), req)
 
 		assert.Error(t, err)
 	})
 }
 
-func TestServer_GetAccount(t *testing.T) {
-	account := mocks.GenericAccount
+func TestServer_GetEventsForBlockIDs(t *testing.T) {
+	header := mocks.GenericHeader
+	events := mocks.GenericEvents(6)
+	types := mocks.GenericEventTypes(1)
+	blockIDs := mocks.GenericBlockIDs(4)
+	blocks := map[flow.Identifier]uint64{
+		blockIDs[0]: mocks.GenericHeight,
+		blockIDs[1]: mocks.GenericHeight + 1,
+		blockIDs[2]: mocks.GenericHeight + 2,
+		blockIDs[3]: mocks.GenericHeight + 3,
+	}
 
-	t.Run("nominal case", func(t *testing.T) {
+	t.Run("nominal case with event type", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.ValuesFunc = func(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
-			assert.Equal(t, mocks.GenericHeight, height)
+		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
+			assert.Contains(t, blocks, blockID)
 
-			return mocks.GenericLedgerValues(4), nil
+			return blocks[blockID], nil
 		}
-		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
-			assert.Equal(t, mocks.GenericHeight, height)
+		index.EventsFunc = func(height uint64, gotTypes ...flow.EventType) ([]flow.Event, error) {
+			assert.InDelta(t, header.Height, height, float64(len(blocks)))
+			assert.Equal(t, types, gotTypes)
 
-			return mocks.GenericHeader, nil
+			return events, nil
 		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.InDelta(t, header.Height, height, float64(len(blocks)))
 
-		invoker := mocks.BaselineInvoker(t)
-		invoker.AccountFunc = func(height uint64, address flow.Address) (*flow.Account, error) {
-			assert.Equal(t, mocks.GenericHeight, height)
-			assert.Equal(t, account.Address, address)
-
-			return &account, nil
+			return header, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
-		s.invoker = invoker
 
-		req := &access.GetAccountRequest{Address: account.Address[:]}
-		resp, err := s.GetAccount(context.Background(), req)
+		var ids [][]byte
+		for _, id := range blockIDs {
+			ids = append(ids, id[:])
+		}
+		req := &access.GetEventsForBlockIDsRequest{
+			Type:     string(types[0]),
+			BlockIds: ids,
+		}
+		resp, err := s.GetEventsForBlockIDs(context.Background(), req)
 
 		require.NoError(t, err)
-		require.NotNil(t, resp.Account)
-		assert.Equal(t, account.Address[:], resp.Account.Address)
-		assert.Equal(t, account.Balance, resp.Account.Balance)
+		assert.Len(t, resp.Results, len(blockIDs))
+		for _, block := range resp.Results {
+			assert.Len(t, block.Events, len(events))
+		}
 	})
 
-	t.Run("handles indexer failure on Last", func(t *testing.T) {
+	t.Run("nominal case without event type", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.LastFunc = func() (uint64, error) {
-			return 0, mocks.GenericError
+		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
+			assert.Contains(t, blocks, blockID)
+
+			return blocks[blockID], nil
+		}
+		index.EventsFunc = func(height uint64, types ...flow.EventType) ([]flow.Event, error) {
+			assert.InDelta(t, header.Height, height, float64(len(blocks)))
+			assert.Empty(t, types)
+
+			return events, nil
+		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.InDelta(t, header.Height, height, float64(len(blocks)))
+
+			return header, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetAccountRequest{Address: account.Address[:]}
-		_, err := s.GetAccount(context.Background(), req)
+		var ids [][]byte
+		for _, id := range blockIDs {
+			ids = append(ids, id[:])
+		}
+		req := &access.GetEventsForBlockIDsRequest{
+			Type:     "",
+			BlockIds: ids,
+		}
+		resp, err := s.GetEventsForBlockIDs(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		assert.Len(t, resp.Results, len(blockIDs))
+		for _, block := range resp.Results {
+			assert.Len(t, block.Events, len(events))
+		}
 	})
 
-	t.Run("handles invoker failure on GetAccount", func(t *testing.T) {
+	t.Run("handles indexer error on HeightForBlock", func(t *testing.T) {
 		t.Parallel()
 
-		invoker := mocks.BaselineInvoker(t)
-		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
-			return nil, mocks.GenericError
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, mocks.GenericError
 		}
 
 		s := baselineServer(t)
-		s.invoker = invoker
-
-		req := &access.GetAccountRequest{Address: account.Address[:]}
-		_, err := s.GetAccount(context.Background(), req)
-
-		assert.Error(t, err)
-	})
-}
-
-func TestServer_GetAccountAtLatestBlock(t *testing.T) {
-	account := mocks.GenericAccount
-
-	t.Run("nominal case", func(t *testing.T) {
-		t.Parallel()
-
-		index := mocks.BaselineReader(t)
-		index.ValuesFunc = func(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
-			assert.Equal(t, mocks.GenericHeight, height)
+		s.index = index
 
-			return mocks.GenericLedgerValues(4), nil
+		var ids [][]byte
+		for _, id := range blockIDs {
+			ids = append(ids, id[:])
 		}
-
-		invoker := mocks.BaselineInvoker(t)
-		invoker.AccountFunc = func(height uint64, address flow.Address) (*flow.Account, error) {
-			assert.Equal(t, mocks.GenericHeight, height)
-			assert.Equal(t, account.Address, address)
-
-			return &account, nil
+		req := &access.GetEventsForBlockIDsRequest{
+			BlockIds: ids,
+			Type:     string(mocks.GenericEventType(0)),
 		}
+		_, err := s.GetEventsForBlockIDs(context.Background(), req)
 
-		s := baselineServer(t)
-		s.index = index
-		s.invoker = invoker
-
-		req := &access.GetAccountAtLatestBlockRequest{Address: account.Address[:]}
-		resp, err := s.GetAccountAtLatestBlock(context.Background(), req)
-
-		require.NoError(t, err)
-		require.NotNil(t, resp.Account)
-		assert.Equal(t, account.Address[:], resp.Account.Address)
-		assert.Equal(t, account.Balance, resp.Account.Balance)
+		assert.Error(t, err)
 	})
 
-	t.Run("handles indexer failure on Last", func(t *testing.T) {
+	t.Run("handles indexer error on Events", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.LastFunc = func() (uint64, error) {
-			return 0, mocks.GenericError
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetAccountAtLatestBlockRequest{Address: account.Address[:]}
-		_, err := s.GetAccountAtLatestBlock(context.Background(), req)
+		var ids [][]byte
+		for _, id := range blockIDs {
+			ids = append(ids, id[:])
+		}
+		req := &access.GetEventsForBlockIDsRequest{
+			BlockIds: ids,
+			Type:     string(mocks.GenericEventType(0)),
+		}
+		_, err := s.GetEventsForBlockIDs(context.Background(), req)
 
 		assert.Error(t, err)
 	})
 
-	t.Run("handles invoker failure on GetAccount", func(t *testing.T) {
+	t.Run("handles indexer error on Header", func(t *testing.T) {
 		t.Parallel()
 
-		invoker := mocks.BaselineInvoker(t)
-		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
 			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
-		s.invoker = invoker
+		s.index = index
 
-		req := &access.GetAccountAtLatestBlockRequest{Address: account.Address[:]}
-		_, err := s.GetAccountAtLatestBlock(context.Background(), req)
+		var ids [][]byte
+		for _, id := range blockIDs {
+			ids = append(ids, id[:])
+		}
+		req := &access.GetEventsForBlockIDsRequest{
+			BlockIds: ids,
+			Type:     string(mocks.GenericEventType(0)),
+		}
+		_, err := s.GetEventsForBlockIDs(context.Background(), req)
 
 		assert.Error(t, err)
 	})
-}
-
-func TestServer_GetAccountAtBlockHeight(t *testing.T) {
-	account := mocks.GenericAccount
 
-	t.Run("nominal case", func(t *testing.T) {
+	t.Run("handles block IDs up to the maximum", func(t *testing.T) {
 		t.Parallel()
 
-		height := mocks.GenericHeight + 999
-
 		index := mocks.BaselineReader(t)
-		index.ValuesFunc = func(gotHeight uint64, paths []ledger.Path) ([]ledger.Value, error) {
-			assert.Equal(t, height, gotHeight)
-
-			return mocks.GenericLedgerValues(4), nil
+		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
+			return blocks[blockID], nil
 		}
-
-		invoker := mocks.BaselineInvoker(t)
-		invoker.AccountFunc = func(gotHeight uint64, address flow.Address) (*flow.Account, error) {
-			assert.Equal(t, height, gotHeight)
-			assert.Equal(t, account.Address, address)
-
-			return &account, nil
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return events, nil
+		}
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return header, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
-		s.invoker = invoker
+		s.cfg.MaxBlockIDs = uint(len(blockIDs))
 
-		req := &access.GetAccountAtBlockHeightRequest{
-			BlockHeight: height,
-			Address:     account.Address[:],
+		var ids [][]byte
+		for _, id := range blockIDs {
+			ids = append(ids, id[:])
 		}
-		resp, err := s.GetAccountAtBlockHeight(context.Background(), req)
+		req := &access.GetEventsForBlockIDsRequest{BlockIds: ids}
+		resp, err := s.GetEventsForBlockIDs(context.Background(), req)
 
 		require.NoError(t, err)
-		assert.NotNil(t, resp.Account)
-		assert.Equal(t, account.Address[:], resp.Account.Address)
-		assert.Equal(t, account.Balance, resp.Account.Balance)
+		assert.Len(t, resp.Results, len(blockIDs))
 	})
 
-	t.Run("handles invoker failure on GetAccount", func(t *testing.T) {
+	t.Run("handles too many block IDs", func(t *testing.T) {
 		t.Parallel()
 
-		invoker := mocks.BaselineInvoker(t)
-		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
-			return nil, mocks.GenericError
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			t.Fatal("unexpected call to HeightForBlock")
+			return 0, nil
 		}
 
 		s := baselineServer(t)
-		s.invoker = invoker
+		s.index = index
+		s.cfg.MaxBlockIDs = uint(len(blockIDs)) - 1
 
-		req := &access.GetAccountAtBlockHeightRequest{
-			BlockHeight: mocks.GenericHeight,
-			Address:     account.Address[:],
+		var ids [][]byte
+		for _, id := range blockIDs {
+			ids = append(ids, id[:])
 		}
-		_, err := s.GetAccountAtBlockHeight(context.Background(), req)
+		req := &access.GetEventsForBlockIDsRequest{BlockIds: ids}
+		_, err := s.GetEventsForBlockIDs(context.Background(), req)
 
-		assert.Error(t, err)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
 	})
-}
-
-func TestServer_ExecuteScriptAtBlockHeight(t *testing.T) {
-	cadenceValue := cadence.NewUInt64(mocks.GenericHeight)
-	cadenceValueBytes, err := json.Encode(cadenceValue)
-	require.NoError(t, err)
-
-	genericAmountBytes, err := json.Encode(mocks.GenericAmount(0))
-	require.NoError(t, err)
 
-	t.Run("nominal case", func(t *testing.T) {
+	t.Run("handles malformed block ID", func(t *testing.T) {
 		t.Parallel()
 
-		invoker := mocks.BaselineInvoker(t)
-		invoker.ScriptFunc = func(height uint64, script []byte, parameters []cadence.Value) (cadence.Value, error) {
-			assert.Equal(t, mocks.GenericHeight, height)
-			assert.Equal(t, mocks.GenericBytes, script)
-			assert.Equal(t, []cadence.Value{cadenceValue}, parameters)
-
-			return mocks.GenericAmount(0), nil
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			t.Fatal("unexpected call to HeightForBlock")
+			return 0, nil
 		}
 
 		s := baselineServer(t)
-		s.invoker = invoker
+		s.index = index
 
-		req := &access.ExecuteScriptAtBlockHeightRequest{
-			BlockHeight: mocks.GenericHeight,
-			Script:      mocks.GenericBytes,
-			Arguments:   [][]byte{cadenceValueBytes},
+		var ids [][]byte
+		for _, id := range blockIDs {
+			ids = append(ids, id[:])
 		}
-		resp, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+		ids[2] = ids[2][:16]
+		req := &access.GetEventsForBlockIDsRequest{
+			BlockIds: ids,
+			Type:     string(mocks.GenericEventType(0)),
+		}
+		_, err := s.GetEventsForBlockIDs(context.Background(), req)
 
-		require.NoError(t, err)
-		assert.Equal(t, genericAmountBytes, resp.Value)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "index 2")
 	})
 
-	t.Run("handles invoker failure", func(t *testing.T) {
+	t.Run("returns events in the same order across calls", func(t *testing.T) {
 		t.Parallel()
 
-		invoker := mocks.BaselineInvoker(t)
-		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
-			return nil, mocks.GenericError
-		}
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = varyingEvents()
 
 		s := baselineServer(t)
-		s.invoker = invoker
+		s.index = index
 
-		req := &access.ExecuteScriptAtBlockHeightRequest{
-			BlockHeight: mocks.GenericHeight,
-			Script:      mocks.GenericBytes,
-			Arguments:   [][]byte{cadenceValueBytes},
+		req := &access.GetEventsForBlockIDsRequest{
+			BlockIds: [][]byte{mocks.GenericBlock.BlockID[:]},
 		}
-		_, err = s.ExecuteScriptAtBlockHeight(context.Background(), req)
+		first, err := s.GetEventsForBlockIDs(context.Background(), req)
+		require.NoError(t, err)
+		second, err := s.GetEventsForBlockIDs(context.Background(), req)
+		require.NoError(t, err)
 
-		assert.Error(t, err)
+		require.Len(t, first.Results, 1)
+		require.Len(t, first.Results[0].Events, 9)
+		assertEventOrder(t, first.Results[0].Events)
+		assert.Equal(t, first.Results, second.Results)
 	})
 }
 
-func TestServer_ExecuteScriptAtBlockID(t *testing.T) {
-	blockID := mocks.GenericHeader.ID()
-
-	cadenceValue := cadence.NewUInt64(mocks.GenericHeight)
-	cadenceValueBytes, err := json.Encode(cadenceValue)
-	require.NoError(t, err)
-
-	genericAmountBytes, err := json.Encode(mocks.GenericAmount(0))
-	require.NoError(t, err)
+func TestServer_GetEventsForHeightRange(t *testing.T) {
+	header := mocks.GenericHeader
+	events := mocks.GenericEvents(6)
+	types := mocks.GenericEventTypes(1)
 
 	t.Run("nominal case", func(t *testing.T) {
 		t.Parallel()
 
-		invoker := mocks.BaselineInvoker(t)
-		invoker.ScriptFunc = func(height uint64, script []byte, parameters []cadence.Value) (cadence.Value, error) {
-			assert.Equal(t, mocks.GenericHeight, height)
-			assert.Equal(t, mocks.GenericBytes, script)
-			assert.Equal(t, []cadence.Value{cadenceValue}, parameters)
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(h uint64, gotTypes ...flow.EventType) ([]flow.Event, error) {
+			// Expect height to be between GenericHeight and GenericHeight + 3 since there are four
+			// given blockIDs.
+			assert.InDelta(t, header.Height, h, 3)
+			assert.Equal(t, types, gotTypes)
 
-			return mocks.GenericAmount(0), nil
+			return events, nil
 		}
+		index.HeaderFunc = func(h uint64) (*flow.Header, error) {
+			// Expect height to be between GenericHeight and GenericHeight + 3 since there are four
+			// given blockIDs.
+			assert.InDelta(t, header.Height, h, 3)
 
-		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(got flow.Identifier) (uint64, error) {
-			assert.Equal(t, blockID, got)
-
-			return mocks.GenericHeight, nil
+			return header, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
-		s.invoker = invoker
 
-		req := &access.ExecuteScriptAtBlockIDRequest{
-			BlockId:   blockID[:],
-			Script:    mocks.GenericBytes,
-			Arguments: [][]byte{cadenceValueBytes},
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height + 3,
+			Type:        string(types[0]),
 		}
-		resp, err := s.ExecuteScriptAtBlockID(context.Background(), req)
+		resp, err := s.GetEventsForHeightRange(context.Background(), req)
 
 		require.NoError(t, err)
-		assert.Equal(t, genericAmountBytes, resp.Value)
+		assert.Len(t, resp.Results, 4)
+		for _, block := range resp.Results {
+			assert.Len(t, block.Events, len(events))
+		}
 	})
 
-	t.Run("handles index failure", func(t *testing.T) {
+	t.Run("clamps invalid timestamps when validated", func(t *testing.T) {
 		t.Parallel()
 
+		corrupt := *header
+		corrupt.Timestamp = time.Time{}
 		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
-			return 0, mocks.GenericError
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return &corrupt, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.ValidateTimestamps = true
 
-		req := &access.ExecuteScriptAtBlockIDRequest{
-			BlockId:   blockID[:],
-			Script:    mocks.GenericBytes,
-			Arguments: [][]byte{cadenceValueBytes},
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height,
 		}
-		_, err = s.ExecuteScriptAtBlockID(context.Background(), req)
+		resp, err := s.GetEventsForHeightRange(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, minTimestamp, resp.Results[0].BlockTimestamp.AsTime())
 	})
 
-	t.Run("handles invoker failure", func(t *testing.T) {
+	t.Run("nominal case without event type", func(t *testing.T) {
 		t.Parallel()
 
-		invoker := mocks.BaselineInvoker(t)
-		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
-			return nil, mocks.GenericError
-		}
-
-		s := baselineServer(t)
-		s.invoker = invoker
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(h uint64, types ...flow.EventType) ([]flow.Event, error) {
+			// Expect height to be between GenericHeight and GenericHeight + 3 since there are four
+			// given blockIDs.
+			assert.InDelta(t, header.Height, h, 3)
+			assert.Empty(t, types)
 
-		req := &access.ExecuteScriptAtBlockIDRequest{
-			BlockId:   blockID[:],
-			Script:    mocks.GenericBytes,
-			Arguments: [][]byte{cadenceValueBytes},
+			return events, nil
 		}
-		_, err = s.ExecuteScriptAtBlockID(context.Background(), req)
-
-		assert.Error(t, err)
-	})
-}
-
-func TestServer_ExecuteScriptAtLatestBlock(t *testing.T) {
-	cadenceValue := cadence.NewUInt64(mocks.GenericHeight)
-	cadenceValueBytes, err := json.Encode(cadenceValue)
-	require.NoError(t, err)
-
-	genericAmountBytes, err := json.Encode(mocks.GenericAmount(0))
-	require.NoError(t, err)
-
-	t.Run("nominal case", func(t *testing.T) {
-		t.Parallel()
-
-		invoker := mocks.BaselineInvoker(t)
-		invoker.ScriptFunc = func(height uint64, script []byte, parameters []cadence.Value) (cadence.Value, error) {
-			assert.Equal(t, mocks.GenericHeight, height)
-			assert.Equal(t, mocks.GenericBytes, script)
-			assert.Equal(t, []cadence.Value{cadenceValue}, parameters)
+		index.HeaderFunc = func(h uint64) (*flow.Header, error) {
+			// Expect height to be between GenericHeight and GenericHeight + 3 since there are four
+			// given blockIDs.
+			assert.InDelta(t, header.Height, h, 3)
 
-			return mocks.GenericAmount(0), nil
+			return header, nil
 		}
 
 		s := baselineServer(t)
-		s.invoker = invoker
+		s.index = index
 
-		req := &access.ExecuteScriptAtLatestBlockRequest{
-			Script:    mocks.GenericBytes,
-			Arguments: [][]byte{cadenceValueBytes},
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height + 3,
+			Type:        "",
 		}
-		resp, err := s.ExecuteScriptAtLatestBlock(context.Background(), req)
+		resp, err := s.GetEventsForHeightRange(context.Background(), req)
 
-		require.NoError(t, err)
+		assert.NoError(t, err)
 
-		assert.Equal(t, genericAmountBytes, resp.Value)
+		assert.Len(t, resp.Results, 4)
+		for _, block := range resp.Results {
+			assert.Len(t, block.Events, len(events))
+		}
 	})
 
-	t.Run("handles index failure", func(t *testing.T) {
+	t.Run("handles indexer error on Header", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.LastFunc = func() (uint64, error) {
-			return 0, mocks.GenericError
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.ExecuteScriptAtLatestBlockRequest{
-			Script:    mocks.GenericBytes,
-			Arguments: [][]byte{cadenceValueBytes},
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height + 3,
+			Type:        string(types[0]),
 		}
-		_, err = s.ExecuteScriptAtLatestBlock(context.Background(), req)
+		_, err := s.GetEventsForHeightRange(context.Background(), req)
 
 		assert.Error(t, err)
 	})
 
-	t.Run("handles invoker failure", func(t *testing.T) {
+	t.Run("handles indexer error on Events", func(t *testing.T) {
 		t.Parallel()
 
-		invoker := mocks.BaselineInvoker(t)
-		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
 			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
-		s.invoker = invoker
+		s.index = index
 
-		req := &access.ExecuteScriptAtLatestBlockRequest{
-			Script:    mocks.GenericBytes,
-			Arguments: [][]byte{cadenceValueBytes},
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height + 3,
+			Type:        string(types[0]),
 		}
-		_, err = s.ExecuteScriptAtLatestBlock(context.Background(), req)
+		_, err := s.GetEventsForHeightRange(context.Background(), req)
 
 		assert.Error(t, err)
 	})
-}
-
-func TestServer_GetLatestBlock(t *testing.T) {
-	header := mocks.GenericHeader
-	blockID := header.ID()
-	sealIDs := mocks.GenericSealIDs(6)
-	collIDs := mocks.GenericCollectionIDs(6)
 
-	t.Run("nominal case", func(t *testing.T) {
+	t.Run("handles inverted height range", func(t *testing.T) {
 		t.Parallel()
 
-		var sealCalled, collCalled int
-		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(id flow.Identifier) (uint64, error) {
-			assert.Equal(t, blockID, id)
+		s := baselineServer(t)
 
-			return header.Height, nil
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height + 3,
+			EndHeight:   header.Height,
+			Type:        string(types[0]),
 		}
-		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
-			assert.Equal(t, header.Height, height)
+		_, err := s.GetEventsForHeightRange(context.Background(), req)
 
-			return header, nil
-		}
-		index.SealsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
-			assert.Equal(t, header.Height, height)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
 
-			return sealIDs, nil
+	t.Run("orders events by transaction and event index", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return shuffledEvents(), nil
 		}
-		index.SealFunc = func(sealID flow.Identifier) (*flow.Seal, error) {
-			assert.Contains(t, sealIDs, sealID)
 
-			seal := mocks.GenericSeal(sealCalled)
-			sealCalled++
+		s := baselineServer(t)
+		s.index = index
 
-			return seal, nil
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height,
 		}
-		index.CollectionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
-			assert.Equal(t, header.Height, height)
+		resp, err := s.GetEventsForHeightRange(context.Background(), req)
 
-			return collIDs, nil
-		}
-		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
-			assert.Contains(t, collIDs, collID)
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		require.Len(t, resp.Results[0].Events, 9)
+		assertEventOrder(t, resp.Results[0].Events)
+	})
 
-			collection := mocks.GenericCollection(collCalled)
-			collCalled++
+	t.Run("returns events in the same order across calls", func(t *testing.T) {
+		t.Parallel()
 
-			return collection, nil
-		}
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = varyingEvents()
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetLatestBlockRequest{}
-		resp, err := s.GetLatestBlock(context.Background(), req)
-
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height,
+		}
+		first, err := s.GetEventsForHeightRange(context.Background(), req)
+		require.NoError(t, err)
+		second, err := s.GetEventsForHeightRange(context.Background(), req)
 		require.NoError(t, err)
-		assert.Equal(t, blockID[:], resp.Block.Id)
-		assert.Equal(t, header.Height, resp.Block.Height)
-		assert.Equal(t, header.ParentID[:], resp.Block.ParentId)
-		assert.NotZero(t, resp.Block.Timestamp)
-
-		for i, collID := range collIDs {
-			seal := mocks.GenericSeal(i)
-			wantSeal := &entities.BlockSeal{
-				BlockId:                    seal.BlockID[:],
-				ExecutionReceiptId:         seal.ResultID[:],
-				ExecutionReceiptSignatures: [][]byte{},
-			}
-			assert.Contains(t, resp.Block.BlockSeals, wantSeal)
 
-			guarantee := mocks.GenericGuarantee(i)
-			wantGuarantee := &entities.CollectionGuarantee{
-				CollectionId: collID[:],
-				Signatures:   [][]byte{guarantee.Signature},
-			}
-			assert.Contains(t, resp.Block.CollectionGuarantees, wantGuarantee)
-		}
+		require.Len(t, first.Results, 1)
+		assertEventOrder(t, first.Results[0].Events)
+		assert.Equal(t, first.Results, second.Results)
 	})
 
-	t.Run("handles indexer failure on Last", func(t *testing.T) {
+	t.Run("retries transient failures of a height", func(t *testing.T) {
 		t.Parallel()
 
+		calls := make(map[uint64]int)
 		index := mocks.BaselineReader(t)
 		index.LastFunc = func() (uint64, error) {
-			return 0, mocks.GenericError
+			return header.Height + 2, nil
+		}
+		index.EventsFunc = func(height uint64, _ ...flow.EventType) ([]flow.Event, error) {
+			calls[height]++
+			if height == header.Height+1 && calls[height] == 1 {
+				return nil, fmt.Errorf("could not get events: %w", status.Error(codes.Unavailable, "connection reset"))
+			}
+			return events, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.RangeBackoff = time.Millisecond
 
-		req := &access.GetLatestBlockRequest{}
-		_, err := s.GetLatestBlock(context.Background(), req)
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height + 2,
+		}
+		resp, err := s.GetEventsForHeightRange(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		assert.Len(t, resp.Results, 3)
+		assert.Equal(t, 1, calls[header.Height])
+		assert.Equal(t, 2, calls[header.Height+1])
+		assert.Equal(t, 1, calls[header.Height+2])
 	})
 
-	t.Run("handles indexer failure on Header", func(t *testing.T) {
+	t.Run("fails after retrying transient failures", func(t *testing.T) {
 		t.Parallel()
 
+		var calls int
 		index := mocks.BaselineReader(t)
-		index.HeaderFunc = func(uint64) (*flow.Header, error) {
-			return nil, mocks.GenericError
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			calls++
+			return nil, status.Error(codes.Unavailable, "connection reset")
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.RangeRetries = 3
+		s.cfg.RangeBackoff = time.Millisecond
 
-		req := &access.GetLatestBlockRequest{}
-		_, err := s.GetLatestBlock(context.Background(), req)
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height,
+		}
+		_, err := s.GetEventsForHeightRange(context.Background(), req)
 
 		assert.Error(t, err)
+		assert.Equal(t, 4, calls)
 	})
 
-	t.Run("handles indexer failure on SealsByHeight", func(t *testing.T) {
+	t.Run("does not retry other failures", func(t *testing.T) {
 		t.Parallel()
 
+		var calls int
 		index := mocks.BaselineReader(t)
-		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			calls++
 			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.RangeBackoff = time.Millisecond
 
-		req := &access.GetLatestBlockRequest{}
-		_, err := s.GetLatestBlock(context.Background(), req)
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height,
+		}
+		_, err := s.GetEventsForHeightRange(context.Background(), req)
 
 		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
 	})
 
-	t.Run("handles indexer failure on CollectionsByHeight", func(t *testing.T) {
+	t.Run("stops retrying when request is canceled", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
-			return nil, mocks.GenericError
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return nil, status.Error(codes.Unavailable, "connection reset")
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.RangeBackoff = time.Hour
 
-		req := &access.GetLatestBlockRequest{}
-		_, err := s.GetLatestBlock(context.Background(), req)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
 
-		assert.Error(t, err)
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height,
+		}
+		_, err := s.GetEventsForHeightRange(ctx, req)
+
+		assert.Equal(t, codes.Canceled, status.Code(err))
 	})
 }
 
-func TestServer_GetBlockByID(t *testing.T) {
+func TestServer_GetDecodedEventsForHeightRange(t *testing.T) {
 	header := mocks.GenericHeader
-	blockID := header.ID()
-	sealIDs := mocks.GenericSealIDs(6)
-	collIDs := mocks.GenericCollectionIDs(6)
+	deposited := flow.Event{
+		Type:             "A.1654653399040a61.FlowToken.TokensDeposited",
+		TransactionID:    mocks.GenericTransaction(0).ID(),
+		TransactionIndex: 1,
+		EventIndex:       2,
+		Payload:          []byte(`{"type":"Event","value":{"id":"A.1654653399040a61.FlowToken.TokensDeposited","fields":[{"name":"amount","value":{"type":"UFix64","value":"12.50000000"}},{"name":"to","value":{"type":"Optional","value":{"type":"Address","value":"0xf919ee77447b7497"}}}]}}`),
+	}
 
 	t.Run("nominal case", func(t *testing.T) {
 		t.Parallel()
 
-		var sealCalled, collCalled int
 		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(gotBlockID flow.Identifier) (uint64, error) {
-			assert.Equal(t, blockID, gotBlockID)
+		index.EventsFunc = func(h uint64, types ...flow.EventType) ([]flow.Event, error) {
+			assert.Equal(t, []flow.EventType{deposited.Type}, types)
 
-			return mocks.GenericHeight, nil
+			return []flow.Event{deposited}, nil
 		}
-		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
-			assert.Equal(t, header.Height, height)
 
-			return mocks.GenericHeader, nil
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.DecodeEvents = true
+
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height + 1,
+			Type:        string(deposited.Type),
 		}
-		index.SealsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
-			assert.Equal(t, header.Height, height)
+		resp, err := s.GetDecodedEventsForHeightRange(context.Background(), req)
 
-			return sealIDs, nil
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 2)
+		for i, result := range resp.Results {
+			assert.Equal(t, header.Height+uint64(i), result.BlockHeight)
+			require.Len(t, result.Events, 1)
+
+			event := result.Events[0]
+			assert.Equal(t, deposited.Payload, event.Event.Payload)
+			assert.Equal(t, deposited.EventIndex, event.Event.EventIndex)
+			want := map[string]string{
+				"amount": "12.50000000",
+				"to":     "0xf919ee77447b7497",
+			}
+			assert.Equal(t, want, event.Fields)
 		}
-		index.SealFunc = func(sealID flow.Identifier) (*flow.Seal, error) {
-			assert.Contains(t, sealIDs, sealID)
+	})
 
-			seal := mocks.GenericSeal(sealCalled)
-			sealCalled++
+	t.Run("handles disabled decoding", func(t *testing.T) {
+		t.Parallel()
 
-			return seal, nil
-		}
-		index.CollectionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
-			assert.Equal(t, header.Height, height)
+		s := baselineServer(t)
 
-			return collIDs, nil
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height,
 		}
-		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
-			assert.Contains(t, collIDs, collID)
+		_, err := s.GetDecodedEventsForHeightRange(context.Background(), req)
 
-			collection := mocks.GenericCollection(collCalled)
-			collCalled++
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
 
-			return collection, nil
+	t.Run("returns undecodable events without fields", func(t *testing.T) {
+		t.Parallel()
+
+		invalid := deposited
+		invalid.EventIndex = 3
+		invalid.Payload = []byte(`{"type":"UFix64","value":"12.50000000"}`)
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return []flow.Event{deposited, invalid}, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.DecodeEvents = true
 
-		req := &access.GetBlockByIDRequest{Id: blockID[:]}
-		resp, err := s.GetBlockByID(context.Background(), req)
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height,
+		}
+		resp, err := s.GetDecodedEventsForHeightRange(context.Background(), req)
 
 		require.NoError(t, err)
-		assert.Equal(t, blockID[:], resp.Block.Id)
-		assert.Equal(t, header.Height, resp.Block.Height)
-		assert.Equal(t, header.ParentID[:], resp.Block.ParentId)
-		assert.NotZero(t, resp.Block.Timestamp)
+		require.Len(t, resp.Results, 1)
+		require.Len(t, resp.Results[0].Events, 2)
+
+		decoded, undecoded := resp.Results[0].Events[0], resp.Results[0].Events[1]
+		assert.Equal(t, deposited.Payload, decoded.Event.Payload)
+		assert.Len(t, decoded.Fields, 2)
+		assert.Equal(t, invalid.Payload, undecoded.Event.Payload)
+		assert.Empty(t, undecoded.Fields)
+	})
 
-		for i, collID := range collIDs {
-			seal := mocks.GenericSeal(i)
-			wantSeal := &entities.BlockSeal{
-				BlockId:                    seal.BlockID[:],
-				ExecutionReceiptId:         seal.ResultID[:],
-				ExecutionReceiptSignatures: [][]byte{},
-			}
-			assert.Contains(t, resp.Block.BlockSeals, wantSeal)
+	t.Run("handles invalid payload with strict decoding", func(t *testing.T) {
+		t.Parallel()
 
-			guarantee := mocks.GenericGuarantee(i)
-			wantGuarantee := &entities.CollectionGuarantee{
-				CollectionId: collID[:],
-				Signatures:   [][]byte{guarantee.Signature},
-			}
-			assert.Contains(t, resp.Block.CollectionGuarantees, wantGuarantee)
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			invalid := deposited
+			invalid.Payload = []byte(`{"type":"UFix64","value":"12.50000000"}`)
+			return []flow.Event{invalid}, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.DecodeEvents = true
+		s.cfg.StrictDecoding = true
+
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height,
+			EndHeight:   header.Height,
 		}
+		_, err := s.GetDecodedEventsForHeightRange(context.Background(), req)
+
+		assert.Error(t, err)
 	})
 
-	t.Run("handles indexer failure on HeightForBlock", func(t *testing.T) {
+	t.Run("handles invalid range", func(t *testing.T) {
 		t.Parallel()
 
+		s := baselineServer(t)
+		s.cfg.DecodeEvents = true
+
+		req := &access.GetEventsForHeightRangeRequest{
+			StartHeight: header.Height + 1,
+			EndHeight:   header.Height,
+		}
+		_, err := s.GetDecodedEventsForHeightRange(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestServer_GetEventCountsForHeightRange(t *testing.T) {
+	types := mocks.GenericEventTypes(2)
+
+	// The heights of the range have two, three and four events, alternating
+	// between both types, so there are five events of the first type and four
+	// of the second.
+	index := func(t *testing.T) *mocks.Reader {
 		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
-			return 0, mocks.GenericError
+		index.EventsFunc = func(height uint64, tt ...flow.EventType) ([]flow.Event, error) {
+			assert.Empty(t, tt)
+			return mocks.GenericEvents(int(height-mocks.GenericHeight)+2, types...), nil
 		}
+		return index
+	}
+	req := &extended.GetEventCountsForHeightRangeRequest{
+		StartHeight: mocks.GenericHeight,
+		EndHeight:   mocks.GenericHeight + 2,
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
 
 		s := baselineServer(t)
-		s.index = index
+		s.index = index(t)
 
-		req := &access.GetBlockByIDRequest{Id: blockID[:]}
-		_, err := s.GetBlockByID(context.Background(), req)
+		resp, err := s.GetEventCountsForHeightRange(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		want := map[string]uint64{
+			string(types[0]): 5,
+			string(types[1]): 4,
+		}
+		assert.Equal(t, want, resp.Counts)
+		assert.Equal(t, uint64(9), resp.Total)
 	})
 
-	t.Run("handles indexer failure on Header", func(t *testing.T) {
+	t.Run("does not count events of denied types", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index(t)
+		s.cfg.DeniedEventTypes = []flow.EventType{types[1]}
+
+		resp, err := s.GetEventCountsForHeightRange(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]uint64{string(types[0]): 5}, resp.Counts)
+		assert.Equal(t, uint64(5), resp.Total)
+	})
+
+	t.Run("handles invalid range", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		_, err := s.GetEventCountsForHeightRange(context.Background(), &extended.GetEventCountsForHeightRangeRequest{
+			StartHeight: mocks.GenericHeight + 1,
+			EndHeight:   mocks.GenericHeight,
+		})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("handles too many heights", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.MaxBatchSize = 2
+
+		_, err := s.GetEventCountsForHeightRange(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("handles index failure", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
 			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetBlockByIDRequest{Id: blockID[:]}
-		_, err := s.GetBlockByID(context.Background(), req)
+		_, err := s.GetEventCountsForHeightRange(context.Background(), req)
 
 		assert.Error(t, err)
 	})
+}
 
-	t.Run("handles indexer failure on SealsByHeight", func(t *testing.T) {
+func TestServer_GetEventsForTransaction(t *testing.T) {
+	header := mocks.GenericHeader
+	blockID := header.ID()
+	txIDs := mocks.GenericTransactionIDs(2)
+	types := mocks.GenericEventTypes(3)
+	events := []flow.Event{
+		{Type: types[0], TransactionID: txIDs[0], EventIndex: 0},
+		{Type: types[1], TransactionID: txIDs[1], EventIndex: 2},
+		{Type: types[0], TransactionID: txIDs[1], EventIndex: 0},
+		{Type: types[2], TransactionID: txIDs[1], EventIndex: 1},
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
-			return nil, mocks.GenericError
+		index.HeightForTransactionFunc = func(txID flow.Identifier) (uint64, error) {
+			assert.Equal(t, txIDs[1], txID)
+
+			return header.Height, nil
+		}
+		index.EventsFunc = func(height uint64, types ...flow.EventType) ([]flow.Event, error) {
+			assert.Equal(t, header.Height, height)
+			assert.Empty(t, types)
+
+			return events, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetBlockByIDRequest{Id: blockID[:]}
-		_, err := s.GetBlockByID(context.Background(), req)
+		req := &extended.GetEventsForTransactionRequest{TransactionId: txIDs[1][:]}
+		resp, err := s.GetEventsForTransaction(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		assert.Equal(t, blockID[:], resp.BlockId)
+		assert.Equal(t, header.Height, resp.BlockHeight)
+		require.Len(t, resp.Events, 3)
+		for i, event := range resp.Events {
+			assert.Equal(t, txIDs[1][:], event.TransactionId)
+			assert.Equal(t, uint32(i), event.EventIndex)
+		}
+		assert.Equal(t, string(types[0]), resp.Events[0].Type)
+		assert.Equal(t, string(types[2]), resp.Events[1].Type)
+		assert.Equal(t, string(types[1]), resp.Events[2].Type)
 	})
 
-	t.Run("handles indexer failure on Seal", func(t *testing.T) {
+	t.Run("handles unknown transaction", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.SealFunc = func(flow.Identifier) (*flow.Seal, error) {
-			return nil, mocks.GenericError
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return 0, fmt.Errorf("could not look up height: %w", badger.ErrKeyNotFound)
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetBlockByIDRequest{Id: blockID[:]}
-		_, err := s.GetBlockByID(context.Background(), req)
+		req := &extended.GetEventsForTransactionRequest{TransactionId: txIDs[1][:]}
+		_, err := s.GetEventsForTransaction(context.Background(), req)
 
-		assert.Error(t, err)
+		assert.Equal(t, codes.NotFound, status.Code(err))
 	})
 
-	t.Run("handles indexer failure on CollectionsByHeight", func(t *testing.T) {
+	t.Run("handles unknown transaction through archive API", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
-			return nil, mocks.GenericError
+		index.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return 0, status.Error(codes.Unknown, "could not get height: Key not found")
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetBlockByIDRequest{Id: blockID[:]}
-		_, err := s.GetBlockByID(context.Background(), req)
+		req := &extended.GetEventsForTransactionRequest{TransactionId: txIDs[1][:]}
+		_, err := s.GetEventsForTransaction(context.Background(), req)
 
-		assert.Error(t, err)
+		assert.Equal(t, codes.NotFound, status.Code(err))
 	})
 
-	t.Run("handles indexer failure on Guarantee", func(t *testing.T) {
+	t.Run("handles indexer failure on Events", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.GuaranteeFunc = func(flow.Identifier) (*flow.CollectionGuarantee, error) {
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
 			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetBlockByIDRequest{Id: blockID[:]}
-		_, err := s.GetBlockByID(context.Background(), req)
+		req := &extended.GetEventsForTransactionRequest{TransactionId: txIDs[1][:]}
+		_, err := s.GetEventsForTransaction(context.Background(), req)
 
 		assert.Error(t, err)
+		assert.NotEqual(t, codes.NotFound, status.Code(err))
 	})
 }
 
-func TestServer_GetBlockByHeight(t *testing.T) {
-	header := mocks.GenericHeader
+func TestServer_Timestamps(t *testing.T) {
+	header := *mocks.GenericHeader
+	header.Timestamp = time.Date(2021, 6, 1, 12, 30, 15, 123456789, time.UTC)
 	blockID := header.ID()
-	sealIDs := mocks.GenericSealIDs(6)
-	collIDs := mocks.GenericCollectionIDs(6)
 
-	t.Run("nominal case", func(t *testing.T) {
-		t.Parallel()
+	index := mocks.BaselineReader(t)
+	index.HeaderFunc = func(uint64) (*flow.Header, error) {
+		return &header, nil
+	}
 
-		var sealCalled, collCalled int
-		index := mocks.BaselineReader(t)
-		index.HeightForBlockFunc = func(gotBlockID flow.Identifier) (uint64, error) {
-			assert.Equal(t, blockID, gotBlockID)
+	s := baselineServer(t)
+	s.index = index
 
-			return header.Height, nil
-		}
-		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
-			assert.Equal(t, header.Height, height)
+	block, err := s.GetBlockByHeight(context.Background(), &access.GetBlockByHeightRequest{Height: header.Height})
+	require.NoError(t, err)
 
-			return header, nil
-		}
-		index.SealsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
-			assert.Equal(t, header.Height, height)
+	byRange, err := s.GetEventsForHeightRange(context.Background(), &access.GetEventsForHeightRangeRequest{
+		StartHeight: header.Height,
+		EndHeight:   header.Height,
+	})
+	require.NoError(t, err)
+	require.Len(t, byRange.Results, 1)
 
-			return sealIDs, nil
-		}
-		index.SealFunc = func(sealID flow.Identifier) (*flow.Seal, error) {
-			assert.Contains(t, sealIDs, sealID)
+	byID, err := s.GetEventsForBlockIDs(context.Background(), &access.GetEventsForBlockIDsRequest{
+		BlockIds: [][]byte{blockID[:]},
+	})
+	require.NoError(t, err)
+	require.Len(t, byID.Results, 1)
 
-			seal := mocks.GenericSeal(sealCalled)
-			sealCalled++
+	assert.Equal(t, header.Timestamp, block.Block.Timestamp.AsTime())
+	assert.True(t, proto.Equal(block.Block.Timestamp, byRange.Results[0].BlockTimestamp))
+	assert.True(t, proto.Equal(block.Block.Timestamp, byID.Results[0].BlockTimestamp))
+}
 
-			return seal, nil
-		}
-		index.CollectionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
-			assert.Equal(t, header.Height, height)
+func TestServer_GetNetworkParameters(t *testing.T) {
+	header := mocks.GenericHeader
 
-			return collIDs, nil
-		}
-		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
-			assert.Contains(t, collIDs, collID)
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
 
-			collection := mocks.GenericCollection(collCalled)
-			collCalled++
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, header.Height, mocks.GenericHeight)
 
-			return collection, nil
+			return header, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetBlockByHeightRequest{Height: header.Height}
-		resp, err := s.GetBlockByHeight(context.Background(), req)
+		req := &access.GetNetworkParametersRequest{}
+		resp, err := s.GetNetworkParameters(context.Background(), req)
 
 		require.NoError(t, err)
-		assert.Equal(t, blockID[:], resp.Block.Id)
-		assert.Equal(t, mocks.GenericHeight, resp.Block.Height)
-		assert.Equal(t, mocks.GenericHeader.ParentID[:], resp.Block.ParentId)
-		assert.NotZero(t, resp.Block.Timestamp)
-
-		for i, collID := range collIDs {
-			seal := mocks.GenericSeal(i)
-			wantSeal := &entities.BlockSeal{
-				BlockId:                    seal.BlockID[:],
-				ExecutionReceiptId:         seal.ResultID[:],
-				ExecutionReceiptSignatures: [][]byte{},
-			}
-			assert.Contains(t, resp.Block.BlockSeals, wantSeal)
-
-			guarantee := mocks.GenericGuarantee(i)
-			wantGuarantee := &entities.CollectionGuarantee{
-				CollectionId: collID[:],
-				Signatures:   [][]byte{guarantee.Signature},
-			}
-			assert.Contains(t, resp.Block.CollectionGuarantees, wantGuarantee)
-		}
+		assert.Equal(t, archive.FlowTestnet.String(), resp.ChainId)
 	})
 
-	t.Run("handles indexer failure on Header", func(t *testing.T) {
+	t.Run("nominal case with height window", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.HeaderFunc = func(uint64) (*flow.Header, error) {
-			return nil, mocks.GenericError
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight + 10, nil
+		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, mocks.GenericHeight+6, height)
+
+			return header, nil
 		}
 
 		s := baselineServer(t)
 		s.index = index
+		s.cfg.HeightWindow = 5
 
-		req := &access.GetBlockByHeightRequest{Height: header.Height}
-		_, err := s.GetBlockByHeight(context.Background(), req)
+		req := &access.GetNetworkParametersRequest{}
+		resp, err := s.GetNetworkParameters(context.Background(), req)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		assert.Equal(t, archive.FlowTestnet.String(), resp.ChainId)
 	})
 
-	t.Run("handles indexer failure on SealsByHeight", func(t *testing.T) {
+	t.Run("handles indexer failure on first", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
-			return nil, mocks.GenericError
+		index.FirstFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetBlockByHeightRequest{Height: header.Height}
-		_, err := s.GetBlockByHeight(context.Background(), req)
+		req := &access.GetNetworkParametersRequest{}
+		_, err := s.GetNetworkParameters(context.Background(), req)
 
 		assert.Error(t, err)
 	})
 
-	t.Run("handles indexer failure on CollectionsByHeight", func(t *testing.T) {
+	t.Run("handles indexer failure on header", func(t *testing.T) {
 		t.Parallel()
 
 		index := mocks.BaselineReader(t)
-		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
 			return nil, mocks.GenericError
 		}
 
 		s := baselineServer(t)
 		s.index = index
 
-		req := &access.GetBlockByHeightRequest{Height: header.Height}
-		_, err := s.GetBlockByHeight(context.Background(), req)
+		req := &access.GetNetworkParametersRequest{}
+		_, err := s.GetNetworkParameters(context.Background(), req)
 
 		assert.Error(t, err)
 	})
 }
 
-func baselineServer(t *testing.T) *Server {
-	t.Helper()
+func TestServer_GetCollectionByID(t *testing.T) {
+	collection := mocks.GenericCollection(0)
+	collID := collection.ID()
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.CollectionFunc = func(gotCollID flow.Identifier) (*flow.LightCollection, error) {
+			assert.Equal(t, collID, gotCollID)
+
+			return collection, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetCollectionByIDRequest{Id: collID[:]}
+		resp, err := s.GetCollectionByID(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotNil(t, resp.Collection)
+		assert.Len(t, resp.Collection.TransactionIds, 2)
+	})
+
+	t.Run("handles indexer failure on collection", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.CollectionFunc = func(flow.Identifier) (*flow.LightCollection, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetCollectionByIDRequest{Id: collID[:]}
+		_, err := s.GetCollectionByID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("sends indexed reference block on request", func(t *testing.T) {
+		t.Parallel()
+
+		guarantee := mocks.GenericGuarantee(0)
+		index := mocks.BaselineReader(t)
+		index.GuaranteeFunc = func(gotCollID flow.Identifier) (*flow.CollectionGuarantee, error) {
+			assert.Equal(t, collID, gotCollID)
+
+			return guarantee, nil
+		}
+		index.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
+			assert.Equal(t, guarantee.ReferenceBlockID, blockID)
+
+			return mocks.GenericHeight, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		var stream headerStream
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ReferenceBlockHeader, "true"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+
+		req := &access.GetCollectionByIDRequest{Id: collID[:]}
+		_, err := s.GetCollectionByID(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{guarantee.ReferenceBlockID.String()}, stream.header.Get(ReferenceBlockIDHeader))
+		assert.Equal(t, []string{fmt.Sprint(mocks.GenericHeight)}, stream.header.Get(ReferenceBlockHeightHeader))
+	})
+
+	t.Run("omits height of reference block out of range", func(t *testing.T) {
+		t.Parallel()
+
+		guarantee := mocks.GenericGuarantee(0)
+		index := mocks.BaselineReader(t)
+		index.GuaranteeFunc = func(flow.Identifier) (*flow.CollectionGuarantee, error) {
+			return guarantee, nil
+		}
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, badger.ErrKeyNotFound
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		var stream headerStream
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ReferenceBlockHeader, "true"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+
+		req := &access.GetCollectionByIDRequest{Id: collID[:]}
+		resp, err := s.GetCollectionByID(ctx, req)
+
+		require.NoError(t, err)
+		assert.NotNil(t, resp.Collection)
+		assert.Equal(t, []string{guarantee.ReferenceBlockID.String()}, stream.header.Get(ReferenceBlockIDHeader))
+		assert.Empty(t, stream.header.Get(ReferenceBlockHeightHeader))
+	})
+
+	t.Run("does not send reference block by default", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.GuaranteeFunc = func(flow.Identifier) (*flow.CollectionGuarantee, error) {
+			t.Error("guarantee should not be read")
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		var stream headerStream
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), &stream)
+
+		req := &access.GetCollectionByIDRequest{Id: collID[:]}
+		_, err := s.GetCollectionByID(ctx, req)
+
+		require.NoError(t, err)
+		assert.Empty(t, stream.header)
+	})
+
+	t.Run("handles indexer failure on reference block height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ReferenceBlockHeader, "true"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &headerStream{})
+
+		req := &access.GetCollectionByIDRequest{Id: collID[:]}
+		_, err := s.GetCollectionByID(ctx, req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_GetAccount(t *testing.T) {
+	account := mocks.GenericAccount
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+
+			return mocks.GenericLedgerValues(4), nil
+		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+
+			return mocks.GenericHeader, nil
+		}
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(height uint64, address flow.Address) (*flow.Account, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			assert.Equal(t, account.Address, address)
+
+			return &account, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.invoker = invoker
+
+		req := &access.GetAccountRequest{Address: account.Address[:]}
+		resp, err := s.GetAccount(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotNil(t, resp.Account)
+		assert.Equal(t, account.Address[:], resp.Account.Address)
+		assert.Equal(t, account.Balance, resp.Account.Balance)
+	})
+
+	t.Run("handles indexer failure on Last", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetAccountRequest{Address: account.Address[:]}
+		_, err := s.GetAccount(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invoker failure on GetAccount", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.GetAccountRequest{Address: account.Address[:]}
+		_, err := s.GetAccount(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_GetAccountAtLatestBlock(t *testing.T) {
+	account := mocks.GenericAccount
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+
+			return mocks.GenericLedgerValues(4), nil
+		}
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(height uint64, address flow.Address) (*flow.Account, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			assert.Equal(t, account.Address, address)
+
+			return &account, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.invoker = invoker
+
+		req := &access.GetAccountAtLatestBlockRequest{Address: account.Address[:]}
+		resp, err := s.GetAccountAtLatestBlock(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotNil(t, resp.Account)
+		assert.Equal(t, account.Address[:], resp.Account.Address)
+		assert.Equal(t, account.Balance, resp.Account.Balance)
+	})
+
+	t.Run("handles empty index with zero last height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, nil
+		}
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, badger.ErrKeyNotFound
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetAccountAtLatestBlockRequest{Address: account.Address[:]}
+		_, err := s.GetAccountAtLatestBlock(context.Background(), req)
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("handles empty index with missing last height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, badger.ErrKeyNotFound
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetAccountAtLatestBlockRequest{Address: account.Address[:]}
+		_, err := s.GetAccountAtLatestBlock(context.Background(), req)
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("handles indexer failure on Last", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetAccountAtLatestBlockRequest{Address: account.Address[:]}
+		_, err := s.GetAccountAtLatestBlock(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invoker failure on GetAccount", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.GetAccountAtLatestBlockRequest{Address: account.Address[:]}
+		_, err := s.GetAccountAtLatestBlock(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_GetAccountAtBlockHeight(t *testing.T) {
+	account := mocks.GenericAccount
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		height := mocks.GenericHeight + 999
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(gotHeight uint64, paths []ledger.Path) ([]ledger.Value, error) {
+			assert.Equal(t, height, gotHeight)
+
+			return mocks.GenericLedgerValues(4), nil
+		}
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(gotHeight uint64, address flow.Address) (*flow.Account, error) {
+			assert.Equal(t, height, gotHeight)
+			assert.Equal(t, account.Address, address)
+
+			return &account, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.invoker = invoker
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: height,
+			Address:     account.Address[:],
+		}
+		resp, err := s.GetAccountAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.NotNil(t, resp.Account)
+		assert.Equal(t, account.Address[:], resp.Account.Address)
+		assert.Equal(t, account.Balance, resp.Account.Balance)
+	})
+
+	t.Run("returns key sequence numbers at height", func(t *testing.T) {
+		t.Parallel()
+
+		// The sequence numbers are those stored at the requested height, which
+		// the invoker reads from the registers at that height.
+		height := mocks.GenericHeight + 999
+		historical := account
+		historical.Keys = []flow.AccountPublicKey{account.Keys[0], account.Keys[0]}
+		historical.Keys[1].Index = 1
+		historical.Keys[1].SeqNumber = 7
+		historical.Keys[1].Revoked = true
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(gotHeight uint64, address flow.Address) (*flow.Account, error) {
+			assert.Equal(t, height, gotHeight)
+
+			return &historical, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: height,
+			Address:     account.Address[:],
+		}
+		resp, err := s.GetAccountAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		require.Len(t, resp.Account.Keys, 2)
+		assert.Equal(t, uint32(0), resp.Account.Keys[0].Index)
+		assert.Equal(t, uint32(42), resp.Account.Keys[0].SequenceNumber)
+		assert.Equal(t, uint32(1), resp.Account.Keys[1].Index)
+		assert.Equal(t, uint32(7), resp.Account.Keys[1].SequenceNumber)
+		assert.True(t, resp.Account.Keys[1].Revoked)
+	})
+
+	t.Run("includes contracts by default", func(t *testing.T) {
+		t.Parallel()
+
+		withContracts := account
+		withContracts.Contracts = map[string][]byte{"Contract": []byte("pub contract Contract {}")}
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
+			return &withContracts, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		resp, err := s.GetAccountAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, withContracts.Contracts, resp.Account.Contracts)
+	})
+
+	t.Run("omits contracts when requested", func(t *testing.T) {
+		t.Parallel()
+
+		withContracts := account
+		withContracts.Contracts = map[string][]byte{"Contract": []byte("pub contract Contract {}")}
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
+			return &withContracts, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(OmitContractsHeader, "true"))
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		resp, err := s.GetAccountAtBlockHeight(ctx, req)
+
+		require.NoError(t, err)
+		assert.Empty(t, resp.Account.Contracts)
+		assert.Equal(t, account.Address[:], resp.Account.Address)
+		assert.Equal(t, account.Balance, resp.Account.Balance)
+		assert.Len(t, resp.Account.Keys, len(account.Keys))
+
+		// The shared account is left untouched for concurrent requests.
+		assert.NotEmpty(t, withContracts.Contracts)
+	})
+
+	t.Run("handles invalid omit contracts header", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(OmitContractsHeader, "maybe"))
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		_, err := s.GetAccountAtBlockHeight(ctx, req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("handles invoker failure on GetAccount", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		_, err := s.GetAccountAtBlockHeight(context.Background(), req)
+
+		assert.Error(t, err)
+		assert.NotEqual(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles account created after height", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(height uint64, address flow.Address) (*flow.Account, error) {
+			err := fvmErrors.NewAccountNotFoundError(address)
+			return nil, fmt.Errorf("could not get account at height %d: %w", height, err)
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		_, err := s.GetAccountAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles account not found sentinel", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
+			return nil, ErrAccountNotFound
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		_, err := s.GetAccountAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("counts requested address", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
+			return &account, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+		s.cfg.Addresses = topk.New(10)
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		_, err := s.GetAccountAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		want := []topk.Entry{{Key: string(account.Address[:]), Count: 1}}
+		assert.Equal(t, want, s.cfg.Addresses.Top())
+	})
+
+	t.Run("looks up concurrent identical accounts once", func(t *testing.T) {
+		t.Parallel()
+
+		const requests = 10
+
+		var calls uint32
+		release := make(chan struct{})
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(uint64, flow.Address) (*flow.Account, error) {
+			atomic.AddUint32(&calls, 1)
+			<-release
+
+			return &account, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				resp, err := s.GetAccountAtBlockHeight(context.Background(), req)
+
+				assert.NoError(t, err)
+				assert.Equal(t, account.Address[:], resp.GetAccount().GetAddress())
+			}()
+		}
+
+		// Give all requests the time to join the lookup in progress.
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, uint32(1), atomic.LoadUint32(&calls))
+	})
+}
+
+func TestServer_GetAccountRegistersAtBlockHeight(t *testing.T) {
+	account := mocks.GenericAccount
+	paths := mocks.GenericLedgerPaths(3)
+	values := mocks.GenericLedgerValues(3)
+
+	factory := func(index archive.Reader) (Invoker, error) {
+		invoker := mocks.BaselineInvoker(t)
+		invoker.AccountFunc = func(height uint64, address flow.Address) (*flow.Account, error) {
+			// Read the same registers twice, to make sure they are only recorded once.
+			for i := 0; i < 2; i++ {
+				_, err := index.Values(height, paths)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return &account, nil
+		}
+
+		return invoker, nil
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(height uint64, gotPaths []ledger.Path) ([]ledger.Value, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			assert.Equal(t, paths, gotPaths)
+
+			return values, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.NewInvoker = factory
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		resp, err := s.GetAccountRegistersAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.False(t, resp.Truncated)
+		require.Len(t, resp.Registers, len(paths))
+		for i, register := range resp.Registers {
+			assert.Equal(t, paths[i][:], register.Path)
+			assert.Equal(t, []byte(values[i]), register.Value)
+		}
+	})
+
+	t.Run("truncates registers above maximum", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.NewInvoker = factory
+		s.cfg.MaxRegisters = 2
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		resp, err := s.GetAccountRegistersAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.True(t, resp.Truncated)
+		assert.Len(t, resp.Registers, 2)
+	})
+
+	t.Run("handles missing invoker factory", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		_, err := s.GetAccountRegistersAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+
+	t.Run("handles index failure on values", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(uint64, []ledger.Path) ([]ledger.Value, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.NewInvoker = factory
+
+		req := &access.GetAccountAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Address:     account.Address[:],
+		}
+		_, err := s.GetAccountRegistersAtBlockHeight(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_ExecuteScriptAtBlockHeight(t *testing.T) {
+	cadenceValue := cadence.NewUInt64(mocks.GenericHeight)
+	cadenceValueBytes, err := json.Encode(cadenceValue)
+	require.NoError(t, err)
+
+	genericAmountBytes, err := json.Encode(mocks.GenericAmount(0))
+	require.NoError(t, err)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(height uint64, script []byte, parameters []cadence.Value) (cadence.Value, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			assert.Equal(t, mocks.GenericBytes, script)
+			assert.Equal(t, []cadence.Value{cadenceValue}, parameters)
+
+			return mocks.GenericAmount(0), nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+			Arguments:   [][]byte{cadenceValueBytes},
+		}
+		resp, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, genericAmountBytes, resp.Value)
+	})
+
+	t.Run("handles invoker failure", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+			Arguments:   [][]byte{cadenceValueBytes},
+		}
+		_, err = s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+	t.Run("counts first address argument", func(t *testing.T) {
+		t.Parallel()
+
+		first := cadence.NewAddress(mocks.GenericAccount.Address)
+		firstBytes, err := json.Encode(first)
+		require.NoError(t, err)
+		second := cadence.NewAddress(flow.EmptyAddress)
+		secondBytes, err := json.Encode(second)
+		require.NoError(t, err)
+
+		s := baselineServer(t)
+		s.cfg.Addresses = topk.New(10)
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+			Arguments:   [][]byte{cadenceValueBytes, firstBytes, secondBytes},
+		}
+		_, err = s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		want := []topk.Entry{{Key: string(mocks.GenericAccount.Address[:]), Count: 1}}
+		assert.Equal(t, want, s.cfg.Addresses.Top())
+	})
+
+	t.Run("reports all invalid arguments", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			t.Error("script should not be executed")
+			return nil, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+			Arguments:   [][]byte{[]byte(`{"type":"Int"`), cadenceValueBytes, []byte(`{"type":"Unknown","value":"1"}`)},
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "argument 0:")
+		assert.NotContains(t, err.Error(), "argument 1:")
+		assert.Contains(t, err.Error(), "argument 2:")
+	})
+
+	// Static types encoded as plain type IDs are only accepted by the lenient
+	// decoder, as older versions of the JSON-Cadence format encoded them.
+	legacyType := []byte(`{"type":"Type","value":{"staticType":"Int"}}`)
+
+	t.Run("rejects legacy encodings with strict arguments", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			t.Error("script should not be executed")
+			return nil, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+			Arguments:   [][]byte{legacyType},
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("accepts legacy encodings with lenient arguments", func(t *testing.T) {
+		t.Parallel()
+
+		var got []cadence.Value
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(_ uint64, _ []byte, parameters []cadence.Value) (cadence.Value, error) {
+			got = parameters
+			return cadenceValue, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+		s.cfg.StrictArguments = false
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+			Arguments:   [][]byte{legacyType, cadenceValueBytes},
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		assert.Equal(t, cadence.NewTypeValue(cadence.TypeID("Int")), got[0])
+		assert.Equal(t, cadenceValue, got[1])
+	})
+
+	t.Run("rejects invalid arguments with lenient arguments", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.StrictArguments = false
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+			Arguments:   [][]byte{[]byte(`{"type":"Unknown","value":"1"}`)},
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "argument 0:")
+	})
+
+	t.Run("executes concurrent identical scripts once", func(t *testing.T) {
+		t.Parallel()
+
+		const requests = 10
+
+		var calls uint32
+		release := make(chan struct{})
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			atomic.AddUint32(&calls, 1)
+			<-release
+
+			return mocks.GenericAmount(0), nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight,
+			Script:      mocks.GenericBytes,
+			Arguments:   [][]byte{cadenceValueBytes},
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				resp, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+				assert.NoError(t, err)
+				assert.Equal(t, genericAmountBytes, resp.GetValue())
+			}()
+		}
+
+		// Give all requests the time to join the execution in progress.
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, uint32(1), atomic.LoadUint32(&calls))
+	})
+
+	t.Run("executes different scripts separately", func(t *testing.T) {
+		t.Parallel()
+
+		var calls uint32
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			atomic.AddUint32(&calls, 1)
+
+			return mocks.GenericAmount(0), nil
+		}
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight + 1, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.invoker = invoker
+
+		for _, height := range []uint64{mocks.GenericHeight, mocks.GenericHeight + 1} {
+			req := &access.ExecuteScriptAtBlockHeightRequest{
+				BlockHeight: height,
+				Script:      mocks.GenericBytes,
+			}
+			_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, uint32(2), atomic.LoadUint32(&calls))
+	})
+
+	t.Run("handles height above indexed range", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			t.Error("script should not be executed")
+			return nil, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight + 1,
+			Script:      mocks.GenericBytes,
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+
+	t.Run("handles height below indexed range", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			t.Error("script should not be executed")
+			return nil, nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: mocks.GenericHeight - 1,
+			Script:      mocks.GenericBytes,
+		}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+}
+
+func TestServer_ExecuteScripts(t *testing.T) {
+	cadenceValue := cadence.NewUInt64(mocks.GenericHeight)
+	cadenceValueBytes, err := json.Encode(cadenceValue)
+	require.NoError(t, err)
+
+	genericAmountBytes, err := json.Encode(mocks.GenericAmount(0))
+	require.NoError(t, err)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(height uint64, script []byte, parameters []cadence.Value) (cadence.Value, error) {
+			assert.Equal(t, mocks.GenericBytes, script)
+			assert.Equal(t, []cadence.Value{cadenceValue}, parameters)
+
+			if height != mocks.GenericHeight {
+				return nil, mocks.GenericError
+			}
+
+			return mocks.GenericAmount(0), nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		stream := &scriptStream{
+			ctx: context.Background(),
+			requests: []*extended.ExecuteScriptsRequest{
+				{BlockHeight: mocks.GenericHeight, Script: mocks.GenericBytes, Arguments: [][]byte{cadenceValueBytes}},
+				{BlockHeight: mocks.GenericHeight + 1, Script: mocks.GenericBytes, Arguments: [][]byte{cadenceValueBytes}},
+				{BlockHeight: mocks.GenericHeight, Script: mocks.GenericBytes, Arguments: [][]byte{cadenceValueBytes}},
+			},
+		}
+		err := s.ExecuteScripts(stream)
+
+		require.NoError(t, err)
+		require.Len(t, stream.responses, 3)
+
+		results := make(map[uint64]*extended.ExecuteScriptsResponse)
+		for _, resp := range stream.responses {
+			results[resp.Index] = resp
+		}
+		assert.Equal(t, genericAmountBytes, results[0].Value)
+		assert.Empty(t, results[0].Error)
+		assert.Empty(t, results[1].Value)
+		assert.NotEmpty(t, results[1].Error)
+		assert.Equal(t, genericAmountBytes, results[2].Value)
+	})
+
+	t.Run("reports panicking script as internal error", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(_ uint64, script []byte, _ []cadence.Value) (cadence.Value, error) {
+			if string(script) == "panic" {
+				panic("failure")
+			}
+			return mocks.GenericAmount(0), nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		stream := &scriptStream{
+			ctx: context.Background(),
+			requests: []*extended.ExecuteScriptsRequest{
+				{BlockHeight: mocks.GenericHeight, Script: mocks.GenericBytes},
+				{BlockHeight: mocks.GenericHeight, Script: []byte("panic")},
+			},
+		}
+		err := s.ExecuteScripts(stream)
+
+		require.NoError(t, err)
+		require.Len(t, stream.responses, 2)
+
+		results := make(map[uint64]*extended.ExecuteScriptsResponse)
+		for _, resp := range stream.responses {
+			results[resp.Index] = resp
+		}
+		assert.Equal(t, genericAmountBytes, results[0].Value)
+		assert.Equal(t, uint32(codes.Internal), results[1].Code)
+		assert.Empty(t, results[1].Value)
+	})
+
+	t.Run("handles receive failure", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		stream := &scriptStream{
+			ctx:     context.Background(),
+			recvErr: mocks.GenericError,
+		}
+		err := s.ExecuteScripts(stream)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles send failure", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		stream := &scriptStream{
+			ctx: context.Background(),
+			requests: []*extended.ExecuteScriptsRequest{
+				{BlockHeight: mocks.GenericHeight, Script: mocks.GenericBytes},
+			},
+			sendErr: mocks.GenericError,
+		}
+		err := s.ExecuteScripts(stream)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_SubscribeExecutionData(t *testing.T) {
+	txs := mocks.GenericTransactions(3)
+	collIDs := mocks.GenericCollectionIDs(2)
+
+	var txIDs []flow.Identifier
+	txMap := make(map[flow.Identifier]*flow.TransactionBody)
+	for _, tx := range txs {
+		txMap[tx.ID()] = tx
+		txIDs = append(txIDs, tx.ID())
+	}
+	collections := map[flow.Identifier]*flow.LightCollection{
+		collIDs[0]: {Transactions: txIDs[:1]},
+		collIDs[1]: {Transactions: txIDs[1:]},
+	}
+
+	baselineIndex := func(t *testing.T) *mocks.Reader {
+		index := mocks.BaselineReader(t)
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
+		}
+		index.TransactionFunc = func(txID flow.Identifier) (*flow.TransactionBody, error) {
+			return txMap[txID], nil
+		}
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return collIDs, nil
+		}
+		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
+			return collections[collID], nil
+		}
+		index.ResultFunc = func(txID flow.Identifier) (*flow.TransactionResult, error) {
+			return &flow.TransactionResult{TransactionID: txID}, nil
+		}
+		return index
+	}
+
+	t.Run("streams indexed blocks and waits for new ones", func(t *testing.T) {
+		t.Parallel()
+
+		last := uint64(mocks.GenericHeight + 1)
+		index := baselineIndex(t)
+		index.LastFunc = func() (uint64, error) {
+			return atomic.LoadUint64(&last), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.PollInterval = time.Millisecond
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var heights []uint64
+		stream := &executionDataStream{
+			ctx: ctx,
+			send: func(resp *extended.ExecutionDataResponse) error {
+				assert.Equal(t, mocks.GenericHeader.ID(), flow.HashToID(resp.BlockId))
+				assert.Len(t, resp.Transactions, len(txIDs))
+				assert.Equal(t, collIDs[1][:], resp.Transactions[2].CollectionId)
+				assert.Equal(t, uint32(2), resp.Transactions[2].Index)
+				assert.NotNil(t, resp.Transactions[2].Result)
+				assert.Len(t, resp.Events, 4)
+
+				heights = append(heights, resp.BlockHeight)
+				switch len(heights) {
+				case 2:
+					// The stream has caught up with the index, so it waits
+					// until the next height is indexed.
+					atomic.AddUint64(&last, 1)
+				case 3:
+					cancel()
+				}
+				return nil
+			},
+		}
+		err := s.SubscribeExecutionData(&extended.SubscribeExecutionDataRequest{StartHeight: mocks.GenericHeight}, stream)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, []uint64{mocks.GenericHeight, mocks.GenericHeight + 1, mocks.GenericHeight + 2}, heights)
+	})
+
+	t.Run("streams blocks with a system transaction", func(t *testing.T) {
+		t.Parallel()
+
+		// The system transaction of a block is not part of any collection.
+		systemTx := mocks.GenericTransaction(len(txs))
+		index := baselineIndex(t)
+		index.TransactionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return append(txIDs[:len(txIDs):len(txIDs)], systemTx.ID()), nil
+		}
+		index.TransactionFunc = func(txID flow.Identifier) (*flow.TransactionBody, error) {
+			if txID == systemTx.ID() {
+				return systemTx, nil
+			}
+			return txMap[txID], nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var heights []uint64
+		stream := &executionDataStream{
+			ctx: ctx,
+			send: func(resp *extended.ExecutionDataResponse) error {
+				require.Len(t, resp.Transactions, len(txIDs)+1)
+				system := resp.Transactions[len(txIDs)]
+				assert.Empty(t, system.CollectionId)
+				assert.Equal(t, uint32(len(txIDs)), system.Index)
+				assert.NotNil(t, system.Result)
+
+				heights = append(heights, resp.BlockHeight)
+				cancel()
+				return nil
+			},
+		}
+		err := s.SubscribeExecutionData(&extended.SubscribeExecutionDataRequest{StartHeight: mocks.GenericHeight}, stream)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, []uint64{mocks.GenericHeight}, heights)
+	})
+
+	t.Run("handles start height below served range", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		stream := &executionDataStream{
+			ctx: context.Background(),
+			send: func(*extended.ExecutionDataResponse) error {
+				t.Error("execution data should not be sent")
+				return nil
+			},
+		}
+		err := s.SubscribeExecutionData(&extended.SubscribeExecutionDataRequest{StartHeight: mocks.GenericHeight - 1}, stream)
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+
+	t.Run("ends stream on shutdown", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = baselineIndex(t)
+		s.Shutdown()
+
+		stream := &executionDataStream{
+			ctx: context.Background(),
+			send: func(*extended.ExecutionDataResponse) error {
+				t.Error("execution data should not be sent")
+				return nil
+			},
+		}
+		err := s.SubscribeExecutionData(&extended.SubscribeExecutionDataRequest{StartHeight: mocks.GenericHeight + 1}, stream)
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("handles indexer failure", func(t *testing.T) {
+		t.Parallel()
+
+		index := baselineIndex(t)
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		stream := &executionDataStream{
+			ctx: context.Background(),
+			send: func(*extended.ExecutionDataResponse) error {
+				t.Error("execution data should not be sent")
+				return nil
+			},
+		}
+		err := s.SubscribeExecutionData(&extended.SubscribeExecutionDataRequest{StartHeight: mocks.GenericHeight}, stream)
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+
+	t.Run("handles send failure", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = baselineIndex(t)
+
+		stream := &executionDataStream{
+			ctx: context.Background(),
+			send: func(*extended.ExecutionDataResponse) error {
+				return mocks.GenericError
+			},
+		}
+		err := s.SubscribeExecutionData(&extended.SubscribeExecutionDataRequest{StartHeight: mocks.GenericHeight}, stream)
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+}
+
+func TestServer_ExecuteScriptAtBlockID(t *testing.T) {
+	blockID := mocks.GenericHeader.ID()
+
+	cadenceValue := cadence.NewUInt64(mocks.GenericHeight)
+	cadenceValueBytes, err := json.Encode(cadenceValue)
+	require.NoError(t, err)
+
+	genericAmountBytes, err := json.Encode(mocks.GenericAmount(0))
+	require.NoError(t, err)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(height uint64, script []byte, parameters []cadence.Value) (cadence.Value, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			assert.Equal(t, mocks.GenericBytes, script)
+			assert.Equal(t, []cadence.Value{cadenceValue}, parameters)
+
+			return mocks.GenericAmount(0), nil
+		}
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(got flow.Identifier) (uint64, error) {
+			assert.Equal(t, blockID, got)
+
+			return mocks.GenericHeight, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtBlockIDRequest{
+			BlockId:   blockID[:],
+			Script:    mocks.GenericBytes,
+			Arguments: [][]byte{cadenceValueBytes},
+		}
+		resp, err := s.ExecuteScriptAtBlockID(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, genericAmountBytes, resp.Value)
+	})
+
+	t.Run("handles index failure", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.ExecuteScriptAtBlockIDRequest{
+			BlockId:   blockID[:],
+			Script:    mocks.GenericBytes,
+			Arguments: [][]byte{cadenceValueBytes},
+		}
+		_, err = s.ExecuteScriptAtBlockID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invoker failure", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtBlockIDRequest{
+			BlockId:   blockID[:],
+			Script:    mocks.GenericBytes,
+			Arguments: [][]byte{cadenceValueBytes},
+		}
+		_, err = s.ExecuteScriptAtBlockID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_ExecuteScriptAtLatestBlock(t *testing.T) {
+	cadenceValue := cadence.NewUInt64(mocks.GenericHeight)
+	cadenceValueBytes, err := json.Encode(cadenceValue)
+	require.NoError(t, err)
+
+	genericAmountBytes, err := json.Encode(mocks.GenericAmount(0))
+	require.NoError(t, err)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(height uint64, script []byte, parameters []cadence.Value) (cadence.Value, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			assert.Equal(t, mocks.GenericBytes, script)
+			assert.Equal(t, []cadence.Value{cadenceValue}, parameters)
+
+			return mocks.GenericAmount(0), nil
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		var stream headerStream
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), &stream)
+
+		req := &access.ExecuteScriptAtLatestBlockRequest{
+			Script:    mocks.GenericBytes,
+			Arguments: [][]byte{cadenceValueBytes},
+		}
+		resp, err := s.ExecuteScriptAtLatestBlock(ctx, req)
+
+		require.NoError(t, err)
+
+		assert.Equal(t, genericAmountBytes, resp.Value)
+		assert.Equal(t, []string{strconv.FormatUint(mocks.GenericHeight, 10)}, stream.header.Get(ScriptHeightHeader))
+	})
+
+	t.Run("executes at height resolved at call time", func(t *testing.T) {
+		t.Parallel()
+
+		// The index advances by one height every time the last height is read.
+		var last uint64
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight + atomic.AddUint64(&last, 1) - 1, nil
+		}
+
+		var executed uint64
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(height uint64, _ []byte, _ []cadence.Value) (cadence.Value, error) {
+			executed = height
+			return mocks.GenericAmount(0), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.invoker = invoker
+
+		var stream headerStream
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), &stream)
+
+		req := &access.ExecuteScriptAtLatestBlockRequest{
+			Script:    mocks.GenericBytes,
+			Arguments: [][]byte{cadenceValueBytes},
+		}
+		_, err := s.ExecuteScriptAtLatestBlock(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, executed)
+		assert.Equal(t, []string{strconv.FormatUint(mocks.GenericHeight, 10)}, stream.header.Get(ScriptHeightHeader))
+	})
+
+	t.Run("handles index failure", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.ExecuteScriptAtLatestBlockRequest{
+			Script:    mocks.GenericBytes,
+			Arguments: [][]byte{cadenceValueBytes},
+		}
+		_, err = s.ExecuteScriptAtLatestBlock(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invoker failure", func(t *testing.T) {
+		t.Parallel()
+
+		invoker := mocks.BaselineInvoker(t)
+		invoker.ScriptFunc = func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.invoker = invoker
+
+		req := &access.ExecuteScriptAtLatestBlockRequest{
+			Script:    mocks.GenericBytes,
+			Arguments: [][]byte{cadenceValueBytes},
+		}
+		_, err = s.ExecuteScriptAtLatestBlock(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_MaxStaleness(t *testing.T) {
+	// The upstream is 10 seconds ahead of the latest indexed block.
+	upstream := func(t *testing.T) *mockUpstream {
+		return &mockUpstream{
+			GetLatestBlockHeaderFunc: func(in *access.GetLatestBlockHeaderRequest) (*access.BlockHeaderResponse, error) {
+				assert.False(t, in.IsSealed)
+
+				timestamp := timestamppb.New(mocks.GenericHeader.Timestamp.Add(10 * time.Second))
+				return &access.BlockHeaderResponse{Block: &entities.BlockHeader{Timestamp: timestamp}}, nil
+			},
+		}
+	}
+	withStaleness := func(staleness string) context.Context {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MaxStalenessHeader, staleness))
+		return grpc.NewContextWithServerTransportStream(ctx, &headerStream{})
+	}
+
+	req := &access.ExecuteScriptAtLatestBlockRequest{
+		Script: mocks.GenericBytes,
+	}
+	fromUpstream := []byte("upstream")
+
+	t.Run("serves from index within staleness", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = upstream(t)
+		s.cfg.Mode = ModeHybrid
+
+		resp, err := s.ExecuteScriptAtLatestBlock(withStaleness("30s"), req)
+
+		require.NoError(t, err)
+		assert.NotEqual(t, fromUpstream, resp.Value)
+	})
+
+	t.Run("forwards scripts to upstream beyond staleness", func(t *testing.T) {
+		t.Parallel()
+
+		u := upstream(t)
+		u.ExecuteScriptAtLatestBlockFunc = func(in *access.ExecuteScriptAtLatestBlockRequest) (*access.ExecuteScriptResponse, error) {
+			assert.Equal(t, req.Script, in.Script)
+
+			return &access.ExecuteScriptResponse{Value: fromUpstream}, nil
+		}
+
+		s := baselineServer(t)
+		s.cfg.Upstream = u
+		s.cfg.Mode = ModeHybrid
+
+		resp, err := s.ExecuteScriptAtLatestBlock(withStaleness("5s"), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, fromUpstream, resp.Value)
+	})
+
+	t.Run("forwards accounts to upstream beyond staleness", func(t *testing.T) {
+		t.Parallel()
+
+		account := &entities.Account{Address: mocks.GenericAccount.Address[:]}
+		u := upstream(t)
+		u.GetAccountAtLatestBlockFunc = func(in *access.GetAccountAtLatestBlockRequest) (*access.AccountResponse, error) {
+			return &access.AccountResponse{Account: account}, nil
+		}
+
+		s := baselineServer(t)
+		s.cfg.Upstream = u
+		s.cfg.Mode = ModeHybrid
+
+		in := &access.GetAccountAtLatestBlockRequest{Address: mocks.GenericAccount.Address[:]}
+		resp, err := s.GetAccountAtLatestBlock(withStaleness("5s"), in)
+
+		require.NoError(t, err)
+		assert.Equal(t, account, resp.Account)
+	})
+
+	t.Run("forwards blocks to upstream beyond staleness", func(t *testing.T) {
+		t.Parallel()
+
+		block := &entities.Block{Height: mocks.GenericHeight + 10}
+		u := upstream(t)
+		u.GetLatestBlockFunc = func(in *access.GetLatestBlockRequest) (*access.BlockResponse, error) {
+			assert.True(t, in.FullBlockResponse)
+
+			return &access.BlockResponse{Block: block}, nil
+		}
+
+		s := baselineServer(t)
+		s.cfg.Upstream = u
+		s.cfg.Mode = ModeHybrid
+
+		in := &access.GetLatestBlockRequest{FullBlockResponse: true}
+		resp, err := s.GetLatestBlock(withStaleness("5s"), in)
+
+		require.NoError(t, err)
+		assert.Equal(t, block, resp.Block)
+	})
+
+	t.Run("serves from index without header", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = &mockUpstream{}
+		s.cfg.Mode = ModeHybrid
+
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), &headerStream{})
+		resp, err := s.ExecuteScriptAtLatestBlock(ctx, req)
+
+		require.NoError(t, err)
+		assert.NotEqual(t, fromUpstream, resp.Value)
+	})
+
+	t.Run("serves from index in archive-only mode", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = &mockUpstream{}
+
+		resp, err := s.ExecuteScriptAtLatestBlock(withStaleness("0s"), req)
+
+		require.NoError(t, err)
+		assert.NotEqual(t, fromUpstream, resp.Value)
+	})
+
+	t.Run("serves from index without upstream", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Mode = ModeHybrid
+
+		resp, err := s.ExecuteScriptAtLatestBlock(withStaleness("0s"), req)
+
+		require.NoError(t, err)
+		assert.NotEqual(t, fromUpstream, resp.Value)
+	})
+
+	t.Run("serves from index on upstream failure", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = &mockUpstream{
+			GetLatestBlockHeaderFunc: func(*access.GetLatestBlockHeaderRequest) (*access.BlockHeaderResponse, error) {
+				return nil, mocks.GenericError
+			},
+		}
+		s.cfg.Mode = ModeHybrid
+
+		failures := testutil.ToFloat64(stalenessCheckFailures)
+
+		resp, err := s.ExecuteScriptAtLatestBlock(withStaleness("0s"), req)
+
+		require.NoError(t, err)
+		assert.NotEqual(t, fromUpstream, resp.Value)
+		assert.GreaterOrEqual(t, testutil.ToFloat64(stalenessCheckFailures), failures+1)
+	})
+
+	t.Run("handles invalid header", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = upstream(t)
+		s.cfg.Mode = ModeHybrid
+
+		_, err := s.ExecuteScriptAtLatestBlock(withStaleness("soon"), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("handles index failure on Header", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.Upstream = upstream(t)
+		s.cfg.Mode = ModeHybrid
+
+		_, err := s.ExecuteScriptAtLatestBlock(withStaleness("5s"), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_GetLatestBlock(t *testing.T) {
+	header := mocks.GenericHeader
+	blockID := header.ID()
+	sealIDs := mocks.GenericSealIDs(6)
+	collIDs := mocks.GenericCollectionIDs(6)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var sealCalled, collCalled int
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(id flow.Identifier) (uint64, error) {
+			assert.Equal(t, blockID, id)
+
+			return header.Height, nil
+		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, header.Height, height)
+
+			return header, nil
+		}
+		index.SealsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Equal(t, header.Height, height)
+
+			return sealIDs, nil
+		}
+		index.SealFunc = func(sealID flow.Identifier) (*flow.Seal, error) {
+			assert.Contains(t, sealIDs, sealID)
+
+			seal := mocks.GenericSeal(sealCalled)
+			sealCalled++
+
+			return seal, nil
+		}
+		index.CollectionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Equal(t, header.Height, height)
+
+			return collIDs, nil
+		}
+		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
+			assert.Contains(t, collIDs, collID)
+
+			collection := mocks.GenericCollection(collCalled)
+			collCalled++
+
+			return collection, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetLatestBlockRequest{}
+		resp, err := s.GetLatestBlock(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, blockID[:], resp.Block.Id)
+		assert.Equal(t, header.Height, resp.Block.Height)
+		assert.Equal(t, header.ParentID[:], resp.Block.ParentId)
+		assert.NotZero(t, resp.Block.Timestamp)
+
+		for i, collID := range collIDs {
+			seal := mocks.GenericSeal(i)
+			wantSeal := &entities.BlockSeal{
+				BlockId:                    seal.BlockID[:],
+				ExecutionReceiptId:         seal.ResultID[:],
+				ExecutionReceiptSignatures: [][]byte{},
+			}
+			assert.Contains(t, resp.Block.BlockSeals, wantSeal)
+
+			guarantee := mocks.GenericGuarantee(i)
+			wantGuarantee := &entities.CollectionGuarantee{
+				CollectionId: collID[:],
+				Signatures:   [][]byte{guarantee.Signature},
+			}
+			assert.Contains(t, resp.Block.CollectionGuarantees, wantGuarantee)
+		}
+	})
+
+	t.Run("handles empty index with zero last height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, nil
+		}
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, badger.ErrKeyNotFound
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetLatestBlockRequest{}
+		_, err := s.GetLatestBlock(context.Background(), req)
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("handles empty index with missing last height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, badger.ErrKeyNotFound
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetLatestBlockRequest{}
+		_, err := s.GetLatestBlock(context.Background(), req)
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("handles indexer failure on Last", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetLatestBlockRequest{}
+		_, err := s.GetLatestBlock(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on Header", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetLatestBlockRequest{}
+		_, err := s.GetLatestBlock(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on SealsByHeight", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetLatestBlockRequest{}
+		_, err := s.GetLatestBlock(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on CollectionsByHeight", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetLatestBlockRequest{}
+		_, err := s.GetLatestBlock(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_GetBlockByID(t *testing.T) {
+	header := mocks.GenericHeader
+	blockID := header.ID()
+	sealIDs := mocks.GenericSealIDs(6)
+	collIDs := mocks.GenericCollectionIDs(6)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var sealCalled, collCalled int
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(gotBlockID flow.Identifier) (uint64, error) {
+			assert.Equal(t, blockID, gotBlockID)
+
+			return mocks.GenericHeight, nil
+		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, header.Height, height)
+
+			return mocks.GenericHeader, nil
+		}
+		index.SealsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Equal(t, header.Height, height)
+
+			return sealIDs, nil
+		}
+		index.SealFunc = func(sealID flow.Identifier) (*flow.Seal, error) {
+			assert.Contains(t, sealIDs, sealID)
+
+			seal := mocks.GenericSeal(sealCalled)
+			sealCalled++
+
+			return seal, nil
+		}
+		index.CollectionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Equal(t, header.Height, height)
+
+			return collIDs, nil
+		}
+		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
+			assert.Contains(t, collIDs, collID)
+
+			collection := mocks.GenericCollection(collCalled)
+			collCalled++
+
+			return collection, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByIDRequest{Id: blockID[:]}
+		resp, err := s.GetBlockByID(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, blockID[:], resp.Block.Id)
+		assert.Equal(t, header.Height, resp.Block.Height)
+		assert.Equal(t, header.ParentID[:], resp.Block.ParentId)
+		assert.NotZero(t, resp.Block.Timestamp)
+
+		for i, collID := range collIDs {
+			seal := mocks.GenericSeal(i)
+			wantSeal := &entities.BlockSeal{
+				BlockId:                    seal.BlockID[:],
+				ExecutionReceiptId:         seal.ResultID[:],
+				ExecutionReceiptSignatures: [][]byte{},
+			}
+			assert.Contains(t, resp.Block.BlockSeals, wantSeal)
+
+			guarantee := mocks.GenericGuarantee(i)
+			wantGuarantee := &entities.CollectionGuarantee{
+				CollectionId: collID[:],
+				Signatures:   [][]byte{guarantee.Signature},
+			}
+			assert.Contains(t, resp.Block.CollectionGuarantees, wantGuarantee)
+		}
+	})
+
+	t.Run("handles indexer failure on HeightForBlock", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByIDRequest{Id: blockID[:]}
+		_, err := s.GetBlockByID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on Header", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByIDRequest{Id: blockID[:]}
+		_, err := s.GetBlockByID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on SealsByHeight", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByIDRequest{Id: blockID[:]}
+		_, err := s.GetBlockByID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on Seal", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.SealFunc = func(flow.Identifier) (*flow.Seal, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByIDRequest{Id: blockID[:]}
+		_, err := s.GetBlockByID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on CollectionsByHeight", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByIDRequest{Id: blockID[:]}
+		_, err := s.GetBlockByID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on Guarantee", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.GuaranteeFunc = func(flow.Identifier) (*flow.CollectionGuarantee, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByIDRequest{Id: blockID[:]}
+		_, err := s.GetBlockByID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("looks up concurrent identical blocks once", func(t *testing.T) {
+		t.Parallel()
+
+		const requests = 10
+
+		var heightCalls, headerCalls uint32
+		heightRelease := make(chan struct{})
+		headerRelease := make(chan struct{})
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			atomic.AddUint32(&heightCalls, 1)
+			<-heightRelease
+
+			return header.Height, nil
+		}
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			atomic.AddUint32(&headerCalls, 1)
+			<-headerRelease
+
+			return header, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByIDRequest{Id: blockID[:]}
+
+		var wg sync.WaitGroup
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				resp, err := s.GetBlockByID(context.Background(), req)
+
+				assert.NoError(t, err)
+				assert.Equal(t, blockID[:], resp.GetBlock().GetId())
+			}()
+		}
+
+		// Give all requests the time to join each lookup in progress.
+		time.Sleep(100 * time.Millisecond)
+		close(heightRelease)
+		time.Sleep(100 * time.Millisecond)
+		close(headerRelease)
+		wg.Wait()
+
+		assert.Equal(t, uint32(1), atomic.LoadUint32(&heightCalls))
+		assert.Equal(t, uint32(1), atomic.LoadUint32(&headerCalls))
+	})
+
+	t.Run("looks up concurrent identical blocks once across request readers", func(t *testing.T) {
+		t.Parallel()
+
+		const requests = 10
+
+		// Each request gets its own reader from its request ID, like the
+		// readers that forward the request ID to the archive API, while the
+		// lookups all reach the same backend.
+		var mu sync.Mutex
+		ids := make(map[string]struct{})
+		var heightCalls uint32
+		release := make(chan struct{})
+
+		s := baselineServer(t)
+		s.cfg.NewIndex = RequestIndexFactory(s.index, func(id string) archive.Reader {
+			mu.Lock()
+			ids[id] = struct{}{}
+			mu.Unlock()
+
+			index := mocks.BaselineReader(t)
+			index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+				atomic.AddUint32(&heightCalls, 1)
+				<-release
+
+				return header.Height, nil
+			}
+			return index
+		})
+
+		listener := bufconn.Listen(1024 * 1024)
+		gsvr := grpc.NewServer(grpc.ChainUnaryInterceptor(
+			tags.UnaryServerInterceptor(),
+			middleware.RequestIDUnaryServerInterceptor(),
+		))
+		access.RegisterAccessAPIServer(gsvr, s)
+		go func() {
+			_ = gsvr.Serve(listener)
+		}()
+		defer gsvr.Stop()
+
+		dialer := func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}
+		conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer conn.Close()
+		client := access.NewAccessAPIClient(conn)
+
+		req := &access.GetBlockByIDRequest{Id: blockID[:]}
+
+		var wg sync.WaitGroup
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				resp, err := client.GetBlockByID(context.Background(), req)
+
+				assert.NoError(t, err)
+				assert.Equal(t, blockID[:], resp.GetBlock().GetId())
+			}()
+		}
+
+		// Give all requests the time to join the lookup in progress.
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Len(t, ids, requests)
+		assert.Equal(t, uint32(1), atomic.LoadUint32(&heightCalls))
+	})
+}
+
+func TestServer_GetBlockByHeight(t *testing.T) {
+	header := mocks.GenericHeader
+	blockID := header.ID()
+	sealIDs := mocks.GenericSealIDs(6)
+	collIDs := mocks.GenericCollectionIDs(6)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var sealCalled, collCalled int
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(gotBlockID flow.Identifier) (uint64, error) {
+			assert.Equal(t, blockID, gotBlockID)
+
+			return header.Height, nil
+		}
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, header.Height, height)
+
+			return header, nil
+		}
+		index.SealsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Equal(t, header.Height, height)
+
+			return sealIDs, nil
+		}
+		index.SealFunc = func(sealID flow.Identifier) (*flow.Seal, error) {
+			assert.Contains(t, sealIDs, sealID)
+
+			seal := mocks.GenericSeal(sealCalled)
+			sealCalled++
+
+			return seal, nil
+		}
+		index.CollectionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Equal(t, header.Height, height)
+
+			return collIDs, nil
+		}
+		index.CollectionFunc = func(collID flow.Identifier) (*flow.LightCollection, error) {
+			assert.Contains(t, collIDs, collID)
+
+			collection := mocks.GenericCollection(collCalled)
+			collCalled++
+
+			return collection, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, blockID[:], resp.Block.Id)
+		assert.Equal(t, mocks.GenericHeight, resp.Block.Height)
+		assert.Equal(t, mocks.GenericHeader.ParentID[:], resp.Block.ParentId)
+		assert.NotZero(t, resp.Block.Timestamp)
+
+		for i, collID := range collIDs {
+			seal := mocks.GenericSeal(i)
+			wantSeal := &entities.BlockSeal{
+				BlockId:                    seal.BlockID[:],
+				ExecutionReceiptId:         seal.ResultID[:],
+				ExecutionReceiptSignatures: [][]byte{},
+			}
+			assert.Contains(t, resp.Block.BlockSeals, wantSeal)
+
+			guarantee := mocks.GenericGuarantee(i)
+			wantGuarantee := &entities.CollectionGuarantee{
+				CollectionId: collID[:],
+				Signatures:   [][]byte{guarantee.Signature},
+			}
+			assert.Contains(t, resp.Block.CollectionGuarantees, wantGuarantee)
+		}
+	})
+
+	t.Run("handles indexer failure on Header", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		_, err := s.GetBlockByHeight(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on SealsByHeight", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		_, err := s.GetBlockByHeight(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles indexer failure on CollectionsByHeight", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		_, err := s.GetBlockByHeight(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("returns block with empty seals when unsealed blocks are allowed", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return []flow.Identifier{}, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.AllowUnsealedBlocks = true
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, blockID[:], resp.Block.Id)
+		assert.Empty(t, resp.Block.BlockSeals)
+	})
+
+	t.Run("returns unavailable when unsealed blocks are not allowed", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.SealsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return []flow.Identifier{}, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.AllowUnsealedBlocks = false
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		_, err := s.GetBlockByHeight(context.Background(), req)
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("returns sealed block when unsealed blocks are not allowed", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.AllowUnsealedBlocks = false
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Len(t, resp.Block.BlockSeals, len(mocks.GenericSealIDs(5)))
+	})
+
+	t.Run("zeroes seal signatures by default", func(t *testing.T) {
+		t.Parallel()
+
+		seal := mocks.GenericSeal(0)
+		seal.AggregatedApprovalSigs = []flow.AggregatedSignature{{
+			VerifierSignatures: []crypto.Signature{[]byte("signature")},
+			SignerIDs:          mocks.GenericBlockIDs(1),
+		}}
+		index := mocks.BaselineReader(t)
+		index.SealFunc = func(flow.Identifier) (*flow.Seal, error) {
+			return seal, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.Block.BlockSeals)
+		for _, got := range resp.Block.BlockSeals {
+			assert.Equal(t, [][]byte{}, got.ExecutionReceiptSignatures)
+			assert.Empty(t, got.AggregatedApprovalSigs)
+			assert.Empty(t, got.ResultId)
+			assert.Empty(t, got.FinalState)
+		}
+	})
+
+	t.Run("returns full seals when enabled", func(t *testing.T) {
+		t.Parallel()
+
+		seal := mocks.GenericSeal(0)
+		signerIDs := mocks.GenericBlockIDs(1)
+		seal.AggregatedApprovalSigs = []flow.AggregatedSignature{{
+			VerifierSignatures: []crypto.Signature{[]byte("signature")},
+			SignerIDs:          signerIDs,
+		}}
+		index := mocks.BaselineReader(t)
+		index.SealFunc = func(flow.Identifier) (*flow.Seal, error) {
+			return seal, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.FullSeals = true
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.Block.BlockSeals)
+		for _, got := range resp.Block.BlockSeals {
+			// Seals do not carry execution receipt signatures, so they are
+			// empty even for full seals, as they are on access nodes.
+			assert.Equal(t, [][]byte{}, got.ExecutionReceiptSignatures)
+			assert.Equal(t, seal.ResultID[:], got.ResultId)
+			assert.Equal(t, seal.FinalState[:], got.FinalState)
+			require.Len(t, got.AggregatedApprovalSigs, 1)
+			assert.Equal(t, [][]byte{[]byte("signature")}, got.AggregatedApprovalSigs[0].VerifierSignatures)
+			assert.Equal(t, [][]byte{signerIDs[0][:]}, got.AggregatedApprovalSigs[0].SignerIds)
+		}
+	})
+
+	t.Run("clamps invalid timestamps when validated", func(t *testing.T) {
+		t.Parallel()
+
+		corrupt := *header
+		corrupt.Timestamp = time.Time{}
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return &corrupt, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.ValidateTimestamps = true
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height, FullBlockResponse: true}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, minTimestamp, resp.Block.Timestamp.AsTime())
+		assert.Equal(t, minTimestamp, resp.Block.BlockHeader.Timestamp.AsTime())
+	})
+
+	t.Run("clamps far-future timestamps when validated", func(t *testing.T) {
+		t.Parallel()
+
+		corrupt := *header
+		corrupt.Timestamp = time.Now().AddDate(100, 0, 0)
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return &corrupt, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.ValidateTimestamps = true
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(maxTimestampSkew), resp.Block.Timestamp.AsTime(), time.Minute)
+	})
+
+	t.Run("returns invalid timestamps unless validated", func(t *testing.T) {
+		t.Parallel()
+
+		corrupt := *header
+		corrupt.Timestamp = time.Time{}
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return &corrupt, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.True(t, resp.Block.Timestamp.AsTime().IsZero())
+	})
+
+	t.Run("returns light block by default", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height, FullBlockResponse: false}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Nil(t, resp.Block.BlockHeader)
+		assert.Empty(t, resp.Block.ExecutionReceiptMetaList)
+		assert.Empty(t, resp.Block.ExecutionResultList)
+		require.NotEmpty(t, resp.Block.BlockSeals)
+		for _, got := range resp.Block.BlockSeals {
+			assert.Empty(t, got.ResultId)
+			assert.Empty(t, got.FinalState)
+		}
+	})
+
+	t.Run("returns full block when requested", func(t *testing.T) {
+		t.Parallel()
+
+		seal := mocks.GenericSeal(0)
+		index := mocks.BaselineReader(t)
+		index.SealFunc = func(flow.Identifier) (*flow.Seal, error) {
+			return seal, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height, FullBlockResponse: true}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotNil(t, resp.Block.BlockHeader)
+		assert.Equal(t, blockID[:], resp.Block.BlockHeader.Id)
+		assert.Equal(t, header.Height, resp.Block.BlockHeader.Height)
+		assert.Equal(t, header.PayloadHash[:], resp.Block.BlockHeader.PayloadHash)
+		assert.Equal(t, header.View, resp.Block.BlockHeader.View)
+
+		// Execution receipts and results are not indexed by the archive.
+		assert.Empty(t, resp.Block.ExecutionReceiptMetaList)
+		assert.Empty(t, resp.Block.ExecutionResultList)
+
+		require.NotEmpty(t, resp.Block.BlockSeals)
+		for _, got := range resp.Block.BlockSeals {
+			assert.Equal(t, seal.ResultID[:], got.ResultId)
+			assert.Equal(t, seal.FinalState[:], got.FinalState)
+		}
+	})
+
+	t.Run("does not serve full blocks from cache", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 1, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.BlockCache = cache.NewManager(0).NewLRU("blocks", 1_000_000)
+
+		light := &access.GetBlockByHeightRequest{Height: header.Height}
+		_, err := s.GetBlockByHeight(context.Background(), light)
+		require.NoError(t, err)
+
+		full := &access.GetBlockByHeightRequest{Height: header.Height, FullBlockResponse: true}
+		resp, err := s.GetBlockByHeight(context.Background(), full)
+		require.NoError(t, err)
+		assert.NotNil(t, resp.Block.BlockHeader)
+
+		// Full responses are not cached, so the light one is still served.
+		resp, err = s.GetBlockByHeight(context.Background(), light)
+		require.NoError(t, err)
+		assert.Nil(t, resp.Block.BlockHeader)
+	})
+
+	t.Run("fails on missing guarantee with strict block assembly", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return collIDs, nil
+		}
+		index.GuaranteeFunc = func(collID flow.Identifier) (*flow.CollectionGuarantee, error) {
+			if collID == collIDs[0] {
+				return nil, badger.ErrKeyNotFound
+			}
+			return mocks.GenericGuarantee(0), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		_, err := s.GetBlockByHeight(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("skips missing guarantees without strict block assembly", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 1, nil
+		}
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			return collIDs, nil
+		}
+		index.GuaranteeFunc = func(collID flow.Identifier) (*flow.CollectionGuarantee, error) {
+			if collID == collIDs[0] {
+				return nil, badger.ErrKeyNotFound
+			}
+			return mocks.GenericGuarantee(0), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.StrictBlockAssembly = false
+		s.cfg.BlockCache = cache.NewManager(0).NewLRU("blocks", 1_000_000)
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		resp, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Len(t, resp.Block.CollectionGuarantees, len(collIDs)-1)
+
+		// Incomplete blocks are not cached, so they are assembled again once
+		// the missing guarantee has been indexed.
+		_, ok := s.cachedBlock(header.Height)
+		assert.False(t, ok)
+	})
+
+	t.Run("fails on guarantee errors without strict block assembly", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.GuaranteeFunc = func(flow.Identifier) (*flow.CollectionGuarantee, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.StrictBlockAssembly = false
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		_, err := s.GetBlockByHeight(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("serves historical blocks from cache", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 1, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.BlockCache = cache.NewManager(0).NewLRU("blocks", 1_000_000)
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		want, err := s.GetBlockByHeight(context.Background(), req)
+		require.NoError(t, err)
+
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		got, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, want.Block.Id, got.Block.Id)
+		assert.Len(t, got.Block.BlockSeals, len(want.Block.BlockSeals))
+		assert.Len(t, got.Block.CollectionGuarantees, len(want.Block.CollectionGuarantees))
+	})
+
+	t.Run("serves historical blocks from disk cache after restart", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 1, nil
+		}
+
+		dir := t.TempDir()
+		disk, err := cache.NewDisk("disk", dir, 1_000_000, nil)
+		require.NoError(t, err)
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.DiskCache = disk
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		want, err := s.GetBlockByHeight(context.Background(), req)
+		require.NoError(t, err)
+		require.NoError(t, disk.Close())
+
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		disk, err = cache.NewDisk("disk", dir, 1_000_000, nil)
+		require.NoError(t, err)
+		defer disk.Close()
+
+		s = baselineServer(t)
+		s.index = index
+		s.cfg.BlockCache = cache.NewManager(0).NewLRU("blocks", 1_000_000)
+		s.cfg.DiskCache = disk
+
+		got, err := s.GetBlockByHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, want.Block.Id, got.Block.Id)
+		assert.Len(t, got.Block.BlockSeals, len(want.Block.BlockSeals))
+		assert.Len(t, got.Block.CollectionGuarantees, len(want.Block.CollectionGuarantees))
+
+		// Blocks read from disk are kept in memory as well.
+		_, ok := s.cfg.BlockCache.Get(header.Height)
+		assert.True(t, ok)
+	})
+
+	t.Run("does not cache latest block", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.BlockCache = cache.NewManager(0).NewLRU("blocks", 1_000_000)
+
+		req := &access.GetBlockByHeightRequest{Height: header.Height}
+		_, err := s.GetBlockByHeight(context.Background(), req)
+		require.NoError(t, err)
+
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		_, err = s.GetBlockByHeight(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("sends block statistics on request", func(t *testing.T) {
+		t.Parallel()
+
+		txIDs := mocks.GenericTransactionIDs(3)
+		computations := map[flow.Identifier]uint64{txIDs[0]: 10, txIDs[1]: 20, txIDs[2]: 30}
+
+		index := mocks.BaselineReader(t)
+		index.TransactionsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+
+			return txIDs, nil
+		}
+		index.ResultFunc = func(txID flow.Identifier) (*flow.TransactionResult, error) {
+			return &flow.TransactionResult{TransactionID: txID, ComputationUsed: computations[txID]}, nil
+		}
+		index.EventsFunc = func(height uint64, types ...flow.EventType) ([]flow.Event, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			assert.Empty(t, types)
+
+			return mocks.GenericEvents(5), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		var stream headerStream
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(BlockStatsHeader, "true"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+
+		_, err := s.GetBlockByHeight(ctx, &access.GetBlockByHeightRequest{Height: mocks.GenericHeight})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"3"}, stream.header.Get(BlockTransactionsHeader))
+		assert.Equal(t, []string{"5"}, stream.header.Get(BlockEventsHeader))
+		assert.Equal(t, []string{"60"}, stream.header.Get(BlockComputationHeader))
+	})
+
+	t.Run("does not send block statistics by default", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ResultFunc = func(flow.Identifier) (*flow.TransactionResult, error) {
+			t.Error("results should not be read")
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		var stream headerStream
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), &stream)
+
+		_, err := s.GetBlockByHeight(ctx, &access.GetBlockByHeightRequest{Height: mocks.GenericHeight})
+
+		require.NoError(t, err)
+		assert.Empty(t, stream.header)
+	})
+
+	t.Run("handles index failure on block statistics", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.ResultFunc = func(flow.Identifier) (*flow.TransactionResult, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(BlockStatsHeader, "true"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &headerStream{})
+
+		_, err := s.GetBlockByHeight(ctx, &access.GetBlockByHeightRequest{Height: mocks.GenericHeight})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_GetSealByBlockID(t *testing.T) {
+	header := mocks.GenericHeader
+	blockID := header.ID()
+
+	seal := mocks.GenericSeal(0)
+	seal.BlockID = blockID
+	sealID := seal.ID()
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(gotBlockID flow.Identifier) (uint64, error) {
+			assert.Equal(t, blockID, gotBlockID)
+
+			return header.Height, nil
+		}
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
+		index.SealsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			if height == header.Height+2 {
+				return []flow.Identifier{sealID}, nil
+			}
+
+			return mocks.GenericSealIDs(2), nil
+		}
+		index.SealFunc = func(gotSealID flow.Identifier) (*flow.Seal, error) {
+			if gotSealID == sealID {
+				return seal, nil
+			}
+
+			return mocks.GenericSeal(1), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetSealByBlockIDRequest{BlockId: blockID[:]}
+		resp, err := s.GetSealByBlockID(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, header.Height+2, resp.Height)
+		assert.Equal(t, blockID[:], resp.Seal.BlockId)
+		assert.Equal(t, seal.ResultID[:], resp.Seal.ResultId)
+		assert.Equal(t, seal.ResultID[:], resp.Seal.ExecutionReceiptId)
+	})
+
+	t.Run("handles missing seal", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetSealByBlockIDRequest{BlockId: blockID[:]}
+		_, err := s.GetSealByBlockID(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("does not look for the seal in the sealed block", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
+		index.SealsByHeightFunc = func(height uint64) ([]flow.Identifier, error) {
+			assert.Greater(t, height, header.Height)
+
+			return mocks.GenericSealIDs(2), nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetSealByBlockIDRequest{BlockId: blockID[:]}
+		_, err := s.GetSealByBlockID(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles unknown block ID", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, badger.ErrKeyNotFound
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetSealByBlockIDRequest{BlockId: blockID[:]}
+		_, err := s.GetSealByBlockID(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles indexer failure on HeightForBlock", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetSealByBlockIDRequest{BlockId: blockID[:]}
+		_, err := s.GetSealByBlockID(context.Background(), req)
+
+		assert.Error(t, err)
+		assert.NotEqual(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles indexer failure on Seal", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
+		index.SealFunc = func(flow.Identifier) (*flow.Seal, error) {
+			return nil, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetSealByBlockIDRequest{BlockId: blockID[:]}
+		_, err := s.GetSealByBlockID(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_GetStateCommitmentAtBlockHeight(t *testing.T) {
+	commit := mocks.GenericCommit(0)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.CommitFunc = func(height uint64) (flow.StateCommitment, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+
+			return commit, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetStateCommitmentAtBlockHeightRequest{BlockHeight: mocks.GenericHeight}
+		resp, err := s.GetStateCommitmentAtBlockHeight(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, resp.BlockHeight)
+		assert.Equal(t, commit[:], resp.StateCommitment)
+	})
+
+	t.Run("handles height above indexed range", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		req := &extended.GetStateCommitmentAtBlockHeightRequest{BlockHeight: mocks.GenericHeight + 1}
+		_, err := s.GetStateCommitmentAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+
+	t.Run("handles height below indexed range", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		req := &extended.GetStateCommitmentAtBlockHeightRequest{BlockHeight: mocks.GenericHeight - 1}
+		_, err := s.GetStateCommitmentAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+
+	t.Run("handles indexer failure on Commit", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return flow.DummyStateCommitment, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetStateCommitmentAtBlockHeightRequest{BlockHeight: mocks.GenericHeight}
+		_, err := s.GetStateCommitmentAtBlockHeight(context.Background(), req)
+
+		assert.Error(t, err)
+		assert.NotEqual(t, codes.OutOfRange, status.Code(err))
+	})
+}
+
+func TestServer_GetLatestHeights(t *testing.T) {
+	t.Run("nominal case without upstream", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		resp, err := s.GetLatestHeights(context.Background(), &extended.GetLatestHeightsRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, resp.SealedHeight)
+		assert.Nil(t, resp.FinalizedHeight)
+	})
+
+	t.Run("nominal case with upstream", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = &mockUpstream{
+			GetLatestBlockHeaderFunc: func(in *access.GetLatestBlockHeaderRequest) (*access.BlockHeaderResponse, error) {
+				assert.False(t, in.IsSealed)
+
+				return &access.BlockHeaderResponse{Block: &entities.BlockHeader{Height: mocks.GenericHeight + 10}}, nil
+			},
+		}
+
+		resp, err := s.GetLatestHeights(context.Background(), &extended.GetLatestHeightsRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, resp.SealedHeight)
+		require.NotNil(t, resp.FinalizedHeight)
+		assert.Equal(t, mocks.GenericHeight+10, *resp.FinalizedHeight)
+	})
+
+	t.Run("handles indexer failure on Last", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		_, err := s.GetLatestHeights(context.Background(), &extended.GetLatestHeightsRequest{})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles upstream failure", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = &mockUpstream{
+			GetLatestBlockHeaderFunc: func(*access.GetLatestBlockHeaderRequest) (*access.BlockHeaderResponse, error) {
+				return nil, mocks.GenericError
+			},
+		}
+
+		_, err := s.GetLatestHeights(context.Background(), &extended.GetLatestHeightsRequest{})
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func TestServer_GetIndexedHeightRange(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return mocks.GenericHeight - 10, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		resp, err := s.GetIndexedHeightRange(context.Background(), &extended.GetIndexedHeightRangeRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight-10, resp.First)
+		assert.Equal(t, mocks.GenericHeight, resp.Last)
+	})
+
+	t.Run("limits first height to window", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return mocks.GenericHeight - 10, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.HeightWindow = 5
+
+		resp, err := s.GetIndexedHeightRange(context.Background(), &extended.GetIndexedHeightRangeRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight-4, resp.First)
+		assert.Equal(t, mocks.GenericHeight, resp.Last)
+	})
+
+	t.Run("caches first height and refreshes last height", func(t *testing.T) {
+		t.Parallel()
+
+		var firsts, lasts int
+		last := mocks.GenericHeight
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			firsts++
+			return mocks.GenericHeight - 10, nil
+		}
+		index.LastFunc = func() (uint64, error) {
+			lasts++
+			last++
+			return last, nil
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.RangeRefresh = time.Hour
+
+		for i := 0; i < 3; i++ {
+			resp, err := s.GetIndexedHeightRange(context.Background(), &extended.GetIndexedHeightRangeRequest{})
+
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericHeight+1, resp.Last)
+		}
+		assert.Equal(t, 1, firsts)
+		assert.Equal(t, 1, lasts)
+
+		// Once the refresh interval has elapsed, the last height is read again.
+		s.lastRefresh = time.Now().Add(-time.Hour)
+
+		resp, err := s.GetIndexedHeightRange(context.Background(), &extended.GetIndexedHeightRangeRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight+2, resp.Last)
+		assert.Equal(t, 1, firsts)
+		assert.Equal(t, 2, lasts)
+	})
+
+	t.Run("handles empty index", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, badger.ErrKeyNotFound
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		_, err := s.GetIndexedHeightRange(context.Background(), &extended.GetIndexedHeightRangeRequest{})
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("handles indexer failure on First", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		_, err := s.GetIndexedHeightRange(context.Background(), &extended.GetIndexedHeightRangeRequest{})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_GetBlockHeadersByHeights(t *testing.T) {
+	header := mocks.GenericHeader
+	blockID := header.ID()
+
+	index := mocks.BaselineReader(t)
+	index.LastFunc = func() (uint64, error) {
+		return mocks.GenericHeight + 3, nil
+	}
+	index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+		switch height {
+		case mocks.GenericHeight + 1:
+			return nil, badger.ErrKeyNotFound
+		case mocks.GenericHeight + 2:
+			return nil, mocks.GenericError
+		}
+
+		return header, nil
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.BatchWorkers = 2
+
+		heights := []uint64{
+			mocks.GenericHeight,
+			mocks.GenericHeight + 3,
+			mocks.GenericHeight + 1,
+			mocks.GenericHeight + 2,
+			mocks.GenericHeight + 4,
+		}
+		req := &extended.GetBlockHeadersByHeightsRequest{Heights: heights}
+		resp, err := s.GetBlockHeadersByHeights(context.Background(), req)
+
+		require.NoError(t, err)
+		require.Len(t, resp.Results, len(heights))
+		for i, height := range heights {
+			assert.Equal(t, height, resp.Results[i].Height)
+		}
+		for _, result := range resp.Results[:2] {
+			assert.Zero(t, result.Code)
+			require.NotNil(t, result.Header)
+			assert.Equal(t, blockID[:], result.Header.Block.Id)
+		}
+		assert.Equal(t, uint32(codes.NotFound), resp.Results[2].Code)
+		assert.Nil(t, resp.Results[2].Header)
+		assert.Equal(t, uint32(codes.Internal), resp.Results[3].Code)
+		assert.NotEmpty(t, resp.Results[3].Error)
+		assert.Equal(t, uint32(codes.OutOfRange), resp.Results[4].Code)
+	})
+
+	t.Run("handles too many heights", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.MaxBatchSize = 2
+
+		req := &extended.GetBlockHeadersByHeightsRequest{Heights: []uint64{1, 2, 3}}
+		_, err := s.GetBlockHeadersByHeights(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("handles indexer failure on First", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+
+		req := &extended.GetBlockHeadersByHeightsRequest{Heights: []uint64{mocks.GenericHeight}}
+		_, err := s.GetBlockHeadersByHeights(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	t.Run("ends open streams", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		// The interceptor signals when the stream is being handled by the server.
+		started := make(chan struct{})
+		interceptor := func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			close(started)
+			return handler(srv, stream)
+		}
+
+		listener := bufconn.Listen(1024 * 1024)
+		gsvr := grpc.NewServer(grpc.StreamInterceptor(interceptor))
+		extended.RegisterExtendedAPIServer(gsvr, s)
+		go func() {
+			_ = gsvr.Serve(listener)
+		}()
+
+		dialer := func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}
+		conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// The stream is left open without sending any script, as a client that
+		// keeps its stream open for later scripts would.
+		stream, err := extended.NewExtendedAPIClient(conn).ExecuteScripts(context.Background())
+		require.NoError(t, err)
+		<-started
+
+		stopped := make(chan struct{})
+		go func() {
+			s.Shutdown()
+			gsvr.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			gsvr.Stop()
+			t.Fatal("graceful stop did not complete")
+		}
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("can be called more than once", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		s.Shutdown()
+		s.Shutdown()
+	})
+}
+
+func TestServer_HeightWindow(t *testing.T) {
+	index := mocks.BaselineReader(t)
+	index.LastFunc = func() (uint64, error) {
+		return mocks.GenericHeight + 10, nil
+	}
+
+	t.Run("serves the whole index without window", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+
+		first, last, err := s.heightRange(index)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, first)
+		assert.Equal(t, mocks.GenericHeight+10, last)
+	})
+
+	t.Run("serves the whole index with a larger window", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.HeightWindow = 11
+
+		first, last, err := s.heightRange(index)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, first)
+		assert.Equal(t, mocks.GenericHeight+10, last)
+	})
+
+	t.Run("serves the most recent heights with a smaller window", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.HeightWindow = 5
+
+		first, last, err := s.heightRange(index)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight+6, first)
+		assert.Equal(t, mocks.GenericHeight+10, last)
+	})
+
+	t.Run("rejects heights below the window", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.HeightWindow = 5
+
+		req := &access.GetBlockByHeightRequest{Height: mocks.GenericHeight + 5}
+		_, err := s.GetBlockByHeight(context.Background(), req)
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+		assert.Contains(t, err.Error(), fmt.Sprintf("[%d, %d]", mocks.GenericHeight+6, mocks.GenericHeight+10))
+	})
+
+	t.Run("rejects event ranges starting below the window", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.HeightWindow = 5
+
+		req := &access.GetEventsForHeightRangeRequest{StartHeight: mocks.GenericHeight, EndHeight: mocks.GenericHeight + 10}
+		_, err := s.GetEventsForHeightRange(context.Background(), req)
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+
+	t.Run("rejects scripts below the window", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.HeightWindow = 5
+
+		req := &access.ExecuteScriptAtBlockHeightRequest{BlockHeight: mocks.GenericHeight, Script: mocks.GenericBytes}
+		_, err := s.ExecuteScriptAtBlockHeight(context.Background(), req)
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+
+	t.Run("reports the window in latest heights", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.HeightWindow = 5
+
+		resp, err := s.GetLatestHeights(context.Background(), &extended.GetLatestHeightsRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight+6, resp.FirstHeight)
+		assert.Equal(t, mocks.GenericHeight+10, resp.SealedHeight)
+	})
+}
+
+func TestServer_IndexFactory(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "request")
+
+	index := mocks.BaselineReader(t)
+	index.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+		return mocks.GenericCommit(1), nil
+	}
+
+	s := baselineServer(t)
+	s.cfg.NewIndex = func(ctx context.Context) archive.Reader {
+		assert.Equal(t, "request", ctx.Value(key{}))
+		return index
+	}
+
+	req := &extended.GetStateCommitmentAtBlockHeightRequest{BlockHeight: mocks.GenericHeight}
+	resp, err := s.GetStateCommitmentAtBlockHeight(ctx, req)
+
+	require.NoError(t, err)
+	commit := mocks.GenericCommit(1)
+	assert.Equal(t, commit[:], resp.StateCommitment)
+}
+
+func TestServer_GetExecutionResultForBlockID(t *testing.T) {
+	s := baselineServer(t)
+
+	blockID := mocks.GenericHeader.ID()
+	req := &access.GetExecutionResultForBlockIDRequest{BlockId: blockID[:]}
+	_, err := s.GetExecutionResultForBlockID(context.Background(), req)
+
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestServer_Mode(t *testing.T) {
+	header := &access.BlockHeaderResponse{Block: &entities.BlockHeader{Height: mocks.GenericHeight}}
+	upstream := &mockUpstream{
+		GetLatestBlockHeaderFunc: func(*access.GetLatestBlockHeaderRequest) (*access.BlockHeaderResponse, error) {
+			return header, nil
+		},
+		GetBlockHeaderByIDFunc: func(*access.GetBlockHeaderByIDRequest) (*access.BlockHeaderResponse, error) {
+			return header, nil
+		},
+		GetBlockHeaderByHeightFunc: func(*access.GetBlockHeaderByHeightRequest) (*access.BlockHeaderResponse, error) {
+			return header, nil
+		},
+		GetExecutionResultForBlockIDFunc: func(*access.GetExecutionResultForBlockIDRequest) (*access.ExecutionResultForBlockIDResponse, error) {
+			return &access.ExecutionResultForBlockIDResponse{}, nil
+		},
+		SendTransactionFunc: func(*access.SendTransactionRequest) (*access.SendTransactionResponse, error) {
+			return &access.SendTransactionResponse{Id: mocks.GenericBytes}, nil
+		},
+		GetLatestProtocolStateSnapshotFunc: func(*access.GetLatestProtocolStateSnapshotRequest) (*access.ProtocolStateSnapshotResponse, error) {
+			return &access.ProtocolStateSnapshotResponse{SerializedSnapshot: mocks.GenericBytes}, nil
+		},
+	}
+
+	// call calls each of the methods that are not implemented by the archive,
+	// and returns their errors by method name.
+	call := func(s *Server) map[string]error {
+		ctx := context.Background()
+		errs := make(map[string]error)
+		_, errs["GetLatestBlockHeader"] = s.GetLatestBlockHeader(ctx, &access.GetLatestBlockHeaderRequest{})
+		_, errs["GetBlockHeaderByID"] = s.GetBlockHeaderByID(ctx, &access.GetBlockHeaderByIDRequest{})
+		_, errs["GetBlockHeaderByHeight"] = s.GetBlockHeaderByHeight(ctx, &access.GetBlockHeaderByHeightRequest{})
+		_, errs["GetExecutionResultForBlockID"] = s.GetExecutionResultForBlockID(ctx, &access.GetExecutionResultForBlockIDRequest{})
+		_, errs["SendTransaction"] = s.SendTransaction(ctx, &access.SendTransactionRequest{})
+		_, errs["GetLatestProtocolStateSnapshot"] = s.GetLatestProtocolStateSnapshot(ctx, &access.GetLatestProtocolStateSnapshotRequest{})
+		return errs
+	}
+
+	t.Run("archive-only mode returns unimplemented", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = upstream
+
+		for method, err := range call(s) {
+			assert.Equal(t, codes.Unimplemented, status.Code(err), method)
+		}
+	})
+
+	t.Run("hybrid mode proxies to upstream", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = upstream
+		s.cfg.Mode = ModeHybrid
+
+		for method, err := range call(s) {
+			assert.NoError(t, err, method)
+		}
+	})
+
+	t.Run("hybrid mode without upstream returns unimplemented", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Mode = ModeHybrid
+
+		for method, err := range call(s) {
+			assert.Equal(t, codes.Unimplemented, status.Code(err), method)
+		}
+	})
+
+	t.Run("overrides take precedence over mode", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = upstream
+		s.cfg.Mode = ModeHybrid
+		s.cfg.ProxyOverrides = map[string]bool{"SendTransaction": false}
+
+		errs := call(s)
+		assert.Equal(t, codes.Unimplemented, status.Code(errs["SendTransaction"]))
+		assert.NoError(t, errs["GetLatestBlockHeader"])
+
+		s = baselineServer(t)
+		s.cfg.Upstream = upstream
+		s.cfg.ProxyOverrides = map[string]bool{"SendTransaction": true}
+
+		errs = call(s)
+		assert.NoError(t, errs["SendTransaction"])
+		assert.Equal(t, codes.Unimplemented, status.Code(errs["GetLatestBlockHeader"]))
+	})
+
+	t.Run("submitter takes precedence over upstream", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Upstream = upstream
+		s.cfg.Mode = ModeHybrid
+		s.cfg.Submitter = submitterFunc(func(context.Context, *access.SendTransactionRequest) (*access.SendTransactionResponse, error) {
+			return nil, mocks.GenericError
+		})
+
+		_, err := s.SendTransaction(context.Background(), &access.SendTransactionRequest{})
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+}
+
+func TestServer_SendTransaction(t *testing.T) {
+	tx := mocks.GenericTransaction(0)
+	txID := tx.ID()
+	req := &access.SendTransactionRequest{Transaction: convert.TransactionToMessage(*tx)}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Submitter = submitterFunc(func(_ context.Context, in *access.SendTransactionRequest) (*access.SendTransactionResponse, error) {
+			assert.Equal(t, req, in)
+
+			return &access.SendTransactionResponse{Id: txID[:]}, nil
+		})
+
+		resp, err := s.SendTransaction(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, txID[:], resp.Id)
+	})
+
+	t.Run("handles submitter failure", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Submitter = submitterFunc(func(context.Context, *access.SendTransactionRequest) (*access.SendTransactionResponse, error) {
+			return nil, status.Error(codes.Unavailable, "unavailable")
+		})
+
+		_, err := s.SendTransaction(context.Background(), req)
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("handles missing submitter", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+
+		_, err := s.SendTransaction(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_GetLatestProtocolStateSnapshot(t *testing.T) {
+	req := &access.GetLatestProtocolStateSnapshotRequest{}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Snapshots = snapshotSourceFunc(func(height uint64) ([]byte, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+
+			return mocks.GenericBytes, nil
+		})
+
+		resp, err := s.GetLatestProtocolStateSnapshot(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericBytes, resp.SerializedSnapshot)
+	})
+
+	t.Run("serves snapshot source instead of upstream", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Mode = ModeHybrid
+		s.cfg.Upstream = &mockUpstream{
+			GetLatestProtocolStateSnapshotFunc: func(*access.GetLatestProtocolStateSnapshotRequest) (*access.ProtocolStateSnapshotResponse, error) {
+				t.Error("request should not be proxied")
+				return nil, nil
+			},
+		}
+		s.cfg.Snapshots = snapshotSourceFunc(func(uint64) ([]byte, error) {
+			return mocks.GenericBytes, nil
+		})
+
+		resp, err := s.GetLatestProtocolStateSnapshot(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericBytes, resp.SerializedSnapshot)
+	})
+
+	t.Run("handles missing snapshot", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Snapshots = snapshotSourceFunc(func(uint64) ([]byte, error) {
+			return nil, badger.ErrKeyNotFound
+		})
+
+		_, err := s.GetLatestProtocolStateSnapshot(context.Background(), req)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("handles snapshot source failure", func(t *testing.T) {
+		t.Parallel()
+
+		s := baselineServer(t)
+		s.cfg.Snapshots = snapshotSourceFunc(func(uint64) ([]byte, error) {
+			return nil, mocks.GenericError
+		})
+
+		_, err := s.GetLatestProtocolStateSnapshot(context.Background(), req)
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+
+	t.Run("handles indexer failure", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := baselineServer(t)
+		s.index = index
+		s.cfg.Snapshots = snapshotSourceFunc(func(uint64) ([]byte, error) {
+			t.Error("snapshot should not be looked up")
+			return nil, nil
+		})
+
+		_, err := s.GetLatestProtocolStateSnapshot(context.Background(), req)
+
+		assert.Error(t, err)
+	})
+}
+
+func baselineServer(t *testing.T) *Server {
+	t.Helper()
 
 	s := Server{
-		codec:   mocks.BaselineCodec(t),
-		index:   mocks.BaselineReader(t),
-		invoker: mocks.BaselineInvoker(t),
+		cfg:      DefaultConfig,
+		codec:    mocks.BaselineCodec(t),
+		index:    mocks.BaselineReader(t),
+		invoker:  mocks.BaselineInvoker(t),
+		scripts:  make(chan struct{}, DefaultConfig.ScriptWorkers),
+		shutdown: make(chan struct{}),
 	}
 
 	return &s
 }
+
+// shuffledEvents returns events for three transactions with three events each,
+// in an order unrelated to their indices, as they can be returned by the index.
+func shuffledEvents() []flow.Event {
+	var events []flow.Event
+	for _, eventIndex := range []uint32{2, 0, 1} {
+		for _, txIndex := range []uint32{1, 2, 0} {
+			event := flow.Event{
+				Type:             mocks.GenericEventType(0),
+				TransactionID:    mocks.GenericTransactionIDs(3)[txIndex],
+				TransactionIndex: txIndex,
+				EventIndex:       eventIndex,
+			}
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// varyingEvents returns a function that returns the events of shuffledEvents
+// in a different order on every other call, like an index that does not
+// guarantee any order.
+func varyingEvents() func(uint64, ...flow.EventType) ([]flow.Event, error) {
+	var calls uint32
+	return func(uint64, ...flow.EventType) ([]flow.Event, error) {
+		events := shuffledEvents()
+		if atomic.AddUint32(&calls, 1)%2 == 0 {
+			for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+				events[i], events[j] = events[j], events[i]
+			}
+		}
+
+		return events, nil
+	}
+}
+
+// assertEventOrder asserts that the given events are ordered by transaction
+// index, and then by event index within each transaction.
+func assertEventOrder(t *testing.T, events []*entities.Event) {
+	t.Helper()
+
+	for i := 1; i < len(events); i++ {
+		prev, next := events[i-1], events[i]
+		if prev.TransactionIndex == next.TransactionIndex {
+			assert.Less(t, prev.EventIndex, next.EventIndex)
+			continue
+		}
+		assert.Less(t, prev.TransactionIndex, next.TransactionIndex)
+	}
+}
+
+// headerStream is a fake server transport stream that records the headers set
+// by handlers.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (h *headerStream) Method() string {
+	return ""
+}
+
+func (h *headerStream) SetHeader(md metadata.MD) error {
+	h.header = metadata.Join(h.header, md)
+	return nil
+}
+
+func (h *headerStream) SendHeader(md metadata.MD) error {
+	return h.SetHeader(md)
+}
+
+func (h *headerStream) SetTrailer(metadata.MD) error {
+	return nil
+}
+
+// scriptStream is a fake script stream that receives the given requests and
+// records the responses sent on it.
+type scriptStream struct {
+	grpc.ServerStream
+
+	ctx       context.Context
+	requests  []*extended.ExecuteScriptsRequest
+	recvErr   error
+	sendErr   error
+	responses []*extended.ExecuteScriptsResponse
+}
+
+func (s *scriptStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *scriptStream) Recv() (*extended.ExecuteScriptsRequest, error) {
+	if s.recvErr != nil {
+		return nil, s.recvErr
+	}
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+
+	return req, nil
+}
+
+func (s *scriptStream) Send(resp *extended.ExecuteScriptsResponse) error {
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+
+	s.responses = append(s.responses, resp)
+
+	return nil
+}
+
+type executionDataStream struct {
+	grpc.ServerStream
+
+	ctx  context.Context
+	send func(*extended.ExecutionDataResponse) error
+}
+
+func (s *executionDataStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *executionDataStream) Send(resp *extended.ExecutionDataResponse) error {
+	return s.send(resp)
+}
+
+type submitterFunc func(ctx context.Context, in *access.SendTransactionRequest) (*access.SendTransactionResponse, error)
+
+func (s submitterFunc) SendTransaction(ctx context.Context, in *access.SendTransactionRequest) (*access.SendTransactionResponse, error) {
+	return s(ctx, in)
+}
+
+type snapshotSourceFunc func(height uint64) ([]byte, error)
+
+func (s snapshotSourceFunc) SnapshotAtHeight(height uint64) ([]byte, error) {
+	return s(height)
+}
+
+// mockUpstream is a fake upstream access node, whose methods call the
+// corresponding functions.
+type mockUpstream struct {
+	GetLatestBlockHeaderFunc           func(in *access.GetLatestBlockHeaderRequest) (*access.BlockHeaderResponse, error)
+	GetBlockHeaderByIDFunc             func(in *access.GetBlockHeaderByIDRequest) (*access.BlockHeaderResponse, error)
+	GetBlockHeaderByHeightFunc         func(in *access.GetBlockHeaderByHeightRequest) (*access.BlockHeaderResponse, error)
+	GetExecutionResultForBlockIDFunc   func(in *access.GetExecutionResultForBlockIDRequest) (*access.ExecutionResultForBlockIDResponse, error)
+	SendTransactionFunc                func(in *access.SendTransactionRequest) (*access.SendTransactionResponse, error)
+	GetLatestProtocolStateSnapshotFunc func(in *access.GetLatestProtocolStateSnapshotRequest) (*access.ProtocolStateSnapshotResponse, error)
+	GetLatestBlockFunc                 func(in *access.GetLatestBlockRequest) (*access.BlockResponse, error)
+	GetAccountAtLatestBlockFunc        func(in *access.GetAccountAtLatestBlockRequest) (*access.AccountResponse, error)
+	ExecuteScriptAtLatestBlockFunc     func(in *access.ExecuteScriptAtLatestBlockRequest) (*access.ExecuteScriptResponse, error)
+}
+
+func (u *mockUpstream) GetLatestBlockHeader(_ context.Context, in *access.GetLatestBlockHeaderRequest, _ ...grpc.CallOption) (*access.BlockHeaderResponse, error) {
+	return u.GetLatestBlockHeaderFunc(in)
+}
+
+func (u *mockUpstream) GetBlockHeaderByID(_ context.Context, in *access.GetBlockHeaderByIDRequest, _ ...grpc.CallOption) (*access.BlockHeaderResponse, error) {
+	return u.GetBlockHeaderByIDFunc(in)
+}
+
+func (u *mockUpstream) GetBlockHeaderByHeight(_ context.Context, in *access.GetBlockHeaderByHeightRequest, _ ...grpc.CallOption) (*access.BlockHeaderResponse, error) {
+	return u.GetBlockHeaderByHeightFunc(in)
+}
+
+func (u *mockUpstream) GetExecutionResultForBlockID(_ context.Context, in *access.GetExecutionResultForBlockIDRequest, _ ...grpc.CallOption) (*access.ExecutionResultForBlockIDResponse, error) {
+	return u.GetExecutionResultForBlockIDFunc(in)
+}
+
+func (u *mockUpstream) SendTransaction(_ context.Context, in *access.SendTransactionRequest, _ ...grpc.CallOption) (*access.SendTransactionResponse, error) {
+	return u.SendTransactionFunc(in)
+}
+
+func (u *mockUpstream) GetLatestProtocolStateSnapshot(_ context.Context, in *access.GetLatestProtocolStateSnapshotRequest, _ ...grpc.CallOption) (*access.ProtocolStateSnapshotResponse, error) {
+	return u.GetLatestProtocolStateSnapshotFunc(in)
+}
+
+func (u *mockUpstream) GetLatestBlock(_ context.Context, in *access.GetLatestBlockRequest, _ ...grpc.CallOption) (*access.BlockResponse, error) {
+	return u.GetLatestBlockFunc(in)
+}
+
+func (u *mockUpstream) GetAccountAtLatestBlock(_ context.Context, in *access.GetAccountAtLatestBlockRequest, _ ...grpc.CallOption) (*access.AccountResponse, error) {
+	return u.GetAccountAtLatestBlockFunc(in)
+}
+
+func (u *mockUpstream) ExecuteScriptAtLatestBlock(_ context.Context, in *access.ExecuteScriptAtLatestBlockRequest, _ ...grpc.CallOption) (*access.ExecuteScriptResponse, error) {
+	return u.ExecuteScriptAtLatestBlockFunc(in)
+}
+
+func BenchmarkServer_GetTransactionResultsByBlockID(b *testing.B) {
+	const (
+		collections  = 20
+		transactions = 50
+	)
+
+	collIDs := mocks.GenericCollectionIDs(collections)
+	txIDs := mocks.GenericTransactionIDs(collections * transactions)
+	collMap := make(map[flow.Identifier]*flow.LightCollection, collections)
+	for i, collID := range collIDs {
+		collMap[collID] = &flow.LightCollection{
+			Transactions: txIDs[i*transactions : (i+1)*transactions],
+		}
+	}
+
+	index := &mocks.Reader{
+		HeightForBlockFunc: func(flow.Identifier) (uint64, error) {
+			return mocks.GenericHeight, nil
+		},
+		HeightForTransactionFunc: func(flow.Identifier) (uint64, error) {
+			return mocks.GenericHeight, nil
+		},
+		HeaderFunc: func(uint64) (*flow.Header, error) {
+			return mocks.GenericHeader, nil
+		},
+		LastFunc: func() (uint64, error) {
+			return mocks.GenericHeight, nil
+		},
+		TransactionsByHeightFunc: func(uint64) ([]flow.Identifier, error) {
+			return txIDs, nil
+		},
+		ResultFunc: func(txID flow.Identifier) (*flow.TransactionResult, error) {
+			return &flow.TransactionResult{TransactionID: txID}, nil
+		},
+		EventsFunc: func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return nil, nil
+		},
+		CollectionsByHeightFunc: func(uint64) ([]flow.Identifier, error) {
+			return collIDs, nil
+		},
+		CollectionFunc: func(collID flow.Identifier) (*flow.LightCollection, error) {
+			return collMap[collID], nil
+		},
+	}
+
+	s := Server{
+		cfg:   DefaultConfig,
+		index: index,
+	}
+
+	req := &access.GetTransactionsByBlockIDRequest{
+		BlockId: convert.IdentifierToMessage(mocks.GenericHeader.ID()),
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := s.GetTransactionResultsByBlockID(context.Background(), req)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkServer_GetEventsForHeightRange(b *testing.B) {
+	// Each response carries about 2MiB of events, over a link with a round-trip
+	// time of 20ms, so that flow-control windows limit the throughput.
+	const (
+		heights = 50
+		events  = 40
+		payload = 1024
+		latency = 10 * time.Millisecond
+	)
+
+	ee := make([]flow.Event, 0, events)
+	for i := 0; i < events; i++ {
+		event := flow.Event{
+			Type:       mocks.GenericEventType(0),
+			EventIndex: uint32(i),
+			Payload:    make([]byte, payload),
+		}
+		ee = append(ee, event)
+	}
+
+	index := &mocks.Reader{
+		EventsFunc: func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return ee, nil
+		},
+		HeaderFunc: func(uint64) (*flow.Header, error) {
+			return mocks.GenericHeader, nil
+		},
+	}
+
+	tests := []struct {
+		name   string
+		window int32
+	}{
+		{name: "minimum windows", window: 64 * 1024},
+		{name: "gRPC defaults"},
+		{name: "4MiB windows", window: 4 * 1024 * 1024},
+	}
+
+	for _, test := range tests {
+		test := test
+		b.Run(test.name, func(b *testing.B) {
+			var serverOptions []grpc.ServerOption
+			clientOptions := []grpc.DialOption{
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+				grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(64 * 1024 * 1024)),
+				grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+					conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+					if err != nil {
+						return nil, err
+					}
+					return newLatencyConn(conn, latency), nil
+				}),
+			}
+			if test.window > 0 {
+				serverOptions = append(serverOptions,
+					grpc.InitialWindowSize(test.window),
+					grpc.InitialConnWindowSize(test.window),
+				)
+				clientOptions = append(clientOptions,
+					grpc.WithInitialWindowSize(test.window),
+					grpc.WithInitialConnWindowSize(test.window),
+				)
+			}
+
+			s := Server{
+				cfg:   DefaultConfig,
+				index: index,
+			}
+			gsvr := grpc.NewServer(serverOptions...)
+			access.RegisterAccessAPIServer(gsvr, &s)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(b, err)
+			go func() {
+				_ = gsvr.Serve(latencyListener{Listener: listener, latency: latency})
+			}()
+			defer gsvr.Stop()
+
+			conn, err := grpc.Dial(listener.Addr().String(), clientOptions...)
+			require.NoError(b, err)
+			defer conn.Close()
+			client := access.NewAccessAPIClient(conn)
+
+			req := &access.GetEventsForHeightRangeRequest{
+				Type:        string(mocks.GenericEventType(0)),
+				StartHeight: mocks.GenericHeight,
+				EndHeight:   mocks.GenericHeight + heights - 1,
+			}
+
+			b.SetBytes(heights * events * payload)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := client.GetEventsForHeightRange(context.Background(), req)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+func BenchmarkServer_Compression(b *testing.B) {
+	// Events carry JSON-CDC payloads, which compress well, while script results
+	// are a few bytes long. The bytes received by the client are reported along
+	// with the time of each request, to weigh the bandwidth saved against the
+	// time spent compressing.
+	const (
+		heights = 10
+		events  = 40
+	)
+
+	txIDs := mocks.GenericTransactionIDs(events)
+	ee := make([]flow.Event, 0, events)
+	for i := 0; i < events; i++ {
+		event := flow.Event{
+			Type:          mocks.GenericEventType(0),
+			TransactionID: txIDs[i],
+			EventIndex:    uint32(i),
+			Payload:       json.MustEncode(mocks.GenericCadenceEvent(i)),
+		}
+		ee = append(ee, event)
+	}
+
+	index := &mocks.Reader{
+		FirstFunc: func() (uint64, error) {
+			return mocks.GenericHeight, nil
+		},
+		LastFunc: func() (uint64, error) {
+			return mocks.GenericHeight + heights, nil
+		},
+		EventsFunc: func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			return ee, nil
+		},
+		HeaderFunc: func(uint64) (*flow.Header, error) {
+			return mocks.GenericHeader, nil
+		},
+	}
+	invoker := &mocks.Invoker{
+		ScriptFunc: func(uint64, []byte, []cadence.Value) (cadence.Value, error) {
+			return mocks.GenericAmount(0), nil
+		},
+	}
+
+	s := Server{
+		cfg:      DefaultConfig,
+		index:    index,
+		invoker:  invoker,
+		scripts:  make(chan struct{}, DefaultConfig.ScriptWorkers),
+		shutdown: make(chan struct{}),
+	}
+	// One server compresses responses like their requests, while the other
+	// applies the default compression of each method.
+	serve := func(opts ...grpc.ServerOption) string {
+		gsvr := grpc.NewServer(opts...)
+		access.RegisterAccessAPIServer(gsvr, &s)
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(b, err)
+		go func() {
+			_ = gsvr.Serve(listener)
+		}()
+		b.Cleanup(gsvr.Stop)
+
+		return listener.Addr().String()
+	}
+	plain := serve()
+	compressor := middleware.NewCompressor(middleware.DefaultCompressedMethods)
+	defaults := serve(grpc.UnaryInterceptor(compressor.UnaryServerInterceptor()))
+
+	requests := []struct {
+		name string
+		call func(client access.AccessAPIClient, opts ...grpc.CallOption) error
+	}{
+		{
+			name: "events",
+			call: func(client access.AccessAPIClient, opts ...grpc.CallOption) error {
+				req := &access.GetEventsForHeightRangeRequest{
+					Type:        string(mocks.GenericEventType(0)),
+					StartHeight: mocks.GenericHeight,
+					EndHeight:   mocks.GenericHeight + heights - 1,
+				}
+				_, err := client.GetEventsForHeightRange(context.Background(), req, opts...)
+				return err
+			},
+		},
+		{
+			name: "script",
+			call: func(client access.AccessAPIClient, opts ...grpc.CallOption) error {
+				req := &access.ExecuteScriptAtBlockHeightRequest{
+					BlockHeight: mocks.GenericHeight,
+					Script:      mocks.GenericBytes,
+				}
+				_, err := client.ExecuteScriptAtBlockHeight(context.Background(), req, opts...)
+				return err
+			},
+		},
+	}
+
+	// Requests are either sent uncompressed, compressed with gzip, or
+	// uncompressed to the server that applies the default compression.
+	variants := []struct {
+		name    string
+		address string
+		opts    []grpc.CallOption
+	}{
+		{name: "uncompressed", address: plain},
+		{name: gzip.Name, address: plain, opts: []grpc.CallOption{grpc.UseCompressor(gzip.Name)}},
+		{name: "defaults", address: defaults},
+	}
+
+	for _, request := range requests {
+		request := request
+		for _, variant := range variants {
+			variant := variant
+			opts := variant.opts
+
+			b.Run(request.name+"/"+variant.name, func(b *testing.B) {
+				var received uint64
+				dialer := func(ctx context.Context, address string) (net.Conn, error) {
+					conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+					if err != nil {
+						return nil, err
+					}
+					return &countingConn{Conn: conn, received: &received}, nil
+				}
+				conn, err := grpc.Dial(variant.address, grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+				require.NoError(b, err)
+				defer conn.Close()
+				client := access.NewAccessAPIClient(conn)
+
+				// The connection is set up before the timer starts, so that
+				// only the bytes of the responses are counted.
+				err = request.call(client, opts...)
+				require.NoError(b, err)
+				atomic.StoreUint64(&received, 0)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					err := request.call(client, opts...)
+					require.NoError(b, err)
+				}
+				b.StopTimer()
+
+				b.ReportMetric(float64(atomic.LoadUint64(&received))/float64(b.N), "wire-B/op")
+			})
+		}
+	}
+}
+
+// countingConn counts the bytes read from the underlying connection.
+type countingConn struct {
+	net.Conn
+	received *uint64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddUint64(c.received, uint64(n))
+	return n, err
+}
+
+// latencyListener accepts connections that simulate a link with the given
+// one-way latency.
+type latencyListener struct {
+	net.Listener
+	latency time.Duration
+}
+
+func (l latencyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return newLatencyConn(conn, l.latency), nil
+}
+
+// latencyConn delays the data it receives by the given latency, without
+// limiting the bandwidth, by reading ahead from the underlying connection.
+type latencyConn struct {
+	net.Conn
+	latency time.Duration
+	chunks  chan latencyChunk
+	pending []byte
+	err     error
+}
+
+type latencyChunk struct {
+	data     []byte
+	received time.Time
+	err      error
+}
+
+func newLatencyConn(conn net.Conn, latency time.Duration) *latencyConn {
+	l := latencyConn{
+		Conn:    conn,
+		latency: latency,
+		chunks:  make(chan latencyChunk, 1024),
+	}
+
+	go func() {
+		for {
+			buf := make([]byte, 32*1024)
+			n, err := conn.Read(buf)
+			l.chunks <- latencyChunk{data: buf[:n], received: time.Now(), err: err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return &l
+}
+
+func (l *latencyConn) Read(p []byte) (int, error) {
+	if len(l.pending) == 0 {
+		if l.err != nil {
+			return 0, l.err
+		}
+
+		chunk := <-l.chunks
+		time.Sleep(time.Until(chunk.received.Add(l.latency)))
+		l.pending, l.err = chunk.data, chunk.err
+	}
+
+	if len(l.pending) == 0 {
+		return 0, l.err
+	}
+
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+
+	return n, nil
+}
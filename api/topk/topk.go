@@ -0,0 +1,144 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package topk
+
+import (
+	"container/heap"
+	"hash/maphash"
+	"sort"
+	"sync"
+)
+
+// Dimensions of the count-min sketch. With these, the count of a key is
+// overestimated by at most 0.07% of the total count, with a probability of
+// about 98%.
+const (
+	sketchDepth = 4
+	sketchWidth = 4096
+)
+
+// Entry is a key along with its estimated count.
+type Entry struct {
+	Key   string
+	Count uint64
+}
+
+// Counter estimates the number of occurrences of keys in a count-min sketch,
+// and keeps track of the keys with the highest counts. Its memory use is
+// bounded regardless of the number of distinct keys. Counts are estimates
+// that can be higher than the actual counts, but never lower.
+type Counter struct {
+	mu     sync.Mutex
+	seed   maphash.Seed
+	sketch [sketchDepth][sketchWidth]uint64
+	top    entries
+	k      int
+}
+
+// New creates a counter that keeps track of the `k` keys with the highest counts.
+func New(k uint) *Counter {
+	c := Counter{
+		seed: maphash.MakeSeed(),
+		top:  entries{index: make(map[string]int, k)},
+		k:    int(k),
+	}
+
+	return &c
+}
+
+// Add counts one occurrence of the given key.
+func (c *Counter) Add(key string) {
+	if c.k == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The positions of the key in each row of the sketch are derived from two
+	// halves of a single hash, which is as good as independent hashes.
+	hash := maphash.String(c.seed, key)
+	h1, h2 := hash&0xffffffff, hash>>32
+	count := ^uint64(0)
+	for row := uint64(0); row < sketchDepth; row++ {
+		column := (h1 + row*h2) % sketchWidth
+		c.sketch[row][column]++
+		if c.sketch[row][column] < count {
+			count = c.sketch[row][column]
+		}
+	}
+
+	i, ok := c.top.index[key]
+	switch {
+	case ok:
+		c.top.list[i].Count = count
+		heap.Fix(&c.top, i)
+	case c.top.Len() < c.k:
+		heap.Push(&c.top, Entry{Key: key, Count: count})
+	case count > c.top.list[0].Count:
+		delete(c.top.index, c.top.list[0].Key)
+		c.top.list[0] = Entry{Key: key, Count: count}
+		c.top.index[key] = 0
+		heap.Fix(&c.top, 0)
+	}
+}
+
+// Top returns the keys with the highest counts, from highest to lowest.
+func (c *Counter) Top() []Entry {
+	c.mu.Lock()
+	top := make([]Entry, len(c.top.list))
+	copy(top, c.top.list)
+	c.mu.Unlock()
+
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].Count > top[j].Count
+	})
+
+	return top
+}
+
+// entries is a min-heap of entries by count, which keeps track of the position
+// of each key in the heap, so that the count of a tracked key can be updated.
+type entries struct {
+	list  []Entry
+	index map[string]int
+}
+
+func (e entries) Len() int {
+	return len(e.list)
+}
+
+func (e entries) Less(i, j int) bool {
+	return e.list[i].Count < e.list[j].Count
+}
+
+func (e entries) Swap(i, j int) {
+	e.list[i], e.list[j] = e.list[j], e.list[i]
+	e.index[e.list[i].Key] = i
+	e.index[e.list[j].Key] = j
+}
+
+func (e *entries) Push(x interface{}) {
+	entry := x.(Entry)
+	e.index[entry.Key] = len(e.list)
+	e.list = append(e.list, entry)
+}
+
+func (e *entries) Pop() interface{} {
+	last := e.list[len(e.list)-1]
+	e.list = e.list[:len(e.list)-1]
+	delete(e.index, last.Key)
+	return last
+}
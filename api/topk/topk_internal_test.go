@@ -0,0 +1,89 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package topk
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounter(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		c := New(3)
+		for i := 0; i < 5; i++ {
+			c.Add("a")
+		}
+		for i := 0; i < 3; i++ {
+			c.Add("b")
+		}
+		c.Add("c")
+
+		top := c.Top()
+		require.Len(t, top, 3)
+		assert.Equal(t, Entry{Key: "a", Count: 5}, top[0])
+		assert.Equal(t, Entry{Key: "b", Count: 3}, top[1])
+		assert.Equal(t, Entry{Key: "c", Count: 1}, top[2])
+	})
+
+	t.Run("keeps only the keys with the highest counts", func(t *testing.T) {
+		t.Parallel()
+
+		c := New(2)
+		for i := 0; i < 100; i++ {
+			c.Add(fmt.Sprint(i))
+		}
+		for i := 0; i < 10; i++ {
+			c.Add("hot")
+			c.Add("warm")
+		}
+		c.Add("hot")
+
+		top := c.Top()
+		require.Len(t, top, 2)
+		assert.Equal(t, "hot", top[0].Key)
+		assert.Equal(t, "warm", top[1].Key)
+		assert.GreaterOrEqual(t, top[0].Count, uint64(11))
+		assert.GreaterOrEqual(t, top[1].Count, uint64(10))
+	})
+
+	t.Run("never underestimates counts", func(t *testing.T) {
+		t.Parallel()
+
+		c := New(1000)
+		for i := 0; i < 10_000; i++ {
+			c.Add(fmt.Sprint(i % 1000))
+		}
+
+		top := c.Top()
+		require.Len(t, top, 1000)
+		for _, entry := range top {
+			assert.GreaterOrEqual(t, entry.Count, uint64(10))
+		}
+	})
+
+	t.Run("does nothing with zero keys", func(t *testing.T) {
+		t.Parallel()
+
+		c := New(0)
+		c.Add("a")
+
+		assert.Empty(t, c.Top())
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package upstream
+
+import (
+	"context"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+// Client is the part of the Access API client used to submit transactions.
+type Client interface {
+	SendTransaction(ctx context.Context, in *access.SendTransactionRequest, opts ...grpc.CallOption) (*access.SendTransactionResponse, error)
+}
+
+// RoundRobin submits transactions to a set of upstream access nodes, picking a
+// different one for each transaction in turn. When an upstream is unavailable,
+// the transaction is retried on the next ones.
+type RoundRobin struct {
+	names   []string
+	clients []Client
+	next    atomic.Uint64
+}
+
+// NewRoundRobin creates a round-robin submitter over the given upstream clients.
+// The names identify the upstreams in metrics, and are usually their addresses.
+func NewRoundRobin(names []string, clients []Client) *RoundRobin {
+	r := RoundRobin{
+		names:   names,
+		clients: clients,
+	}
+
+	return &r
+}
+
+// SendTransaction submits the transaction to the next upstream, and returns the
+// response of the first upstream that accepts it. Only unavailable upstreams are
+// retried, as other errors are likely to be caused by the transaction itself.
+func (r *RoundRobin) SendTransaction(ctx context.Context, in *access.SendTransactionRequest) (*access.SendTransactionResponse, error) {
+	if len(r.clients) == 0 {
+		return nil, status.Error(codes.Unavailable, "no upstream access nodes configured")
+	}
+
+	start := r.next.Add(1) - 1
+	var err error
+	for attempt := 0; attempt < len(r.clients); attempt++ {
+		i := int((start + uint64(attempt)) % uint64(len(r.clients)))
+
+		var resp *access.SendTransactionResponse
+		resp, err = r.clients[i].SendTransaction(ctx, in)
+		if err == nil {
+			return resp, nil
+		}
+
+		code := status.Code(err)
+		submitErrors.WithLabelValues(r.names[i], code.String()).Inc()
+		if code != codes.Unavailable {
+			return nil, err
+		}
+	}
+
+	return nil, status.Errorf(codes.Unavailable, "all upstream access nodes are unavailable: %s", err)
+}
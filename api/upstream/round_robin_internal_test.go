@@ -0,0 +1,129 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package upstream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+func TestRoundRobin_SendTransaction(t *testing.T) {
+	req := &access.SendTransactionRequest{}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var calls []int
+		clients := make([]Client, 3)
+		for i := range clients {
+			i := i
+			clients[i] = clientFunc(func() (*access.SendTransactionResponse, error) {
+				calls = append(calls, i)
+				return &access.SendTransactionResponse{Id: []byte{byte(i)}}, nil
+			})
+		}
+		r := NewRoundRobin([]string{"a", "b", "c"}, clients)
+
+		for i := 0; i < 4; i++ {
+			resp, err := r.SendTransaction(context.Background(), req)
+
+			require.NoError(t, err)
+			assert.Equal(t, []byte{byte(i % 3)}, resp.Id)
+		}
+		assert.Equal(t, []int{0, 1, 2, 0}, calls)
+	})
+
+	t.Run("retries unavailable upstream", func(t *testing.T) {
+		t.Parallel()
+
+		failures := submitErrors.WithLabelValues("retry-a", codes.Unavailable.String())
+		before := testutil.ToFloat64(failures)
+
+		clients := []Client{
+			clientFunc(func() (*access.SendTransactionResponse, error) {
+				return nil, status.Error(codes.Unavailable, "unavailable")
+			}),
+			clientFunc(func() (*access.SendTransactionResponse, error) {
+				return &access.SendTransactionResponse{Id: []byte{1}}, nil
+			}),
+		}
+		r := NewRoundRobin([]string{"retry-a", "retry-b"}, clients)
+
+		resp, err := r.SendTransaction(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1}, resp.Id)
+		assert.Equal(t, before+1, testutil.ToFloat64(failures))
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		t.Parallel()
+
+		var called bool
+		clients := []Client{
+			clientFunc(func() (*access.SendTransactionResponse, error) {
+				return nil, status.Error(codes.InvalidArgument, "invalid signature")
+			}),
+			clientFunc(func() (*access.SendTransactionResponse, error) {
+				called = true
+				return &access.SendTransactionResponse{}, nil
+			}),
+		}
+		r := NewRoundRobin([]string{"a", "b"}, clients)
+
+		_, err := r.SendTransaction(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.False(t, called)
+	})
+
+	t.Run("handles all upstreams unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		unavailable := clientFunc(func() (*access.SendTransactionResponse, error) {
+			return nil, status.Error(codes.Unavailable, "unavailable")
+		})
+		r := NewRoundRobin([]string{"a", "b"}, []Client{unavailable, unavailable})
+
+		_, err := r.SendTransaction(context.Background(), req)
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("handles no upstreams", func(t *testing.T) {
+		t.Parallel()
+
+		r := NewRoundRobin(nil, nil)
+
+		_, err := r.SendTransaction(context.Background(), req)
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+type clientFunc func() (*access.SendTransactionResponse, error)
+
+func (c clientFunc) SendTransaction(context.Context, *access.SendTransactionRequest, ...grpc.CallOption) (*access.SendTransactionResponse, error) {
+	return c()
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+// Package workerpool provides a bounded and cancellable way of processing a set
+// of items concurrently, shared by the handlers that parallelize their work.
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// errPanicked cancels the remaining calls once one of them panicked.
+var errPanicked = errors.New("worker panicked")
+
+// Map calls `fn` for each of the given items, with at most `workers` calls in
+// flight at the same time, and returns their results in the order of the items.
+// A limit of zero means that all items are processed at once. The first error
+// cancels the context given to the other calls, stops the remaining items from
+// being processed, and is returned once all calls in flight have returned. A
+// panic in a call is handled like an error, and then raised again from Map, in
+// the goroutine of its caller, with the stack trace of the call.
+func Map[In any, Out any](parent context.Context, workers uint, items []In, fn func(ctx context.Context, item In) (Out, error)) ([]Out, error) {
+	group, ctx := errgroup.WithContext(parent)
+	if workers > 0 {
+		group.SetLimit(int(workers))
+	}
+
+	var (
+		mu       sync.Mutex
+		panicked interface{}
+	)
+
+	results := make([]Out, len(items))
+	for i, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+
+		i, item := i, item
+		group.Go(func() (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				mu.Lock()
+				if panicked == nil {
+					panicked = fmt.Sprintf("%v\n\n%s", r, debug.Stack())
+				}
+				mu.Unlock()
+				err = errPanicked
+			}()
+
+			err = ctx.Err()
+			if err != nil {
+				return err
+			}
+
+			result, err := fn(ctx, item)
+			if err != nil {
+				return err
+			}
+			results[i] = result
+
+			return nil
+		})
+	}
+
+	err := group.Wait()
+	if panicked != nil {
+		panic(panicked)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The parent context might have been canceled before all items were
+	// scheduled, without any of the calls failing.
+	err = parent.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
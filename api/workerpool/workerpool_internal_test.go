@@ -0,0 +1,129 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap(t *testing.T) {
+	t.Run("preserves ordering", func(t *testing.T) {
+		t.Parallel()
+
+		items := []int{5, 4, 3, 2, 1, 0}
+		results, err := Map(context.Background(), 3, items, func(_ context.Context, item int) (int, error) {
+			// Later items finish first, so that completion order differs from item order.
+			time.Sleep(time.Duration(item) * time.Millisecond)
+			return item * 10, nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []int{50, 40, 30, 20, 10, 0}, results)
+	})
+
+	t.Run("limits concurrency", func(t *testing.T) {
+		t.Parallel()
+
+		var inflight, peak atomic.Int32
+		items := make([]int, 20)
+		_, err := Map(context.Background(), 4, items, func(context.Context, int) (struct{}, error) {
+			current := inflight.Add(1)
+			defer inflight.Add(-1)
+			for {
+				max := peak.Load()
+				if current <= max || peak.CompareAndSwap(max, current) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return struct{}{}, nil
+		})
+
+		require.NoError(t, err)
+		assert.LessOrEqual(t, peak.Load(), int32(4))
+	})
+
+	t.Run("cancels on first error", func(t *testing.T) {
+		t.Parallel()
+
+		failure := errors.New("failure")
+		var calls atomic.Int32
+		items := make([]int, 100)
+		for i := range items {
+			items[i] = i
+		}
+		_, err := Map(context.Background(), 2, items, func(ctx context.Context, item int) (int, error) {
+			calls.Add(1)
+			if item == 1 {
+				return 0, failure
+			}
+
+			// Wait for cancellation, so that no other calls complete first.
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(time.Second):
+				return item, nil
+			}
+		})
+
+		assert.ErrorIs(t, err, failure)
+		assert.Less(t, calls.Load(), int32(len(items)))
+	})
+
+	t.Run("raises panics in the caller", func(t *testing.T) {
+		t.Parallel()
+
+		var value interface{}
+		func() {
+			defer func() {
+				value = recover()
+			}()
+
+			_, _ = Map(context.Background(), 2, []int{1, 2, 3}, func(_ context.Context, item int) (int, error) {
+				if item == 2 {
+					panic("failure")
+				}
+				return item, nil
+			})
+		}()
+
+		require.IsType(t, "", value)
+		assert.Contains(t, value, "failure")
+	})
+
+	t.Run("handles canceled context", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var calls atomic.Int32
+		_, err := Map(ctx, 2, []int{1, 2, 3}, func(context.Context, int) (int, error) {
+			calls.Add(1)
+			return 0, nil
+		})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, calls.Load())
+	})
+}
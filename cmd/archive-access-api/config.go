@@ -0,0 +1,128 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// envPrefix is the prefix of the environment variables that override the
+// configuration, e.g. ARCHIVE_ACCESS_CACHE_SIZE for the cache size.
+const envPrefix = "ARCHIVE_ACCESS"
+
+// loadConfig applies the configuration file at the given path, if any, and the
+// environment variables to the flags of the given set. Flags that were given on
+// the command line take precedence over environment variables, which in turn
+// take precedence over the configuration file. Keys of the configuration file
+// are the long names of the flags, and unknown keys are rejected.
+func loadConfig(flags *pflag.FlagSet, path string) error {
+	v := viper.New()
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+
+	if path != "" {
+		v.SetConfigFile(path)
+		err := v.ReadInConfig()
+		if err != nil {
+			return fmt.Errorf("could not read config file: %w", err)
+		}
+
+		var unknown []string
+		for key := range v.AllSettings() {
+			if flags.Lookup(key) == nil {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return fmt.Errorf("unknown config keys: %s", strings.Join(unknown, ", "))
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || !v.IsSet(flag.Name) {
+			return
+		}
+
+		value, valErr := configValue(v.Get(flag.Name))
+		if valErr != nil {
+			err = fmt.Errorf("invalid value for %s: %w", flag.Name, valErr)
+			return
+		}
+		setErr := flags.Set(flag.Name, value)
+		if setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, flag.Name, setErr)
+		}
+	})
+
+	return err
+}
+
+// configValue converts a value from the configuration file or the environment to
+// the string representation expected by the corresponding flag. Maps are not
+// supported, because the configuration keys are case-insensitive, while method
+// names are not; they have to be given as strings, like on the command line.
+func configValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "", fmt.Errorf("maps are not supported, use a string of comma-separated key=value pairs")
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// configSummary returns the effective values of all flags of the given set, by
+// their long names, once the configuration has been loaded. Credentials that are
+// embedded in URLs, such as passwords of upstream addresses, are redacted.
+func configSummary(flags *pflag.FlagSet) *zerolog.Event {
+	summary := zerolog.Dict()
+	flags.VisitAll(func(flag *pflag.Flag) {
+		summary.Str(flag.Name, redactValue(flag.Value.String()))
+	})
+	return summary
+}
+
+// redactValue replaces the passwords of the URLs within the given flag value,
+// which can be a comma-separated list, with a placeholder.
+func redactValue(value string) string {
+	items := strings.Split(value, ",")
+	for i, item := range items {
+		u, err := url.Parse(item)
+		if err != nil || u.User == nil {
+			continue
+		}
+		_, ok := u.User.Password()
+		if !ok {
+			continue
+		}
+		items[i] = u.Redacted()
+	}
+	return strings.Join(items, ",")
+}
@@ -15,30 +15,51 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip" // Responds to gzip-compressed requests in kind.
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 
 	grpczerolog "github.com/grpc-ecosystem/go-grpc-middleware/providers/zerolog/v2"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
 
+	"github.com/onflow/flow-go/model/flow"
 	"github.com/onflow/flow/protobuf/go/flow/access"
 
 	accessApi "github.com/onflow/flow-archive-access/api"
+	"github.com/onflow/flow-archive-access/api/admin"
+	"github.com/onflow/flow-archive-access/api/cache"
+	"github.com/onflow/flow-archive-access/api/extended"
+	accessHealth "github.com/onflow/flow-archive-access/api/health"
+	"github.com/onflow/flow-archive-access/api/middleware"
+	"github.com/onflow/flow-archive-access/api/topk"
+	"github.com/onflow/flow-archive-access/api/upstream"
 	archiveAPI "github.com/onflow/flow-archive/api/archive"
 	"github.com/onflow/flow-archive/codec/zbor"
+	"github.com/onflow/flow-archive/models/archive"
 	"github.com/onflow/flow-archive/service/invoker"
 )
 
@@ -47,6 +68,14 @@ const (
 	failure = 1
 )
 
+// unknownChain is logged as the chain ID when it cannot be resolved on startup.
+const unknownChain = "unknown"
+
+const (
+	registerCacheSize = 1_000_000
+	metricsShutdown   = 5 * time.Second
+)
+
 func main() {
 	os.Exit(run())
 }
@@ -59,23 +88,162 @@ func run() int {
 
 	// Command line parameter initialization.
 	var (
-		flagAddress string
-		flagArchive string
-		flagCache   uint64
-		flagLevel   string
+		flagAddress   string
+		flagConfig    string
+		flagMetrics   string
+		flagAdmin     string
+		flagScrape    string
+		flagArchive   string
+		flagCache     uint64
+		flagBlocks    uint64
+		flagDiskPath  string
+		flagDiskSize  uint64
+		flagTotal     uint64
+		flagLevel     string
+		flagRegisters uint
+		flagUnsealed  bool
+		flagSystemTx  bool
+		flagSeals     bool
+		flagAssembly  bool
+		flagStamps    bool
+		flagDupes     bool
+		flagSysChain  string
+		flagRoot      string
+		flagChainHdr  bool
+		flagDump      string
+		flagInflight  uint
+		flagLimits    map[string]int
+		flagWait      time.Duration
+		flagSlow      time.Duration
+		flagShutdown  time.Duration
+		flagDrain     time.Duration
+		flagShedRate  float64
+		flagShedLat   time.Duration
+		flagShedFrac  float64
+		flagShedWin   uint
+		flagShedRetry time.Duration
+		flagShedOps   []string
+		flagWorkers   uint
+		flagBatch     uint
+		flagBatchers  uint
+		flagBlockIDs  uint
+		flagWindow    uint64
+		flagPin       uint64
+		flagBudget    uint64
+		flagMeter     bool
+		flagRefresh   time.Duration
+		flagPoll      time.Duration
+		flagRetries   uint
+		flagBackoff   time.Duration
+		flagTopK      uint
+		flagAllowEvs  []string
+		flagDenyEvs   []string
+		flagDecode    bool
+		flagStrict    bool
+		flagArgStrict bool
+		flagMode      string
+		flagProxy     map[string]string
+		flagCompress  map[string]string
+		flagStreamWin int32
+		flagStreams   uint32
+		flagConnWin   int32
+		flagReadBuf   int
+		flagWriteBuf  int
+		flagSubmit    []string
+		flagUpstream  string
+		flagLiveness  string
+		flagReadiness string
+		flagReadyInt  time.Duration
+		flagReuse     bool
+		flagRecord    string
+		flagRedact    string
 	)
 
 	pflag.StringVarP(&flagAddress, "address", "a", "127.0.0.1:9000", "address to serve Access API on")
 	pflag.StringVarP(&flagArchive, "archive", "d", "127.0.0.1:80", "host URL for Archive API endpoint")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.BoolVar(&flagReuse, "reuse-port", false, "listen with SO_REUSEPORT, so that several processes can serve on the same address (where supported)")
+	pflag.StringVar(&flagAdmin, "admin-address", "", "address to serve the Admin API on, which should not be publicly reachable (disabled if empty)")
+	pflag.StringVar(&flagMetrics, "metrics-address", "", "address to serve Prometheus metrics on (disabled if empty)")
+	pflag.StringVar(&flagScrape, "metrics-path", "/metrics", "HTTP path to serve Prometheus metrics on")
+	pflag.StringVar(&flagRecord, "record", "", "path to a file to append received unary requests to, for replay with the validator (disabled if empty)")
+	pflag.StringVar(&flagRedact, "redaction-policy", "", "path to a JSON policy file with the fields and event types to redact from responses (disabled if empty)")
+	pflag.StringVar(&flagConfig, "config", "", "path to a configuration file with flag values, overridden by environment variables and flags")
 
 	pflag.Uint64Var(&flagCache, "cache-size", 1_000_000_000, "maximum cache size for register reads in bytes")
+	pflag.Uint64Var(&flagTotal, "total-cache-size", 0, "maximum total size of the response caches in bytes (0 for no global limit)")
+	pflag.Uint64Var(&flagBlocks, "block-cache-size", 100_000_000, "maximum cache size for block responses in bytes (0 to disable)")
+	pflag.StringVar(&flagDiskPath, "disk-cache-path", "", "path to a directory for a persistent cache of historical block responses, which survives restarts (disabled if empty)")
+	pflag.Uint64Var(&flagDiskSize, "disk-cache-size", 10_000_000_000, "maximum size of the responses in the disk cache in bytes (0 for unlimited)")
+	pflag.BoolVar(&flagUnsealed, "allow-unsealed-blocks", true, "return blocks without indexed seals instead of an unavailable error")
+	pflag.BoolVar(&flagSystemTx, "include-system-tx", true, "append the system chunk transaction to the transactions returned for a block")
+	pflag.StringVar(&flagSysChain, "system-tx-chain", "", "chain ID to build the system chunk transaction for, such as flow-emulator for custom networks (derived from the block header if empty)")
+	pflag.StringVar(&flagRoot, "trusted-root", "", "hexadecimal ID of the root block that the index has to start at, checked on startup (not checked if empty)")
+	pflag.BoolVar(&flagChainHdr, "chain-header", false, "send the chain ID of the served network in the x-chain-id header of every response")
+	pflag.StringVar(&flagDump, "dump-descriptors", "", "path to write the descriptor set of the served APIs to, printing their implemented and unimplemented methods, instead of serving them")
+	pflag.BoolVar(&flagSeals, "full-seals", false, "return the seals of blocks with their result ID, final state and aggregated approval signatures, like access nodes")
+	pflag.BoolVar(&flagAssembly, "strict-block-assembly", true, "fail requests for blocks that reference a collection whose guarantee is not indexed, instead of leaving the collection out")
+	pflag.BoolVar(&flagStamps, "validate-timestamps", false, "clamp block timestamps before 2019 or more than a day in the future, which come from corrupt index data, and log a warning")
+	pflag.BoolVar(&flagDupes, "resolve-duplicate-transactions", false, "look up all the blocks that can include a transaction, and use the first one that does, instead of the one the index maps it to (costs up to 600 reads per transaction lookup)")
+	pflag.StringSliceVar(&flagAllowEvs, "allow-event-types", nil, "event types that can be queried by the events endpoints, denying all others (all allowed if empty)")
+	pflag.StringSliceVar(&flagDenyEvs, "deny-event-types", nil, "event types that cannot be queried by the events endpoints, such as high-volume types")
+	pflag.BoolVar(&flagDecode, "decode-events", false, "serve events with their fields decoded from their payloads on GetDecodedEventsForHeightRange")
+	pflag.BoolVar(&flagStrict, "strict-event-decoding", false, "fail requests for decoded events when an event cannot be decoded, instead of returning it without decoded fields")
+	pflag.BoolVar(&flagArgStrict, "script-arg-strict", true, "decode script arguments strictly, instead of also accepting static types encoded as plain type IDs by other SDK versions")
+	pflag.Uint64Var(&flagWindow, "height-window", 0, "number of most recent heights served, for archives that prune older heights (0 to serve all indexed heights)")
+	pflag.Uint64Var(&flagPin, "pin-head", 0, "height at which the served view of the index is frozen, regardless of ongoing indexing (0 to follow the index)")
+	pflag.Uint64Var(&flagBudget, "request-budget", 0, "maximum number of register reads and events of a single script execution or event lookup (0 for no limit)")
+	pflag.BoolVar(&flagMeter, "meter-scripts", false, "execute each script with a dedicated invoker, to record the registers it reads in the per-script metrics")
+	pflag.Uint32Var(&flagStreams, "max-concurrent-streams", 100, "maximum number of concurrent streams, including unary requests, on each client connection (0 for unlimited)")
+	pflag.Int32Var(&flagStreamWin, "initial-window-size", 0, "flow-control window of each gRPC stream in bytes, at least 64KiB (0 for dynamic sizing by gRPC)")
+	pflag.Int32Var(&flagConnWin, "initial-conn-window-size", 0, "flow-control window of each gRPC connection in bytes, at least 64KiB (0 for dynamic sizing by gRPC)")
+	pflag.IntVar(&flagReadBuf, "read-buffer-size", 32*1024, "size of the read buffer of each gRPC connection in bytes")
+	pflag.IntVar(&flagWriteBuf, "write-buffer-size", 32*1024, "size of the write buffer of each gRPC connection in bytes")
+	pflag.UintVar(&flagTopK, "top-addresses", 0, "number of most requested account addresses tracked for the Admin API (0 to disable)")
+	pflag.UintVar(&flagRegisters, "max-registers", 1000, "maximum number of raw registers returned for an account")
+	pflag.UintVar(&flagInflight, "max-inflight", 0, "maximum number of concurrent requests per method (0 for unlimited)")
+	pflag.StringToIntVar(&flagLimits, "max-inflight-methods", nil, "maximum number of concurrent requests for specific methods, overriding the default (e.g. ExecuteScriptAtBlockHeight=10)")
+	pflag.UintVar(&flagWorkers, "script-workers", 8, "maximum number of concurrently executed scripts from script streams")
+	pflag.UintVar(&flagBatch, "max-batch-size", 1000, "maximum number of items requested at once from batch endpoints")
+	pflag.UintVar(&flagBlockIDs, "max-block-ids", 50, "maximum number of block IDs in a single GetEventsForBlockIDs request")
+	pflag.UintVar(&flagBatchers, "batch-workers", 16, "maximum number of items of a batch request looked up concurrently")
+	pflag.StringSliceVar(&flagSubmit, "submit-upstreams", nil, "addresses of access nodes to forward submitted transactions to, in round-robin order (disabled if empty)")
+	pflag.StringVar(&flagMode, "mode", string(accessApi.ModeArchiveOnly), "whether requests that cannot be served from the index are proxied to the upstream (archive-only or hybrid)")
+	pflag.StringToStringVar(&flagProxy, "proxy-methods", nil, "whether requests for specific methods are proxied to the upstream, overriding the mode (e.g. SendTransaction=true,GetLatestBlockHeader=false)")
+	pflag.StringToStringVar(&flagCompress, "compression-methods", nil, "whether responses to specific methods are compressed with gzip when clients accept it, overriding the defaults (e.g. GetEventsForHeightRange=false,GetAccount=true)")
+	pflag.StringVar(&flagUpstream, "upstream", "", "address of an access node to query for its latest finalized block, and to forward requests too stale for the index to (disabled if empty)")
+	pflag.StringVar(&flagLiveness, "liveness-service", "liveness", "health service name that is serving as long as the process runs")
+	pflag.StringVar(&flagReadiness, "readiness-service", "readiness", "health service name that is serving only while the archive index is reachable")
+	pflag.DurationVar(&flagReadyInt, "readiness-interval", 10*time.Second, "interval at which the archive index is checked for readiness")
+	pflag.DurationVar(&flagRefresh, "range-refresh-interval", time.Second, "interval at which the last indexed height returned by GetIndexedHeightRange is refreshed")
+	pflag.DurationVar(&flagPoll, "poll-interval", time.Second, "interval at which SubscribeExecutionData streams check the index for new heights")
+	pflag.UintVar(&flagRetries, "range-retries", 2, "maximum number of retries of the reads for a height of an event range when the archive is unavailable")
+	pflag.DurationVar(&flagBackoff, "range-backoff", 100*time.Millisecond, "delay before the first retry of the reads for a height of an event range, doubled for each following retry")
+	pflag.DurationVar(&flagSlow, "slow-request-threshold", 0, "duration above which a unary request is logged as slow, with its method and height (0 to disable)")
+	pflag.DurationVar(&flagShutdown, "shutdown-timeout", 30*time.Second, "maximum duration of a graceful shutdown, after which the remaining requests and streams are aborted")
+	pflag.DurationVar(&flagDrain, "drain-timeout", 20*time.Second, "duration that in-flight unary requests are given to complete on shutdown before they are aborted, which should be below the shutdown timeout")
+	pflag.DurationVar(&flagWait, "inflight-wait", 0, "maximum duration a request waits for a free slot before being rejected")
+	pflag.Float64Var(&flagShedRate, "shed-error-rate", 0, "share of recent requests failing with backend errors above which expensive requests are shed (0 to disable)")
+	pflag.DurationVar(&flagShedLat, "shed-latency", 0, "average duration of recent requests above which expensive requests are shed (0 to disable)")
+	pflag.Float64Var(&flagShedFrac, "shed-fraction", 0.5, "fraction of expensive requests that are shed while the backend is degraded")
+	pflag.UintVar(&flagShedWin, "shed-window", 100, "number of most recent requests whose outcomes are used to detect a degraded backend")
+	pflag.DurationVar(&flagShedRetry, "shed-retry", time.Second, "delay after which clients are told to retry shed requests")
+	pflag.StringSliceVar(&flagShedOps, "shed-methods", []string{"ExecuteScriptAtLatestBlock", "ExecuteScriptAtBlockID", "ExecuteScriptAtBlockHeight", "ExecuteScripts"}, "methods whose requests are shed while the backend is degraded")
 
 	pflag.Parse()
 
 	// Logger initialization.
 	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
 	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	// Configuration file and environment variables are applied to the flags
+	// that were not given on the command line.
+	err := loadConfig(pflag.CommandLine, flagConfig)
+	if err != nil {
+		log.Error().Str("config", flagConfig).Err(err).Msg("could not load configuration")
+		return failure
+	}
+
 	level, err := zerolog.ParseLevel(flagLevel)
 	if err != nil {
 		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
@@ -83,27 +251,62 @@ func run() int {
 	}
 	log = log.Level(level)
 
-	// Initialize codec.
-	codec := zbor.NewCodec()
+	// Summarize the effective configuration in a single line, so that the settings
+	// of a running deployment can be told at a glance.
+	log.Info().Dict("config", configSummary(pflag.CommandLine)).Msg("configuration")
 
-	// GRPC API initialization.
-	opts := []logging.Option{
-		logging.WithLevels(logging.DefaultServerCodeToLevel),
+	if !strings.HasPrefix(flagScrape, "/") {
+		log.Error().Str("metrics_path", flagScrape).Msg("metrics path must start with a slash")
+		return failure
 	}
-	gsvr := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			tags.UnaryServerInterceptor(),
-			logging.UnaryServerInterceptor(grpczerolog.InterceptorLogger(log), opts...),
-		),
-		grpc.ChainStreamInterceptor(
-			tags.StreamServerInterceptor(),
-			logging.StreamServerInterceptor(grpczerolog.InterceptorLogger(log), opts...),
-		),
-	)
 
-	// automatically add metrics with grpc_server_handled_total{grpc_code="Internal|Unknown|OK"}
-	grpc_prometheus.EnableHandlingTimeHistogram()
-	grpc_prometheus.Register(gsvr)
+	if flagWorkers == 0 {
+		log.Error().Msg("number of script workers must be positive")
+		return failure
+	}
+
+	if flagReadyInt <= 0 {
+		log.Error().Dur("interval", flagReadyInt).Msg("readiness interval must be positive")
+		return failure
+	}
+
+	if flagBatchers == 0 {
+		log.Error().Msg("number of batch workers must be positive")
+		return failure
+	}
+
+	if flagPoll <= 0 {
+		log.Error().Dur("interval", flagPoll).Msg("poll interval must be positive")
+		return failure
+	}
+	if flagShedFrac <= 0 || flagShedFrac > 1 {
+		log.Error().Float64("fraction", flagShedFrac).Msg("shed fraction must be above 0 and at most 1")
+		return failure
+	}
+	if flagShedWin == 0 {
+		log.Error().Msg("shed window must be positive")
+		return failure
+	}
+
+	if flagSysChain != "" {
+		_, err = accessApi.ParseChain(flow.ChainID(flagSysChain))
+		if err != nil {
+			log.Error().Str("system_tx_chain", flagSysChain).Err(err).Msg("could not parse system transaction chain")
+			return failure
+		}
+	}
+
+	var trusted flow.Identifier
+	if flagRoot != "" {
+		trusted, err = flow.HexStringToIdentifier(flagRoot)
+		if err != nil {
+			log.Error().Str("trusted_root", flagRoot).Err(err).Msg("could not parse trusted root block ID")
+			return failure
+		}
+	}
+
+	// Initialize codec.
+	codec := zbor.NewCodec()
 
 	// Initialize the API client.
 	conn, err := grpc.Dial(flagArchive, grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -116,28 +319,454 @@ func run() int {
 	client := archiveAPI.NewAPIClient(conn)
 	index := archiveAPI.IndexFromAPI(client, codec)
 
-	invoke, err := invoker.New(index, invoker.WithCacheSize(flagCache))
+	// Resolve the chain ID once from the first indexed block, before the logger
+	// is given to any component, so that every log line carries it and logs
+	// from several archive nodes can be told apart. As for the root block, an
+	// archive that cannot be reached yet, or an empty index, does not prevent
+	// the server from starting.
+	chainID, err := indexedChain(index)
+	if err != nil {
+		log.Warn().Err(err).Msg("could not resolve chain ID of index")
+		chainID = unknownChain
+	}
+	log = log.With().Str("chain", chainID).Logger()
+
+	// The root block of the index is checked against the trusted one, if given,
+	// so that the server never serves data from a wrong or tampered index.
+	// Without a trusted root, the root is only logged, and failing to read it
+	// does not prevent the server from starting.
+	rootID, err := accessApi.VerifyRoot(index, trusted)
+	switch {
+	case err != nil && flagRoot != "":
+		log.Error().Str("trusted_root", flagRoot).Err(err).Msg("could not verify root block of index")
+		return failure
+	case err != nil:
+		log.Warn().Err(err).Msg("could not detect root block of index")
+	default:
+		log.Info().Hex("root_id", rootID[:]).Bool("trusted", flagRoot != "").Msg("root block of index detected")
+	}
+
+	// Register reads of the shared invoker are counted in the metrics, so that
+	// the load that scripts put on the archive can be monitored.
+	invoke, err := invoker.New(accessApi.MeterRegisters(index), invoker.WithCacheSize(flagCache))
 	if err != nil {
 		log.Error().Err(err).Msg("could not initialize script invoker")
 		return failure
 	}
 
-	server := accessApi.NewServer(index, codec, invoke)
+	// Raw register lookups need a fresh invoker for each request, so that every
+	// register read is recorded rather than served from a warm cache.
+	factory := func(index archive.Reader) (accessApi.Invoker, error) {
+		return invoker.New(index, invoker.WithCacheSize(registerCacheSize))
+	}
+
+	// Calls to the archive are made through a dedicated index for each request
+	// that carries a request ID, so that the ID is propagated to the archive.
+	reader := accessApi.RequestIndexFactory(index, func(id string) archive.Reader {
+		return archiveAPI.IndexFromAPI(archiveAPI.NewAPIClient(middleware.RequestIDConn(conn, id)), codec)
+	})
+
+	// The mode sets whether all proxy-capable methods are proxied to the upstream
+	// access node, unless they are overridden individually.
+	mode, err := accessApi.ParseMode(flagMode)
+	if err != nil {
+		log.Error().Err(err).Msg("could not parse mode")
+		return failure
+	}
+	proxyable := make(map[string]struct{}, len(accessApi.ProxyMethods))
+	for _, method := range accessApi.ProxyMethods {
+		proxyable[method] = struct{}{}
+	}
+	overrides := make(map[string]bool, len(flagProxy))
+	for method, value := range flagProxy {
+		_, ok := proxyable[method]
+		if !ok {
+			log.Error().Str("method", method).Strs("supported", accessApi.ProxyMethods).Msg("method cannot be proxied")
+			return failure
+		}
+		proxy, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Error().Str("method", method).Str("value", value).Err(err).Msg("could not parse proxy override")
+			return failure
+		}
+		overrides[method] = proxy
+	}
+	// Responses are compressed by default for the methods that return large
+	// payloads, unless they are overridden individually.
+	compressed := make(map[string]bool, len(middleware.DefaultCompressedMethods)+len(flagCompress))
+	for method, compress := range middleware.DefaultCompressedMethods {
+		compressed[method] = compress
+	}
+	for method, value := range flagCompress {
+		compress, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Error().Str("method", method).Str("value", value).Err(err).Msg("could not parse compression override")
+			return failure
+		}
+		compressed[method] = compress
+	}
+	if mode == accessApi.ModeHybrid && flagUpstream == "" {
+		log.Warn().Msg("hybrid mode has no effect without an upstream access node")
+	}
+	if flagDrain >= flagShutdown {
+		log.Warn().Dur("drain_timeout", flagDrain).Dur("shutdown_timeout", flagShutdown).Msg("drain timeout is not below shutdown timeout, in-flight requests will be stopped before they are drained")
+	}
+
+	allowed := make([]flow.EventType, 0, len(flagAllowEvs))
+	for _, typ := range flagAllowEvs {
+		allowed = append(allowed, flow.EventType(typ))
+	}
+	denied := make([]flow.EventType, 0, len(flagDenyEvs))
+	for _, typ := range flagDenyEvs {
+		denied = append(denied, flow.EventType(typ))
+	}
+
+	options := []accessApi.Option{
+		accessApi.WithMaxRegisters(flagRegisters),
+		accessApi.WithInvokerFactory(factory),
+		accessApi.WithAllowUnsealedBlocks(flagUnsealed),
+		accessApi.WithScriptWorkers(flagWorkers),
+		accessApi.WithIndexFactory(reader),
+		accessApi.WithIncludeSystemTx(flagSystemTx),
+		accessApi.WithFullSeals(flagSeals),
+		accessApi.WithStrictBlockAssembly(flagAssembly),
+		accessApi.WithValidateTimestamps(flagStamps),
+		accessApi.WithResolveDuplicates(flagDupes),
+		accessApi.WithSystemTxChain(flow.ChainID(flagSysChain)),
+		accessApi.WithLogger(log),
+		accessApi.WithMaxBatchSize(flagBatch),
+		accessApi.WithBatchWorkers(flagBatchers),
+		accessApi.WithMaxBlockIDs(flagBlockIDs),
+		accessApi.WithHeightWindow(flagWindow),
+		accessApi.WithPinnedHead(flagPin),
+		accessApi.WithRequestBudget(flagBudget),
+		accessApi.WithScriptMetering(flagMeter),
+		accessApi.WithRangeRefresh(flagRefresh),
+		accessApi.WithPollInterval(flagPoll),
+		accessApi.WithRangeRetries(flagRetries, flagBackoff),
+		accessApi.WithEventTypes(allowed, denied),
+		accessApi.WithDecodeEvents(flagDecode),
+		accessApi.WithStrictDecoding(flagStrict),
+		accessApi.WithStrictArguments(flagArgStrict),
+		accessApi.WithMode(mode),
+		accessApi.WithProxyOverrides(overrides),
+	}
+
+	// Transactions are only accepted if there are access nodes to forward them to.
+	if len(flagSubmit) > 0 {
+		clients := make([]upstream.Client, 0, len(flagSubmit))
+		for _, address := range flagSubmit {
+			upstreamConn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				log.Error().Str("upstream", address).Err(err).Msg("could not dial upstream access node")
+				return failure
+			}
+			defer upstreamConn.Close()
+
+			clients = append(clients, access.NewAccessAPIClient(upstreamConn))
+		}
+		options = append(options, accessApi.WithSubmitter(upstream.NewRoundRobin(flagSubmit, clients)))
+	}
+
+	// The upstream access node, if any, provides the latest finalized block, and
+	// serves the requests that are proxied to it.
+	if flagUpstream != "" {
+		upstreamConn, err := grpc.Dial(flagUpstream, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Error().Str("upstream", flagUpstream).Err(err).Msg("could not dial upstream access node")
+			return failure
+		}
+		defer upstreamConn.Close()
+
+		options = append(options, accessApi.WithUpstream(access.NewAccessAPIClient(upstreamConn)))
+	}
+
+	// Historical blocks never change, so their responses can be cached. All
+	// caches share a global budget, on top of their individual limits.
+	caches := cache.NewManager(flagTotal)
+	if flagBlocks > 0 {
+		options = append(options, accessApi.WithBlockCache(caches.NewLRU("blocks", flagBlocks)))
+	}
+
+	// Historical block responses are also written through to disk, if enabled,
+	// so that they are not lost on restart. The entries are bound to the root
+	// block of the index, so that a cache directory reused for another network
+	// or spork is flushed instead of serving its blocks.
+	var disk *cache.Disk
+	switch {
+	case flagDiskPath != "" && rootID == flow.ZeroID:
+		log.Warn().Msg("disk cache disabled, as the root block of the index is unknown")
+	case flagDiskPath != "":
+		disk, err = cache.NewDisk("disk", flagDiskPath, flagDiskSize, rootID[:])
+		if err != nil {
+			log.Error().Str("disk_cache_path", flagDiskPath).Err(err).Msg("could not open disk cache")
+			return failure
+		}
+		defer disk.Close()
+
+		options = append(options, accessApi.WithDiskCache(disk))
+	}
+
+	// Requested addresses are counted in a sketch of bounded size, so that the
+	// most requested accounts can be inspected through the Admin API.
+	var addresses *topk.Counter
+	if flagTopK > 0 {
+		addresses = topk.New(flagTopK)
+		options = append(options, accessApi.WithAddressAccounting(addresses))
+	}
+
+	server := accessApi.NewServer(index, codec, invoke, options...)
+
+	// The descriptors are dumped with the same configuration as the served
+	// APIs, so that the methods listed as implemented are the ones that would
+	// be served, and the server then exits without serving them.
+	if flagDump != "" {
+		services := []protoreflect.FullName{"flow.access.AccessAPI", "flow.archive.access.ExtendedAPI"}
+		set, err := accessApi.DescriptorSet(services...)
+		if err != nil {
+			log.Error().Err(err).Msg("could not build descriptor set")
+			return failure
+		}
+		data, err := proto.Marshal(set)
+		if err != nil {
+			log.Error().Err(err).Msg("could not encode descriptor set")
+			return failure
+		}
+		err = os.WriteFile(flagDump, data, 0644)
+		if err != nil {
+			log.Error().Str("path", flagDump).Err(err).Msg("could not write descriptor set")
+			return failure
+		}
+
+		methods := struct {
+			Implemented   []string `json:"implemented"`
+			Unimplemented []string `json:"unimplemented"`
+		}{}
+		for _, name := range services {
+			descriptor, _ := protoregistry.GlobalFiles.FindDescriptorByName(name)
+			service := descriptor.(protoreflect.ServiceDescriptor)
+			for i := 0; i < service.Methods().Len(); i++ {
+				method := service.Methods().Get(i)
+				fullMethod := "/" + string(name) + "/" + string(method.Name())
+				if server.Implements(string(method.Name())) {
+					methods.Implemented = append(methods.Implemented, fullMethod)
+				} else {
+					methods.Unimplemented = append(methods.Unimplemented, fullMethod)
+				}
+			}
+		}
+		err = json.NewEncoder(os.Stdout).Encode(methods)
+		if err != nil {
+			log.Error().Err(err).Msg("could not print methods")
+			return failure
+		}
+
+		log.Info().Str("path", flagDump).Int("files", len(set.File)).Msg("descriptor set written")
+		return success
+	}
+
+	// Initialize the limiter for concurrent in-flight requests.
+	limits := make(map[string]uint, len(flagLimits))
+	for method, limit := range flagLimits {
+		if limit < 0 {
+			log.Error().Str("method", method).Int("limit", limit).Msg("invalid in-flight request limit")
+			return failure
+		}
+		limits[method] = uint(limit)
+	}
+	limiter := middleware.NewConcurrencyLimiter(flagInflight, limits, flagWait)
+
+	// Received requests are recorded only when a record file is given, and
+	// responses are only redacted when a redaction policy is given.
+	compressor := middleware.NewCompressor(compressed)
+	unary := []grpc.UnaryServerInterceptor{
+		tags.UnaryServerInterceptor(),
+		middleware.RequestIDUnaryServerInterceptor(),
+		compressor.UnaryServerInterceptor(),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		tags.StreamServerInterceptor(),
+		middleware.RequestIDStreamServerInterceptor(),
+		compressor.StreamServerInterceptor(),
+	}
+	switch {
+	case flagChainHdr && chainID == unknownChain:
+		log.Warn().Msg("chain ID header disabled, as the chain ID of the index is unknown")
+	case flagChainHdr:
+		unary = append(unary, middleware.ChainIDUnaryServerInterceptor(chainID))
+		stream = append(stream, middleware.ChainIDStreamServerInterceptor(chainID))
+	}
+	if flagRecord != "" {
+		file, err := os.OpenFile(flagRecord, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Error().Str("record", flagRecord).Err(err).Msg("could not open record file")
+			return failure
+		}
+		defer file.Close()
+		recorder := middleware.NewRecorder(log, file)
+		unary = append(unary, recorder.UnaryServerInterceptor())
+	}
+	if flagRedact != "" {
+		policy, err := middleware.LoadRedactionPolicy(flagRedact)
+		if err != nil {
+			log.Error().Str("policy", flagRedact).Err(err).Msg("could not load redaction policy")
+			return failure
+		}
+		redactor, err := middleware.NewRedactor(policy)
+		if err != nil {
+			log.Error().Str("policy", flagRedact).Err(err).Msg("could not initialize redactor")
+			return failure
+		}
+		unary = append(unary, redactor.UnaryServerInterceptor())
+		stream = append(stream, redactor.StreamServerInterceptor())
+	}
+
+	// GRPC API initialization.
+	opts := []logging.Option{
+		logging.WithLevels(logging.DefaultServerCodeToLevel),
+	}
+	// The depth of requested heights is measured against the cached indexed
+	// range, so that it does not cost an index read per request.
+	depth := func(ctx context.Context) (uint64, error) {
+		heights, err := server.GetIndexedHeightRange(ctx, &extended.GetIndexedHeightRangeRequest{})
+		if err != nil {
+			return 0, err
+		}
+		return heights.Last, nil
+	}
+	unary = append(unary,
+		middleware.CodesUnaryServerInterceptor(),
+		middleware.DepthUnaryServerInterceptor(depth),
+		logging.UnaryServerInterceptor(grpczerolog.InterceptorLogger(log), opts...),
+	)
+	if flagSlow > 0 {
+		unary = append(unary, middleware.SlowUnaryServerInterceptor(log, flagSlow))
+	}
+	stream = append(stream,
+		middleware.CodesStreamServerInterceptor(),
+		logging.StreamServerInterceptor(grpczerolog.InterceptorLogger(log), opts...),
+	)
+	// Expensive requests are shed before they take an in-flight slot, so that
+	// they leave room for cheap reads while the backend is degraded.
+	if flagShedRate > 0 || flagShedLat > 0 {
+		shedder := middleware.NewLoadShedder(flagShedOps, flagShedWin, flagShedRate, flagShedLat, flagShedFrac, flagShedRetry)
+		unary = append(unary, shedder.UnaryServerInterceptor())
+		stream = append(stream, shedder.StreamServerInterceptor())
+	}
+	// In-flight requests are aborted once the drain timeout has elapsed on
+	// shutdown, so that the graceful stop completes within its own timeout even
+	// when handlers, such as script executions, do not return.
+	drainer := middleware.NewDrainer(flagDrain)
+	unary = append(unary, limiter.UnaryServerInterceptor(), drainer.UnaryServerInterceptor())
+	stream = append(stream, limiter.StreamServerInterceptor())
+	// Panics are recovered from last, right around the handlers, since the
+	// drainer runs them in their own goroutine, and so that the other
+	// interceptors see the resulting error like any other.
+	unary = append(unary, middleware.RecoveryUnaryServerInterceptor(log))
+	stream = append(stream, middleware.RecoveryStreamServerInterceptor(log))
+	// Flow-control windows are only set when configured, as setting them turns
+	// off the dynamic window sizing of gRPC.
+	serverOptions := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+		grpc.ReadBufferSize(flagReadBuf),
+		grpc.WriteBufferSize(flagWriteBuf),
+	}
+	if flagStreamWin > 0 {
+		serverOptions = append(serverOptions, grpc.InitialWindowSize(flagStreamWin))
+	}
+	if flagConnWin > 0 {
+		serverOptions = append(serverOptions, grpc.InitialConnWindowSize(flagConnWin))
+	}
+	// The number of concurrent streams is limited on each connection, so that a
+	// single client cannot exhaust the resources of the server by opening them
+	// without bounds.
+	if flagStreams > 0 {
+		serverOptions = append(serverOptions, grpc.MaxConcurrentStreams(flagStreams))
+	}
+	gsvr := grpc.NewServer(serverOptions...)
+
+	// The liveness service is serving as soon as the process runs, while the
+	// readiness service reflects whether the archive index can be reached.
+	hsvr := health.NewServer()
+	hsvr.SetServingStatus(flagLiveness, grpc_health_v1.HealthCheckResponse_SERVING)
+	hsvr.SetServingStatus(flagReadiness, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(gsvr, hsvr)
+	readiness := accessHealth.NewReadiness(log, hsvr, index, flagReadiness, flagReadyInt)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go readiness.Run(ctx)
+
+	// automatically add metrics with grpc_server_handled_total{grpc_code="Internal|Unknown|OK"}
+	grpc_prometheus.EnableHandlingTimeHistogram()
+	grpc_prometheus.Register(gsvr)
 
 	// This section launches the main executing components in their own
 	// goroutine, so they can run concurrently. Afterwards, we wait for an
 	// interrupt signal in order to proceed with the next section.
-	listener, err := net.Listen("tcp", flagAddress)
+	var listen net.ListenConfig
+	if flagReuse && reusePortSupported {
+		listen.Control = reusePort
+	}
+	if flagReuse && !reusePortSupported {
+		log.Warn().Msg("SO_REUSEPORT is not supported on this platform, listening without it")
+	}
+	listener, err := listen.Listen(context.Background(), "tcp", flagAddress)
 	if err != nil {
 		log.Error().Str("address", flagAddress).Err(err).Msg("could not listen")
 		return failure
 	}
 	done := make(chan struct{})
 	failed := make(chan struct{})
+
+	// The metrics server is optional, so a failure to serve metrics is logged,
+	// but does not stop the API server.
+	mux := http.NewServeMux()
+	mux.Handle(flagScrape, promhttp.Handler())
+	msvr := &http.Server{
+		Addr:    flagMetrics,
+		Handler: mux,
+	}
+	if flagMetrics != "" {
+		go func() {
+			log.Info().Str("address", flagMetrics).Str("path", flagScrape).Msg("metrics server starting")
+			err := msvr.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Warn().Err(err).Msg("metrics server failed")
+			}
+		}()
+	}
+
+	// The admin server is optional as well, and only serves operator endpoints,
+	// so that they are never exposed on the public address.
+	asvr := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			tags.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(grpczerolog.InterceptorLogger(log), opts...),
+			middleware.RecoveryUnaryServerInterceptor(log),
+		),
+	)
+	if flagAdmin != "" {
+		adminListener, err := net.Listen("tcp", flagAdmin)
+		if err != nil {
+			log.Error().Str("address", flagAdmin).Err(err).Msg("could not listen for admin API")
+			return failure
+		}
+		admin.RegisterAdminAPIServer(asvr, accessApi.NewAdminServer(log, index, flagWindow, flagPin, addresses, caches, disk))
+		go func() {
+			log.Info().Str("address", flagAdmin).Msg("admin server starting")
+			err := asvr.Serve(adminListener)
+			if err != nil {
+				log.Warn().Err(err).Msg("admin server failed")
+			}
+		}()
+	}
+
 	go func() {
 		log.Info().Msg("Flow Access API Server starting")
 
 		access.RegisterAccessAPIServer(gsvr, server)
+		extended.RegisterExtendedAPIServer(gsvr, server)
 		err = gsvr.Serve(listener)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Warn().Err(err).Msg("Flow Access API Server failed")
@@ -167,7 +796,44 @@ func run() int {
 	// sure that the main executing components are shutting down within the
 	// allocated shutdown time. Otherwise, we will force the shutdown and log
 	// an error. We then wait for shutdown on each component to complete.
-	gsvr.GracefulStop()
+	cancel()
+	hsvr.Shutdown()
+	server.Shutdown()
+	drainer.Drain()
+	stopped := make(chan struct{})
+	go func() {
+		gsvr.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(flagShutdown):
+		log.Warn().Msg("Flow Access API Server did not stop in time, forcing stop")
+		gsvr.Stop()
+	}
+	asvr.GracefulStop()
+
+	ctx, cancel = context.WithTimeout(context.Background(), metricsShutdown)
+	defer cancel()
+	err = msvr.Shutdown(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("could not shut down metrics server")
+	}
 
 	return success
 }
+
+// indexedChain returns the chain ID of the blocks in the given index, read from
+// its first indexed block.
+func indexedChain(index archive.Reader) (string, error) {
+	first, err := index.First()
+	if err != nil {
+		return "", fmt.Errorf("could not get first height: %w", err)
+	}
+	header, err := index.Header(first)
+	if err != nil {
+		return "", fmt.Errorf("could not get header at height %d: %w", first, err)
+	}
+
+	return header.ChainID.String(), nil
+}
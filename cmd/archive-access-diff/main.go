@@ -0,0 +1,192 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	_ "github.com/onflow/flow/protobuf/go/flow/access"
+
+	"github.com/onflow/flow-archive-access/api/compare"
+	_ "github.com/onflow/flow-archive-access/api/extended"
+)
+
+// Like diff, the exit status tells apart identical responses, differing ones,
+// and failures to compare them.
+const (
+	same      = 0
+	different = 1
+	failure   = 2
+)
+
+// services are the gRPC services whose responses can be compared.
+var services = []protoreflect.FullName{
+	"flow.access.AccessAPI",
+	"flow.archive.access.ExtendedAPI",
+}
+
+// stdin is the file name that stands for the standard input.
+const stdin = "-"
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagMethod string
+		flagFormat string
+		flagIgnore []string
+		flagLevel  string
+	)
+
+	pflag.StringVarP(&flagMethod, "method", "m", "", "name of the Access API or extended API method that returned the responses (e.g. GetBlockByHeight)")
+	pflag.StringVarP(&flagFormat, "format", "f", "json", "encoding of the responses (json or protobuf)")
+	pflag.StringSliceVar(&flagIgnore, "ignore-fields", nil, "full names of the response fields that are left out of the comparison")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s: [flags] FIRST SECOND\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compares two responses read from the given files, or from the standard input for %q.\n", stdin)
+		pflag.PrintDefaults()
+	}
+
+	pflag.Parse()
+
+	// Logger initialization.
+	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	level, err := zerolog.ParseLevel(flagLevel)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		return failure
+	}
+	log = log.Level(level)
+
+	if pflag.NArg() != 2 {
+		log.Error().Int("files", pflag.NArg()).Msg("exactly two response files are required")
+		return failure
+	}
+	first, second := pflag.Arg(0), pflag.Arg(1)
+	if first == stdin && second == stdin {
+		log.Error().Msg("only one response can be read from the standard input")
+		return failure
+	}
+	if flagMethod == "" {
+		log.Error().Msg("method is required")
+		return failure
+	}
+	if flagFormat != "json" && flagFormat != "protobuf" {
+		log.Error().Str("format", flagFormat).Msg("format must be json or protobuf")
+		return failure
+	}
+
+	output, err := responseType(protoreflect.Name(flagMethod))
+	if err != nil {
+		log.Error().Str("method", flagMethod).Err(err).Msg("could not resolve response type")
+		return failure
+	}
+
+	firstResp, err := readResponse(first, flagFormat, output)
+	if err != nil {
+		log.Error().Str("file", first).Err(err).Msg("could not read response")
+		return failure
+	}
+	secondResp, err := readResponse(second, flagFormat, output)
+	if err != nil {
+		log.Error().Str("file", second).Err(err).Msg("could not read response")
+		return failure
+	}
+
+	ignored := make(map[protoreflect.FullName]struct{}, len(flagIgnore))
+	for _, name := range flagIgnore {
+		ignored[protoreflect.FullName(name)] = struct{}{}
+	}
+	compare.Clear(proto.MessageReflect(firstResp), ignored)
+	compare.Clear(proto.MessageReflect(secondResp), ignored)
+
+	differences := compare.Fields(firstResp, secondResp)
+	for _, difference := range differences {
+		fmt.Println(difference)
+	}
+
+	log.Debug().Int("differences", len(differences)).Msg("comparison done")
+
+	if len(differences) > 0 {
+		return different
+	}
+
+	return same
+}
+
+// responseType returns the type of the responses of the method with the given
+// name, from any of the supported services.
+func responseType(method protoreflect.Name) (protoreflect.MessageType, error) {
+	for _, name := range services {
+		descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not find service descriptor for %s: %w", name, err)
+		}
+		found := descriptor.(protoreflect.ServiceDescriptor).Methods().ByName(method)
+		if found == nil {
+			continue
+		}
+
+		return protoregistry.GlobalTypes.FindMessageByName(found.Output().FullName())
+	}
+
+	return nil, fmt.Errorf("unknown method %s", method)
+}
+
+// readResponse reads a response of the given type and encoding from the file
+// with the given name, or from the standard input.
+func readResponse(filename string, format string, typ protoreflect.MessageType) (proto.Message, error) {
+	var data []byte
+	var err error
+	if filename == stdin {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := proto.MessageV1(typ.New().Interface())
+	switch format {
+	case "protobuf":
+		err = proto.Unmarshal(data, resp)
+	default:
+		err = jsonpb.Unmarshal(bytes.NewReader(data), resp)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode response: %w", err)
+	}
+
+	return resp, nil
+}
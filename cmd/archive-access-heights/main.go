@@ -0,0 +1,144 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagAPI      string
+		flagInterval time.Duration
+		flagTimeout  time.Duration
+		flagOutput   string
+		flagFormat   string
+		flagRotate   int64
+		flagSealed   bool
+		flagLevel    string
+	)
+
+	pflag.StringVarP(&flagAPI, "api", "a", "127.0.0.1:9000", "address of the Access API whose served height is recorded")
+	pflag.DurationVarP(&flagInterval, "interval", "i", 10*time.Second, "interval at which the served height is polled")
+	pflag.DurationVarP(&flagTimeout, "timeout", "t", 5*time.Second, "timeout of each poll")
+	pflag.StringVarP(&flagOutput, "output", "o", "heights.csv", "path to the file to which samples are appended")
+	pflag.StringVarP(&flagFormat, "format", "f", formatCSV, "format of the samples, either csv or json (one object per line)")
+	pflag.Int64Var(&flagRotate, "rotate-size", 0, "size in bytes above which the output file is rotated (0 disables rotation)")
+	pflag.BoolVar(&flagSealed, "sealed", true, "poll the latest sealed block instead of the latest finalized block")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+
+	pflag.Parse()
+
+	// Logger initialization.
+	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	level, err := zerolog.ParseLevel(flagLevel)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		return failure
+	}
+	log = log.Level(level)
+
+	if flagInterval <= 0 {
+		log.Error().Dur("interval", flagInterval).Msg("interval must be positive")
+		return failure
+	}
+
+	output, err := newSeries(flagOutput, flagFormat, flagRotate)
+	if err != nil {
+		log.Error().Str("output", flagOutput).Err(err).Msg("could not open output file")
+		return failure
+	}
+	defer func() {
+		err := output.Close()
+		if err != nil {
+			log.Error().Str("output", flagOutput).Err(err).Msg("could not close output file")
+		}
+	}()
+
+	// Initialize the API client.
+	conn, err := grpc.Dial(flagAPI, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Error().Str("api", flagAPI).Err(err).Msg("could not dial API host")
+		return failure
+	}
+	defer conn.Close()
+	client := access.NewAccessAPIClient(conn)
+
+	// Polling stops on interrupt, after which the output file is flushed and
+	// closed by the deferred calls above.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(flagInterval)
+	defer ticker.Stop()
+
+	log.Info().Str("api", flagAPI).Str("output", flagOutput).Dur("interval", flagInterval).Msg("recording served heights")
+
+	for {
+		// A failed poll is logged and skipped instead of stopping the tool, so
+		// that gaps in the series show when the API was unavailable.
+		reqCtx, cancel := context.WithTimeout(ctx, flagTimeout)
+		resp, err := client.GetLatestBlockHeader(reqCtx, &access.GetLatestBlockHeaderRequest{IsSealed: flagSealed})
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			log.Warn().Str("api", flagAPI).Err(err).Msg("could not get latest block header")
+		default:
+			header := resp.GetBlock()
+			record := sample{
+				Timestamp: time.Now().UTC(),
+				Height:    header.GetHeight(),
+				BlockTime: header.GetTimestamp().AsTime(),
+			}
+			err = output.Write(record)
+			if err != nil {
+				log.Error().Str("output", flagOutput).Err(err).Msg("could not record sample")
+				return failure
+			}
+			log.Debug().Uint64("height", record.Height).Msg("sample recorded")
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("stopping")
+			return success
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Supported formats of the time series file.
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+// sample is the height served at a given time.
+type sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Height    uint64    `json:"height"`
+	BlockTime time.Time `json:"block_timestamp"`
+}
+
+// series appends samples to a file, one per line, and rotates the file once it
+// would grow beyond its size limit.
+type series struct {
+	path   string
+	format string
+	limit  int64
+	file   *os.File
+	size   int64
+}
+
+// newSeries opens the file at the given path for appending samples in the given
+// format. A zero limit disables rotation.
+func newSeries(path string, format string, limit int64) (*series, error) {
+
+	if format != formatCSV && format != formatJSON {
+		return nil, fmt.Errorf("unsupported format (%s), must be %s or %s", format, formatCSV, formatJSON)
+	}
+
+	s := series{
+		path:   path,
+		format: format,
+		limit:  limit,
+	}
+	err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Write appends the sample to the file, after rotating it if the sample would
+// make it exceed its size limit.
+func (s *series) Write(sample sample) error {
+
+	line, err := s.encode(sample)
+	if err != nil {
+		return fmt.Errorf("could not encode sample: %w", err)
+	}
+
+	if s.limit > 0 && s.size > 0 && s.size+int64(len(line)) > s.limit {
+		err = s.rotate(sample.Timestamp)
+		if err != nil {
+			return fmt.Errorf("could not rotate file: %w", err)
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("could not write sample: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes the file to disk and closes it.
+func (s *series) Close() error {
+
+	err := s.file.Sync()
+	if err != nil {
+		_ = s.file.Close()
+		return fmt.Errorf("could not sync file: %w", err)
+	}
+
+	return s.file.Close()
+}
+
+func (s *series) open() error {
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("could not stat file: %w", err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	// CSV files start with a header, so that they can be loaded as-is by most
+	// tools. Appending to an existing file keeps its header.
+	if s.format == formatCSV && s.size == 0 {
+		n, err := file.WriteString("timestamp,height,block_timestamp\n")
+		s.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("could not write header: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rotate moves the current file aside, with the given time as suffix, and
+// starts a new one at the original path.
+func (s *series) rotate(now time.Time) error {
+
+	err := s.Close()
+	if err != nil {
+		return err
+	}
+	rotated := s.path + "." + now.UTC().Format("20060102T150405Z")
+	err = os.Rename(s.path, rotated)
+	if err != nil {
+		return fmt.Errorf("could not rename file: %w", err)
+	}
+
+	return s.open()
+}
+
+func (s *series) encode(sample sample) ([]byte, error) {
+
+	if s.format == formatJSON {
+		line, err := json.Marshal(sample)
+		if err != nil {
+			return nil, err
+		}
+		return append(line, '\n'), nil
+	}
+
+	line := sample.Timestamp.UTC().Format(time.RFC3339Nano) + "," +
+		strconv.FormatUint(sample.Height, 10) + "," +
+		sample.BlockTime.UTC().Format(time.RFC3339Nano) + "\n"
+
+	return []byte(line), nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+
+	accessApi "github.com/onflow/flow-archive-access/api"
+	archiveAPI "github.com/onflow/flow-archive/api/archive"
+	"github.com/onflow/flow-archive/codec/zbor"
+	"github.com/onflow/flow-archive/service/invoker"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagArchive   string
+		flagCache     uint64
+		flagHeight    uint64
+		flagScript    string
+		flagArguments []string
+		flagOutput    string
+		flagWarm      bool
+		flagLevel     string
+	)
+
+	pflag.StringVarP(&flagArchive, "archive", "d", "127.0.0.1:80", "host URL for Archive API endpoint")
+	pflag.Uint64Var(&flagCache, "cache-size", 1_000_000_000, "maximum cache size for register reads in bytes")
+	pflag.Uint64Var(&flagHeight, "height", 0, "block height at which the script is executed")
+	pflag.StringVarP(&flagScript, "script", "s", "", "path to the Cadence script to execute")
+	pflag.StringArrayVarP(&flagArguments, "argument", "a", nil, "JSON-CDC encoded argument of the script, repeated for each argument in order")
+	pflag.StringVarP(&flagOutput, "output", "o", "script.pprof", "path to which the CPU profile is written")
+	pflag.BoolVar(&flagWarm, "warm", false, "execute the script once before profiling it, so that its register reads are served from the cache")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+
+	pflag.Parse()
+
+	// Logger initialization.
+	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	level, err := zerolog.ParseLevel(flagLevel)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		return failure
+	}
+	log = log.Level(level)
+
+	if flagScript == "" {
+		log.Error().Msg("script is required")
+		return failure
+	}
+	script, err := os.ReadFile(flagScript)
+	if err != nil {
+		log.Error().Str("script", flagScript).Err(err).Msg("could not read script")
+		return failure
+	}
+	arguments := make([][]byte, 0, len(flagArguments))
+	for _, argument := range flagArguments {
+		arguments = append(arguments, []byte(argument))
+	}
+
+	// Initialize the API client.
+	conn, err := grpc.Dial(flagArchive, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Error().Str("dps", flagArchive).Err(err).Msg("could not dial API host")
+		return failure
+	}
+	defer conn.Close()
+
+	// The script goes through the same server and invoker as in the Access API
+	// server, so that the profile covers argument decoding and height checks,
+	// and register reads are metered the same way.
+	codec := zbor.NewCodec()
+	client := archiveAPI.NewAPIClient(conn)
+	index := archiveAPI.IndexFromAPI(client, codec)
+	invoke, err := invoker.New(accessApi.MeterRegisters(index), invoker.WithCacheSize(flagCache))
+	if err != nil {
+		log.Error().Err(err).Msg("could not initialize script invoker")
+		return failure
+	}
+	server := accessApi.NewServer(index, codec, invoke, accessApi.WithLogger(log))
+
+	req := access.ExecuteScriptAtBlockHeightRequest{
+		BlockHeight: flagHeight,
+		Script:      script,
+		Arguments:   arguments,
+	}
+
+	if flagWarm {
+		_, err = server.ExecuteScriptAtBlockHeight(context.Background(), &req)
+		if err != nil {
+			log.Error().Uint64("height", flagHeight).Err(err).Msg("could not execute script to warm up cache")
+			return failure
+		}
+	}
+
+	output, err := os.Create(flagOutput)
+	if err != nil {
+		log.Error().Str("output", flagOutput).Err(err).Msg("could not create profile file")
+		return failure
+	}
+	defer output.Close()
+
+	// Only the execution of the script is profiled, so that the profile is not
+	// diluted by the setup above.
+	err = pprof.StartCPUProfile(output)
+	if err != nil {
+		log.Error().Err(err).Msg("could not start CPU profile")
+		return failure
+	}
+	start := time.Now()
+	resp, err := server.ExecuteScriptAtBlockHeight(context.Background(), &req)
+	duration := time.Since(start)
+	pprof.StopCPUProfile()
+	if err != nil {
+		log.Error().Uint64("height", flagHeight).Err(err).Msg("could not execute script")
+		return failure
+	}
+
+	log.Info().
+		Uint64("height", flagHeight).
+		Dur("duration", duration).
+		Str("output", flagOutput).
+		Msg("script profiled")
+
+	fmt.Println(string(resp.Value))
+
+	return success
+}
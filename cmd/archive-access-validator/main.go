@@ -0,0 +1,950 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/onflow/flow/protobuf/go/flow/access"
+
+	"github.com/onflow/flow-archive-access/api/compare"
+	"github.com/onflow/flow-archive-access/api/middleware"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+// service is the gRPC service whose recorded requests can be replayed. Requests
+// for other services, such as the extended API, are not served by access nodes
+// and are skipped.
+const service = "flow.access.AccessAPI"
+
+// maxLine is the maximum size of a recorded request.
+const maxLine = 16 * 1024 * 1024
+
+// defaultIgnored are the fields that are left out of comparisons by default.
+// Unless the archive is started with `--full-seals`, the seals of its blocks
+// only carry the IDs of the sealed block and of its result. Execution receipt
+// signatures need not be ignored, as they are empty on both sides.
+var defaultIgnored = []string{
+	"flow.entities.BlockSeal.result_id",
+	"flow.entities.BlockSeal.final_state",
+	"flow.entities.BlockSeal.aggregated_approval_sigs",
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagArchive []string
+		flagAccess  string
+		flagReplay  string
+		flagTimeout time.Duration
+		flagLevel   string
+		flagBisect  string
+		flagRequest string
+		flagStart   uint64
+		flagEnd     uint64
+		flagIgnore  []string
+		flagEvents  string
+		flagWindow  uint64
+		flagGoOn    bool
+		flagCounts  bool
+		flagIndices bool
+		flagStep    uint64
+		flagOnly    []string
+		flagRepeat  uint
+		flagScript  string
+		flagArgs    []string
+		flagHeight  uint64
+	)
+
+	pflag.StringSliceVarP(&flagArchive, "archive", "a", []string{"127.0.0.1:9000"}, "addresses of the archive Access API replicas to validate, each compared separately")
+	pflag.StringVarP(&flagAccess, "access", "n", "", "address of the access node Access API to compare against")
+	pflag.StringVarP(&flagReplay, "replay", "r", "", "path to the file with the recorded requests to replay, one JSON record per line")
+	pflag.DurationVarP(&flagTimeout, "timeout", "t", 10*time.Second, "timeout for each replayed request")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVarP(&flagBisect, "bisect", "b", "", "Access API method to bisect the first diverging height for, instead of replaying requests (e.g. GetBlockByHeight)")
+	pflag.StringVar(&flagRequest, "request", "{}", "JSON request to bisect with, whose height fields are set to each bisected height")
+	pflag.Uint64Var(&flagStart, "start", 0, "lowest height of the bisected or compared range, at which both APIs must agree when bisecting")
+	pflag.Uint64Var(&flagEnd, "end", 0, "highest height of the bisected or compared range, at which both APIs must disagree when bisecting")
+	pflag.StringVarP(&flagEvents, "events", "e", "", "event type to compare the events of over the range, in chunks, instead of replaying requests (e.g. flow.AccountCreated)")
+	pflag.Uint64Var(&flagWindow, "window", 100, "number of heights of each chunk of compared events")
+	pflag.BoolVar(&flagGoOn, "continue-on-error", false, "keep comparing events after the first diverging chunk")
+	pflag.BoolVar(&flagCounts, "result-counts", false, "compare the number of transaction results of the blocks sampled over the range, instead of replaying requests")
+	pflag.BoolVar(&flagIndices, "result-indices", false, "compare the transaction results of the blocks sampled over the range by index, instead of replaying requests")
+	pflag.Uint64Var(&flagStep, "step", 1, "number of heights between the blocks sampled for result counts or indices")
+	pflag.StringSliceVar(&flagIgnore, "ignore-fields", defaultIgnored, "full names of the response fields that are left out of comparisons")
+	pflag.UintVar(&flagRepeat, "repeat", 0, "number of times to execute the same script on each archive replica, checking that all results are identical, instead of replaying requests (disabled if 0)")
+	pflag.StringVar(&flagScript, "script", "", "path to the Cadence script to execute repeatedly")
+	pflag.StringArrayVar(&flagArgs, "argument", nil, "JSON-CDC encoded argument of the repeated script, repeated for each argument in order")
+	pflag.Uint64Var(&flagHeight, "height", 0, "block height at which the repeated script is executed")
+	pflag.StringSliceVar(&flagOnly, "endpoints", nil, "Access API methods whose recorded requests are replayed, skipping the others (all if empty)")
+
+	pflag.Parse()
+
+	// Logger initialization.
+	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	level, err := zerolog.ParseLevel(flagLevel)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		return failure
+	}
+	log = log.Level(level)
+
+	if len(flagArchive) == 0 {
+		log.Error().Msg("at least one archive address is required")
+		return failure
+	}
+	if flagAccess == "" && flagRepeat == 0 {
+		log.Error().Msg("access node address is required")
+		return failure
+	}
+	modes := 0
+	for _, set := range []bool{flagBisect != "", flagEvents != "", flagCounts, flagIndices, flagRepeat > 0} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		log.Error().Msg("bisection, event comparison, result count comparison, result index comparison and repeated scripts cannot be combined")
+		return failure
+	}
+	if modes == 0 && flagReplay == "" {
+		log.Error().Msg("replay file is required")
+		return failure
+	}
+	if modes > 0 && len(flagOnly) > 0 {
+		log.Error().Msg("endpoints can only be selected when replaying requests")
+		return failure
+	}
+	if flagBisect != "" && flagStart >= flagEnd {
+		log.Error().Uint64("start", flagStart).Uint64("end", flagEnd).Msg("bisected range must have a start below its end")
+		return failure
+	}
+	if (flagEvents != "" || flagCounts || flagIndices) && flagStart > flagEnd {
+		log.Error().Uint64("start", flagStart).Uint64("end", flagEnd).Msg("compared range must not have a start above its end")
+		return failure
+	}
+	if flagEvents != "" && flagWindow == 0 {
+		log.Error().Msg("window size must be positive")
+		return failure
+	}
+	if (flagCounts || flagIndices) && flagStep == 0 {
+		log.Error().Msg("step must be positive")
+		return failure
+	}
+	if flagRepeat > 0 && flagScript == "" {
+		log.Error().Msg("script is required to repeat it")
+		return failure
+	}
+
+	// Resolve the methods of the Access API, so that recorded requests can be
+	// decoded into the right message types.
+	descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		log.Error().Str("service", service).Err(err).Msg("could not find service descriptor")
+		return failure
+	}
+	methods := descriptor.(protoreflect.ServiceDescriptor).Methods()
+
+	// Only the unary methods of the Access API can be replayed, so selecting any
+	// other endpoint is most likely a typo.
+	var endpoints map[protoreflect.Name]struct{}
+	if len(flagOnly) > 0 {
+		endpoints = make(map[protoreflect.Name]struct{}, len(flagOnly))
+	}
+	for _, name := range flagOnly {
+		method := methods.ByName(protoreflect.Name(name))
+		if method == nil || method.IsStreamingClient() || method.IsStreamingServer() {
+			log.Error().Str("endpoint", name).Msg("unknown endpoint")
+			return failure
+		}
+		endpoints[method.Name()] = struct{}{}
+	}
+
+	ignored := make(map[protoreflect.FullName]struct{}, len(flagIgnore))
+	for _, name := range flagIgnore {
+		ignored[protoreflect.FullName(name)] = struct{}{}
+	}
+
+	// Initialize the connections to all APIs.
+	archives := make([]*replica, 0, len(flagArchive))
+	for _, address := range flagArchive {
+		conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Error().Str("archive", address).Err(err).Msg("could not dial archive API")
+			return failure
+		}
+		defer conn.Close()
+
+		archives = append(archives, &replica{address: address, conn: conn})
+	}
+
+	// Repeated scripts are only compared between executions on each replica, so
+	// the access node is not needed.
+	if flagRepeat > 0 {
+		script, err := os.ReadFile(flagScript)
+		if err != nil {
+			log.Error().Str("script", flagScript).Err(err).Msg("could not read script")
+			return failure
+		}
+		arguments := make([][]byte, 0, len(flagArgs))
+		for _, argument := range flagArgs {
+			arguments = append(arguments, []byte(argument))
+		}
+		req := access.ExecuteScriptAtBlockHeightRequest{
+			BlockHeight: flagHeight,
+			Script:      script,
+			Arguments:   arguments,
+		}
+		return repeatScript(log, archives, methods, &req, flagRepeat, flagTimeout)
+	}
+
+	node, err := grpc.Dial(flagAccess, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Error().Str("access", flagAccess).Err(err).Msg("could not dial access node API")
+		return failure
+	}
+	defer node.Close()
+
+	// Responses cannot match if the APIs are not serving the same network, so
+	// there is no point in comparing anything in that case.
+	for _, archive := range archives {
+		err = checkGetNetworkParameters(access.NewAccessAPIClient(archive.conn), access.NewAccessAPIClient(node), flagTimeout)
+		if err != nil {
+			log.Error().Str("archive", archive.address).Err(err).Msg("network check failed")
+			return failure
+		}
+	}
+
+	// Each replica is bisected on its own, as replicas can diverge at different
+	// heights.
+	if flagBisect != "" {
+		result := success
+		for _, archive := range archives {
+			alog := log.With().Str("archive", archive.address).Logger()
+			if bisect(alog, archive.conn, node, methods, ignored, flagBisect, flagRequest, flagStart, flagEnd, flagTimeout) != success {
+				result = failure
+			}
+		}
+		return result
+	}
+
+	if flagEvents != "" {
+		return compareEvents(log, archives, node, methods, ignored, flagEvents, flagStart, flagEnd, flagWindow, flagGoOn, flagTimeout)
+	}
+
+	if flagCounts {
+		return compareResultCounts(log, archives, node, flagStart, flagEnd, flagStep, flagTimeout)
+	}
+
+	if flagIndices {
+		return compareResultIndices(log, archives, node, methods, ignored, flagStart, flagEnd, flagStep, flagTimeout)
+	}
+
+	return replay(log, archives, node, methods, endpoints, ignored, flagReplay, flagTimeout)
+}
+
+// replica is an archive Access API server under validation, along with the
+// number of requests for which its responses differ from the access node's.
+type replica struct {
+	address string
+	conn    *grpc.ClientConn
+	diffs   uint
+}
+
+// replay replays each recorded request of the given file against the access node
+// and every archive replica, and reports the requests for which the responses
+// of a replica differ from the ones of the access node, along with the replicas
+// that diverged. Only requests for the given endpoints are replayed, unless no
+// endpoints are given.
+func replay(log zerolog.Logger, archives []*replica, node *grpc.ClientConn, methods protoreflect.MethodDescriptors, endpoints map[protoreflect.Name]struct{}, ignored map[protoreflect.FullName]struct{}, filename string, timeout time.Duration) int {
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Error().Str("replay", filename).Err(err).Msg("could not open replay file")
+		return failure
+	}
+	defer file.Close()
+
+	// Replay each recorded request against all APIs and compare the responses.
+	var replayed, skipped, filtered, diffs uint
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var record middleware.Record
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			log.Error().Int("line", line).Err(err).Msg("could not decode record")
+			return failure
+		}
+
+		rlog := log.With().Int("line", line).Str("method", record.Method).Logger()
+
+		name := strings.TrimPrefix(path.Dir(record.Method), "/")
+		if name != service {
+			rlog.Debug().Msg("skipping request for unsupported service")
+			skipped++
+			continue
+		}
+		method := methods.ByName(protoreflect.Name(path.Base(record.Method)))
+		if method == nil {
+			rlog.Warn().Msg("skipping request for unknown method")
+			skipped++
+			continue
+		}
+		if method.IsStreamingClient() || method.IsStreamingServer() {
+			rlog.Debug().Msg("skipping request for streaming method")
+			skipped++
+			continue
+		}
+		_, selected := endpoints[method.Name()]
+		if endpoints != nil && !selected {
+			rlog.Debug().Msg("skipping request for unselected endpoint")
+			filtered++
+			continue
+		}
+
+		req, err := newMessage(method.Input())
+		if err != nil {
+			rlog.Error().Err(err).Msg("could not create request")
+			return failure
+		}
+		err = jsonpb.Unmarshal(bytes.NewReader(record.Request), req)
+		if err != nil {
+			rlog.Error().Err(err).Msg("could not decode request")
+			return failure
+		}
+
+		accessResp, accessErr := invoke(node, record.Method, req, method.Output(), timeout)
+		replayed++
+
+		var diverged []string
+		for _, archive := range archives {
+			archiveResp, archiveErr := invoke(archive.conn, record.Method, req, method.Output(), timeout)
+			diff := compare.Responses(archiveResp, archiveErr, accessResp, accessErr, ignored)
+			if diff != "" {
+				rlog.Warn().Str("archive", archive.address).RawJSON("request", record.Request).Msg(diff)
+				diverged = append(diverged, archive.address)
+				archive.diffs++
+			}
+		}
+		if len(diverged) > 0 {
+			if len(archives) > 1 {
+				rlog.Warn().Strs("diverged", diverged).Msg("replicas diverged")
+			}
+			diffs++
+			continue
+		}
+
+		rlog.Debug().Msg("responses match")
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		log.Error().Str("replay", filename).Err(err).Msg("could not read replay file")
+		return failure
+	}
+
+	for _, archive := range archives {
+		log.Info().Str("archive", archive.address).Uint("replayed", replayed).Uint("diffs", archive.diffs).Msg("replica done")
+	}
+	selected := []string{"all"}
+	if endpoints != nil {
+		selected = make([]string, 0, len(endpoints))
+		for name := range endpoints {
+			selected = append(selected, string(name))
+		}
+		sort.Strings(selected)
+	}
+	log.Info().
+		Strs("endpoints", selected).
+		Uint("replayed", replayed).
+		Uint("skipped", skipped).
+		Uint("filtered", filtered).
+		Uint("diffs", diffs).
+		Msg("replay done")
+
+	if diffs > 0 {
+		return failure
+	}
+
+	return success
+}
+
+// bisect binary-searches the given height range for the first height at which
+// the responses of both APIs to the given method differ. Both APIs must agree at
+// the start of the range and disagree at its end.
+func bisect(log zerolog.Logger, archive *grpc.ClientConn, node *grpc.ClientConn, methods protoreflect.MethodDescriptors, ignored map[protoreflect.FullName]struct{}, name string, request string, start uint64, end uint64, timeout time.Duration) int {
+	log = log.With().Str("method", name).Logger()
+
+	method := methods.ByName(protoreflect.Name(name))
+	if method == nil {
+		log.Error().Msg("unknown method")
+		return failure
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		log.Error().Msg("streaming methods cannot be bisected")
+		return failure
+	}
+	fullMethod := fmt.Sprintf("/%s/%s", service, name)
+
+	diff := func(height uint64) (string, error) {
+		req, err := newMessage(method.Input())
+		if err != nil {
+			return "", fmt.Errorf("could not create request: %w", err)
+		}
+		err = jsonpb.UnmarshalString(request, req)
+		if err != nil {
+			return "", fmt.Errorf("could not decode request: %w", err)
+		}
+		err = setHeight(req, height)
+		if err != nil {
+			return "", fmt.Errorf("could not set request height: %w", err)
+		}
+
+		archiveResp, archiveErr := invoke(archive, fullMethod, req, method.Output(), timeout)
+		accessResp, accessErr := invoke(node, fullMethod, req, method.Output(), timeout)
+
+		return compare.Responses(archiveResp, archiveErr, accessResp, accessErr, ignored), nil
+	}
+
+	startDiff, err := diff(start)
+	if err != nil {
+		log.Error().Err(err).Msg("could not compare responses")
+		return failure
+	}
+	if startDiff != "" {
+		log.Error().Uint64("height", start).Msg("responses already differ at start of range: " + startDiff)
+		return failure
+	}
+	endDiff, err := diff(end)
+	if err != nil {
+		log.Error().Err(err).Msg("could not compare responses")
+		return failure
+	}
+	if endDiff == "" {
+		log.Error().Uint64("height", end).Msg("responses match at end of range")
+		return failure
+	}
+
+	// Responses match at the low height and differ at the high height, which
+	// is kept along with its diff until both heights are adjacent.
+	low, high, highDiff := start, end, endDiff
+	for high-low > 1 {
+		mid := low + (high-low)/2
+		midDiff, err := diff(mid)
+		if err != nil {
+			log.Error().Err(err).Msg("could not compare responses")
+			return failure
+		}
+
+		log.Debug().Uint64("height", mid).Bool("match", midDiff == "").Msg("compared responses")
+
+		if midDiff == "" {
+			low = mid
+			continue
+		}
+		high, highDiff = mid, midDiff
+	}
+
+	log.Info().Uint64("height", high).Msg("first diverging height found: " + highDiff)
+
+	return success
+}
+
+// compareEvents compares the events of the given type over the given height range
+// between the access node and every archive replica, one chunk of heights at a
+// time, so that memory use is bounded by the size of a chunk. It reports the
+// first diverging height of each diverging chunk and, unless told to continue,
+// stops at the first one.
+func compareEvents(log zerolog.Logger, archives []*replica, node *grpc.ClientConn, methods protoreflect.MethodDescriptors, ignored map[protoreflect.FullName]struct{}, eventType string, start uint64, end uint64, window uint64, keepGoing bool, timeout time.Duration) int {
+	log = log.With().Str("type", eventType).Logger()
+
+	method := methods.ByName("GetEventsForHeightRange")
+	fullMethod := fmt.Sprintf("/%s/%s", service, method.Name())
+
+	var chunks, diffs uint
+	for low := start; ; low += window {
+		high := low + window - 1
+		if high > end || high < low {
+			high = end
+		}
+		clog := log.With().Uint64("start", low).Uint64("end", high).Logger()
+
+		req := access.GetEventsForHeightRangeRequest{
+			Type:        eventType,
+			StartHeight: low,
+			EndHeight:   high,
+		}
+		accessResp, accessErr := invoke(node, fullMethod, &req, method.Output(), timeout)
+		chunks++
+
+		diverged := false
+		for _, archive := range archives {
+			archiveResp, archiveErr := invoke(archive.conn, fullMethod, &req, method.Output(), timeout)
+			diff := compare.Responses(archiveResp, archiveErr, accessResp, accessErr, ignored)
+			if diff == "" {
+				continue
+			}
+
+			// The first diverging height can only be told when both sides
+			// returned events; otherwise, the whole chunk is reported.
+			height := low
+			if archiveErr == nil && accessErr == nil {
+				height = firstDiverging(archiveResp.(*access.EventsResponse), accessResp.(*access.EventsResponse))
+			}
+			clog.Warn().Str("archive", archive.address).Uint64("height", height).Msg(diff)
+			archive.diffs++
+			diverged = true
+		}
+		if diverged {
+			diffs++
+		} else {
+			clog.Debug().Msg("events match")
+		}
+
+		if high == end || (diverged && !keepGoing) {
+			break
+		}
+	}
+
+	for _, archive := range archives {
+		log.Info().Str("archive", archive.address).Uint("chunks", chunks).Uint("diffs", archive.diffs).Msg("replica done")
+	}
+	log.Info().Uint("chunks", chunks).Uint("diffs", diffs).Msg("comparison done")
+
+	if diffs > 0 {
+		return failure
+	}
+
+	return success
+}
+
+// compareResultCounts compares the number of transaction results of the blocks
+// sampled every given number of heights over the given range, between the access
+// node and every archive replica. Only the counts are compared, so that blocks
+// whose system chunk transaction is missing or duplicated are told apart from
+// blocks whose results differ in content. It reports every offending block.
+func compareResultCounts(log zerolog.Logger, archives []*replica, node *grpc.ClientConn, start uint64, end uint64, step uint64, timeout time.Duration) int {
+	client := access.NewAccessAPIClient(node)
+
+	offending := make(map[string][]uint64, len(archives))
+	var sampled, failed, diffs uint
+	for height := start; ; height += step {
+		hlog := log.With().Uint64("height", height).Logger()
+
+		diverged, err := compareResultCount(hlog, client, archives, height, timeout)
+		switch {
+		case err != nil:
+			hlog.Error().Err(err).Msg("could not compare transaction result counts")
+			failed++
+		case len(diverged) > 0:
+			for _, archive := range diverged {
+				offending[archive.address] = append(offending[archive.address], height)
+				archive.diffs++
+			}
+			sampled++
+			diffs++
+		default:
+			hlog.Debug().Msg("transaction result counts match")
+			sampled++
+		}
+
+		if end-height < step {
+			break
+		}
+	}
+
+	for _, archive := range archives {
+		log.Info().Str("archive", archive.address).Uint("sampled", sampled).Uint("diffs", archive.diffs).Uints64("heights", offending[archive.address]).Msg("replica done")
+	}
+	log.Info().Uint("sampled", sampled).Uint("failed", failed).Uint("diffs", diffs).Msg("result count comparison done")
+
+	if diffs > 0 || failed > 0 {
+		return failure
+	}
+
+	return success
+}
+
+// compareResultCount compares the number of transaction results of the block at
+// the given height between the access node and every archive replica, and
+// returns the replicas whose count differs. A replica that fails to return the
+// results of a block that the access node returns is considered to differ.
+func compareResultCount(log zerolog.Logger, client access.AccessAPIClient, archives []*replica, height uint64, timeout time.Duration) ([]*replica, error) {
+	// Archives do not serve headers by height, so block IDs are looked up on
+	// the access node.
+	blockID, err := blockIDAtHeight(client, height, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block ID from access node: %w", err)
+	}
+	accessCount, err := countResults(client, blockID, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction results from access node: %w", err)
+	}
+
+	log = log.With().Hex("block_id", blockID).Int("access_count", accessCount).Logger()
+
+	var diverged []*replica
+	for _, archive := range archives {
+		archiveCount, err := countResults(access.NewAccessAPIClient(archive.conn), blockID, timeout)
+		if err != nil {
+			log.Warn().Str("archive", archive.address).Err(err).Msg("archive could not return transaction results")
+			diverged = append(diverged, archive)
+			continue
+		}
+		if archiveCount != accessCount {
+			log.Warn().Str("archive", archive.address).Int("archive_count", archiveCount).Msg("transaction result counts differ")
+			diverged = append(diverged, archive)
+		}
+	}
+
+	return diverged, nil
+}
+
+// compareResultIndices compares the transaction results returned by
+// `GetTransactionResultByIndex` for every index of the blocks sampled every given
+// number of heights over the given range, between the access node and every
+// archive replica. It reports the first diverging index of every offending
+// block. If the access node does not implement the method, the comparison is
+// skipped.
+func compareResultIndices(log zerolog.Logger, archives []*replica, node *grpc.ClientConn, methods protoreflect.MethodDescriptors, ignored map[protoreflect.FullName]struct{}, start uint64, end uint64, step uint64, timeout time.Duration) int {
+	method := methods.ByName("GetTransactionResultByIndex")
+
+	offending := make(map[string][]uint64, len(archives))
+	var sampled, failed, diffs uint
+	for height := start; ; height += step {
+		hlog := log.With().Uint64("height", height).Logger()
+
+		diverged, err := compareResultIndex(hlog, archives, node, method, ignored, height, timeout)
+		switch {
+		case errors.Is(err, errUnsupported):
+			log.Warn().Msg("access node does not support GetTransactionResultByIndex, skipping result index comparison")
+			return success
+		case err != nil:
+			hlog.Error().Err(err).Msg("could not compare transaction results by index")
+			failed++
+		case len(diverged) > 0:
+			for _, archive := range diverged {
+				offending[archive.address] = append(offending[archive.address], height)
+				archive.diffs++
+			}
+			sampled++
+			diffs++
+		default:
+			hlog.Debug().Msg("transaction results by index match")
+			sampled++
+		}
+
+		if end-height < step {
+			break
+		}
+	}
+
+	for _, archive := range archives {
+		log.Info().Str("archive", archive.address).Uint("sampled", sampled).Uint("diffs", archive.diffs).Uints64("heights", offending[archive.address]).Msg("replica done")
+	}
+	log.Info().Uint("sampled", sampled).Uint("failed", failed).Uint("diffs", diffs).Msg("result index comparison done")
+
+	if diffs > 0 || failed > 0 {
+		return failure
+	}
+
+	return success
+}
+
+// repeatScript executes the same script the given number of times on every
+// archive replica, and checks that each replica returns the same result every
+// time. For each replica, the first execution whose result differs from the
+// first one is logged.
+func repeatScript(log zerolog.Logger, archives []*replica, methods protoreflect.MethodDescriptors, req *access.ExecuteScriptAtBlockHeightRequest, repeat uint, timeout time.Duration) int {
+	method := methods.ByName("ExecuteScriptAtBlockHeight")
+	fullMethod := fmt.Sprintf("/%s/%s", service, method.Name())
+
+	log = log.With().Uint64("height", req.BlockHeight).Uint("repeat", repeat).Logger()
+
+	var diffs uint
+	for _, archive := range archives {
+		alog := log.With().Str("archive", archive.address).Logger()
+
+		firstResp, firstErr := invoke(archive.conn, fullMethod, req, method.Output(), timeout)
+		if firstErr != nil {
+			alog.Warn().Err(firstErr).Msg("first script execution failed, comparing errors")
+		}
+		for iteration := uint(1); iteration < repeat; iteration++ {
+			resp, err := invoke(archive.conn, fullMethod, req, method.Output(), timeout)
+			diff := repeatDiff(resp, err, firstResp, firstErr)
+			if diff == "" {
+				continue
+			}
+
+			alog.Warn().Uint("iteration", iteration).Msg(diff)
+			archive.diffs++
+			diffs++
+			break
+		}
+
+		alog.Info().Uint("diffs", archive.diffs).Msg("replica done")
+	}
+	log.Info().Uint("diffs", diffs).Msg("repeated script comparison done")
+
+	if diffs > 0 {
+		return failure
+	}
+
+	return success
+}
+
+// repeatDiff returns a description of the difference between the result of a
+// repeated script execution and the result of the first one, or an empty string
+// if they are identical. Errors are considered identical when they have the
+// same status code.
+func repeatDiff(resp proto.Message, err error, firstResp proto.Message, firstErr error) string {
+	switch {
+	case err != nil && firstErr != nil:
+		code, firstCode := status.Code(err), status.Code(firstErr)
+		if code == firstCode {
+			return ""
+		}
+		return fmt.Sprintf("error codes differ: returned %s (%v), first returned %s (%v)", code, err, firstCode, firstErr)
+
+	case err != nil:
+		return fmt.Sprintf("only repeated execution returned an error: %v", err)
+
+	case firstErr != nil:
+		return fmt.Sprintf("only first execution returned an error: %v", firstErr)
+
+	case proto.Equal(resp, firstResp):
+		return ""
+	}
+
+	differences := compare.Fields(resp, firstResp)
+	descriptions := make([]string, 0, len(differences))
+	for _, difference := range differences {
+		descriptions = append(descriptions, difference.String())
+	}
+
+	return fmt.Sprintf("results differ (repeated != first): %s", strings.Join(descriptions, "; "))
+}
+
+// errUnsupported is returned when the access node does not implement a method.
+var errUnsupported = errors.New("method not supported by access node")
+
+// compareResultIndex compares the transaction results at every index of the
+// block at the given height between the access node and every archive replica,
+// and returns the replicas whose results differ. For each of them, only the
+// first diverging index is logged.
+func compareResultIndex(log zerolog.Logger, archives []*replica, node *grpc.ClientConn, method protoreflect.MethodDescriptor, ignored map[protoreflect.FullName]struct{}, height uint64, timeout time.Duration) ([]*replica, error) {
+	client := access.NewAccessAPIClient(node)
+	fullMethod := fmt.Sprintf("/%s/%s", service, method.Name())
+
+	// The number of results of the block on the access node gives the indices
+	// to compare, including the one of the system chunk transaction.
+	blockID, err := blockIDAtHeight(client, height, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block ID from access node: %w", err)
+	}
+	count, err := countResults(client, blockID, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction results from access node: %w", err)
+	}
+
+	log = log.With().Hex("block_id", blockID).Int("count", count).Logger()
+
+	type result struct {
+		resp proto.Message
+		err  error
+	}
+	accessResults := make([]result, 0, count)
+	for index := 0; index < count; index++ {
+		req := access.GetTransactionByIndexRequest{BlockId: blockID, Index: uint32(index)}
+		resp, err := invoke(node, fullMethod, &req, method.Output(), timeout)
+		if status.Code(err) == codes.Unimplemented {
+			return nil, errUnsupported
+		}
+		accessResults = append(accessResults, result{resp: resp, err: err})
+	}
+
+	var diverged []*replica
+	for _, archive := range archives {
+		for index, accessResult := range accessResults {
+			req := access.GetTransactionByIndexRequest{BlockId: blockID, Index: uint32(index)}
+			archiveResp, archiveErr := invoke(archive.conn, fullMethod, &req, method.Output(), timeout)
+			diff := compare.Responses(archiveResp, archiveErr, accessResult.resp, accessResult.err, ignored)
+			if diff == "" {
+				continue
+			}
+
+			log.Warn().Str("archive", archive.address).Int("index", index).Msg(diff)
+			diverged = append(diverged, archive)
+			break
+		}
+	}
+
+	return diverged, nil
+}
+
+// blockIDAtHeight returns the ID of the block at the given height.
+func blockIDAtHeight(client access.AccessAPIClient, height uint64, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := client.GetBlockHeaderByHeight(ctx, &access.GetBlockHeaderByHeightRequest{Height: height})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Block.Id, nil
+}
+
+// countResults returns the number of transaction results of the block with the
+// given ID.
+func countResults(client access.AccessAPIClient, blockID []byte, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := client.GetTransactionResultsByBlockID(ctx, &access.GetTransactionsByBlockIDRequest{BlockId: blockID})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(resp.TransactionResults), nil
+}
+
+// firstDiverging returns the height of the first block for which the events of
+// the archive and of the access node differ.
+func firstDiverging(archiveResp *access.EventsResponse, accessResp *access.EventsResponse) uint64 {
+	archiveResults, accessResults := archiveResp.Results, accessResp.Results
+	for i := 0; i < len(archiveResults) && i < len(accessResults); i++ {
+		if !proto.Equal(archiveResults[i], accessResults[i]) {
+			return accessResults[i].BlockHeight
+		}
+	}
+
+	switch {
+	case len(archiveResults) > len(accessResults):
+		return archiveResults[len(accessResults)].BlockHeight
+	case len(accessResults) > len(archiveResults):
+		return accessResults[len(archiveResults)].BlockHeight
+	default:
+		return 0
+	}
+}
+
+// setHeight sets the height fields of the given request, such as `height` or
+// `block_height`, or both `start_height` and `end_height` for ranges.
+func setHeight(req proto.Message, height uint64) error {
+	msg := proto.MessageReflect(req)
+	fields := msg.Descriptor().Fields()
+
+	set := false
+	for _, name := range []protoreflect.Name{"height", "block_height", "start_height", "end_height"} {
+		field := fields.ByName(name)
+		if field == nil || field.Kind() != protoreflect.Uint64Kind {
+			continue
+		}
+		msg.Set(field, protoreflect.ValueOfUint64(height))
+		set = true
+	}
+	if !set {
+		return fmt.Errorf("request type %s has no height field", msg.Descriptor().FullName())
+	}
+
+	return nil
+}
+
+// checkGetNetworkParameters checks that the archive and the access node report
+// the same chain ID.
+func checkGetNetworkParameters(archive access.AccessAPIClient, node access.AccessAPIClient, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	archiveParams, err := archive.GetNetworkParameters(ctx, &access.GetNetworkParametersRequest{})
+	if err != nil {
+		return fmt.Errorf("could not get archive network parameters: %w", err)
+	}
+	nodeParams, err := node.GetNetworkParameters(ctx, &access.GetNetworkParametersRequest{})
+	if err != nil {
+		return fmt.Errorf("could not get access node network parameters: %w", err)
+	}
+
+	if archiveParams.ChainId != nodeParams.ChainId {
+		return fmt.Errorf("chain IDs differ: archive serves %q, access node serves %q", archiveParams.ChainId, nodeParams.ChainId)
+	}
+
+	return nil
+}
+
+// newMessage creates an empty message of the type with the given descriptor.
+func newMessage(descriptor protoreflect.MessageDescriptor) (proto.Message, error) {
+	typ := proto.MessageType(string(descriptor.FullName()))
+	if typ == nil {
+		return nil, fmt.Errorf("unknown message type %s", descriptor.FullName())
+	}
+
+	return reflect.New(typ.Elem()).Interface().(proto.Message), nil
+}
+
+// invoke calls a unary method on the given connection.
+func invoke(conn *grpc.ClientConn, method string, req proto.Message, output protoreflect.MessageDescriptor, timeout time.Duration) (proto.Message, error) {
+	resp, err := newMessage(output)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err = conn.Invoke(ctx, method, req, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}